@@ -12,7 +12,7 @@ def create_jwt():
         "iss": "KTHAIS",
         "email": "vivienne@kthais.com",
         "roles": "admin,user",
-        "id": str(uuid.uuid4()),
+        "user_id": str(uuid.uuid4()),
         "exp": int(time.time()) + 600 
     }
     print(f"Key: {jwt_key}")
@@ -13,7 +13,7 @@ def create_jwt():
         "iss": "KTHAIS",
         "email": "vivienne@kthais.com",
         "roles": "admin,user",
-        "id": str(uuid.uuid4()),
+        "user_id": str(uuid.uuid4()),
         "exp": int(time.time()) + 600 
     }
     print(f"Key: {jwt_key}")
@@ -33,7 +33,6 @@ def Upload_jobs(file_path, api_url):
     f.close()
     with open(file_path, 'rb') as file:
         headers = {"authorization": f"Bearer {token}"}
-        cookies = {"jwt": token}
         for line in file.readlines():
             title, description, salary, location, jobType, cname = line.decode('utf-8').strip().split(',')
             id = uuid.uuid4()
@@ -47,7 +46,7 @@ def Upload_jobs(file_path, api_url):
                 "jobType": jobType,
                 "company": company_id
             }
-            response = requests.post(f"{api_url}/admin/new", json=data, headers=headers, cookies=cookies)
+            response = requests.post(f"{api_url}/admin/new", json=data, headers=headers)
             if response.ok:
                 print(f"Uploaded job listing: {title}")
             else:
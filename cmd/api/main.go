@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
@@ -14,6 +15,8 @@ import (
 	"backend/internal/handlers"
 	"backend/internal/mailchimp"
 	"backend/internal/models"
+	"backend/internal/reminders"
+	"backend/internal/utils"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/sessions"
@@ -106,17 +109,30 @@ func main() {
 	// Initialize DB
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
 		cfg.Database.Host, cfg.Database.User, cfg.Database.Password, cfg.Database.DBName, cfg.Database.Port, cfg.Database.SSLMode)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	// Registrations are unique per event and user
+	if err := models.RemoveDuplicateRegistrations(db); err != nil {
+		log.Fatal("Failed to remove duplicate registrations:", err)
+	}
+
 	// Auto migrate the schema
 	err = db.AutoMigrate(
 		&models.User{},
 		&models.Profile{},
 		&models.Event{},
+		&models.EventSeries{},
 		&models.Registration{},
+		&models.SentReminder{},
+		&models.LotteryDraw{},
+		&models.Team{},
+		&models.PolicyOverride{},
+		&models.SurveyResponse{},
+		&models.RegistrationTransfer{},
+		&models.LoginToken{},
 		&models.TeamMember{},
 		&models.BlobData{},
 		&models.JobListing{},
@@ -175,14 +191,30 @@ func main() {
 		log.Fatal("Failed to initialize mailchimp client:", err)
 	}
 
+	// Keep the keys Google signs ID tokens with at hand
+	googleKeys := utils.NewJWKSCache(utils.GoogleJWKSURL, &http.Client{Timeout: 10 * time.Second})
+	googleKeys.Start(context.Background())
+
 	// Initialize handlers
-	setupRoutes(r, db, mailchimpApi, cfg)
+	setupRoutes(r, db, mailchimpApi, cfg, googleKeys)
+
+	// Pass on waitlist offers that were not confirmed in time
+	handlers.StartWaitlistSweeper(db, cfg, time.Minute)
+
+	// Draw the lotteries that should be drawn as soon as applications close
+	handlers.StartLotterySweeper(db, cfg, time.Minute)
+
+	// Remind approved registrants before their events start
+	reminders.NewScheduler(db, cfg).Start(context.Background(), time.Minute)
+
+	// Ask attendees for feedback after their events end
+	reminders.NewSurveySender(db, cfg).Start(context.Background(), time.Minute)
 
 	// Run the server
 	r.Run(":" + cfg.Server.Port)
 }
 
-func setupRoutes(r *gin.Engine, db *gorm.DB, mailchimpApi *mailchimp.MailchimpAPI, cfg *config.Config) {
+func setupRoutes(r *gin.Engine, db *gorm.DB, mailchimpApi *mailchimp.MailchimpAPI, cfg *config.Config, googleKeys utils.KeySource) {
 	api := r.Group("/api/v1")
 
 	// Public routes
@@ -192,8 +224,8 @@ func setupRoutes(r *gin.Engine, db *gorm.DB, mailchimpApi *mailchimp.MailchimpAP
 
 	// Register all handlers
 	allHandlers := []handlers.Handler{
-		handlers.NewEventHandler(db),
-		handlers.NewAuthHandler(db, mailchimpApi, cfg.JwtSigningKey),
+		handlers.NewEventHandler(db, cfg),
+		handlers.NewAuthHandler(db, mailchimpApi, cfg, googleKeys),
 		handlers.NewRegistrationHandler(db, cfg),
 		handlers.NewProfileHandler(db, mailchimpApi, cfg),
 		handlers.NewCompanyHandler(db, cfg),
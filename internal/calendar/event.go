@@ -0,0 +1,90 @@
+package calendar
+
+import (
+	"fmt"
+	"time"
+
+	"backend/internal/models"
+)
+
+const uidDomain = "kthais.com"
+
+// EventUID returns the stable iCalendar UID for an event
+func EventUID(id uint) string {
+	return fmt.Sprintf("event-%d@%s", id, uidDomain)
+}
+
+// FromEvent converts an event into a VEVENT. url is the public page of the
+// event and may be empty.
+func FromEvent(e models.Event, url string) Event {
+	end := e.EndDate
+	if end.Before(e.StartDate) {
+		end = time.Time{}
+	}
+	stamp := e.UpdatedAt
+	if stamp.IsZero() {
+		stamp = e.CreatedAt
+	}
+	return Event{
+		UID:          EventUID(e.ID),
+		Summary:      e.Title,
+		Description:  e.Description,
+		Location:     e.Location,
+		URL:          url,
+		Start:        e.StartDate,
+		End:          end,
+		Stamp:        stamp,
+		LastModified: e.UpdatedAt,
+		Status:       eventStatus(e.Status),
+	}
+}
+
+// eventStatus maps the status of an event to the iCalendar STATUS, so that
+// calendar clients strike through cancelled events instead of dropping them
+func eventStatus(s models.EventStatus) string {
+	if s == models.EventStatusCancelled {
+		return "CANCELLED"
+	}
+	return "CONFIRMED"
+}
+
+// SeriesUID returns the stable iCalendar UID for a recurring series
+func SeriesUID(id uint) string {
+	return fmt.Sprintf("series-%d@%s", id, uidDomain)
+}
+
+// FromSeries converts a recurring series into a single VEVENT with an RRULE.
+// Occurrences that were edited on their own should be added with
+// FromOccurrence so they override the generated ones.
+func FromSeries(s models.EventSeries, url string) Event {
+	stamp := s.UpdatedAt
+	if stamp.IsZero() {
+		stamp = s.CreatedAt
+	}
+	return Event{
+		UID:          SeriesUID(s.ID),
+		Summary:      s.Title,
+		Description:  s.Description,
+		Location:     s.Location,
+		URL:          url,
+		Start:        s.StartDate,
+		End:          s.EndDate,
+		Stamp:        stamp,
+		LastModified: s.UpdatedAt,
+		Status:       "CONFIRMED",
+		TZID:         models.SeriesTimeZone,
+		RRule:        s.Recurrence.RRule(),
+		ExDates:      s.Recurrence.Excluded(s.StartDate),
+	}
+}
+
+// FromOccurrence converts an occurrence of a series into an overriding VEVENT
+func FromOccurrence(e models.Event, url string) Event {
+	event := FromEvent(e, url)
+	if e.SeriesID != nil && e.OccurrenceDate != nil {
+		event.UID = SeriesUID(*e.SeriesID)
+		event.TZID = models.SeriesTimeZone
+		event.RecurrenceID = *e.OccurrenceDate
+	}
+	return event
+}
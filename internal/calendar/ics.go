@@ -0,0 +1,222 @@
+package calendar
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// this package renders events as iCalendar (RFC 5545) data so they can be
+// downloaded as .ics files or subscribed to from Google Calendar / Outlook.
+
+const (
+	productID = "-//KTH AI Society//Events//EN"
+	// RFC 5545 3.1: lines should not be longer than 75 octets, excluding the line break
+	maxLineOctets = 75
+	dateTimeUTC   = "20060102T150405Z"
+	dateTimeLocal = "20060102T150405"
+)
+
+// VTIMEZONE definitions for the time zones recurring events can use. Clients
+// need them to expand a recurrence rule in local time across DST changes.
+var timezones = map[string][]string{
+	"Europe/Stockholm": {
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/Stockholm",
+		"BEGIN:DAYLIGHT",
+		"TZOFFSETFROM:+0100",
+		"TZOFFSETTO:+0200",
+		"TZNAME:CEST",
+		"DTSTART:19700329T020000",
+		"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU",
+		"END:DAYLIGHT",
+		"BEGIN:STANDARD",
+		"TZOFFSETFROM:+0200",
+		"TZOFFSETTO:+0100",
+		"TZNAME:CET",
+		"DTSTART:19701025T030000",
+		"RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+	},
+}
+
+// Calendar is a VCALENDAR object containing any number of events
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// Event is a single VEVENT
+// UID must be stable across renders so that calendar clients replace the old
+// entry instead of adding a duplicate when an event is updated.
+type Event struct {
+	UID          string
+	Summary      string
+	Description  string
+	Location     string
+	URL          string
+	Start        time.Time
+	End          time.Time
+	Stamp        time.Time
+	LastModified time.Time
+	Status       string // TENTATIVE, CONFIRMED or CANCELLED
+
+	// recurring events
+	TZID         string // dates are written in local time of this zone, must be in timezones
+	RRule        string
+	ExDates      []time.Time
+	RecurrenceID time.Time // set on an occurrence that overrides one generated by RRule
+}
+
+// WriteTo writes the calendar to w with CRLF line endings, escaped text values
+// and folded lines.
+func (c *Calendar) WriteTo(w io.Writer) (int64, error) {
+	cw := &contentWriter{w: w}
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.line("PRODID:" + productID)
+	cw.line("CALSCALE:GREGORIAN")
+	cw.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		cw.line("X-WR-CALNAME:" + EscapeText(c.Name))
+	}
+	written := map[string]bool{}
+	for _, e := range c.Events {
+		if e.TZID == "" || written[e.TZID] {
+			continue
+		}
+		written[e.TZID] = true
+		for _, l := range timezones[e.TZID] {
+			cw.line(l)
+		}
+	}
+	for _, e := range c.Events {
+		e.write(cw)
+	}
+	cw.line("END:VCALENDAR")
+	return cw.n, cw.err
+}
+
+// String renders the calendar, mostly useful for tests and small payloads
+func (c *Calendar) String() string {
+	var sb strings.Builder
+	_, _ = c.WriteTo(&sb)
+	return sb.String()
+}
+
+func (e *Event) write(cw *contentWriter) {
+	cw.line("BEGIN:VEVENT")
+	cw.line("UID:" + EscapeText(e.UID))
+	cw.line("DTSTAMP:" + formatUTC(e.Stamp))
+	if !e.RecurrenceID.IsZero() {
+		cw.line(e.dateProperty("RECURRENCE-ID", e.RecurrenceID))
+	}
+	cw.line(e.dateProperty("DTSTART", e.Start))
+	if !e.End.IsZero() {
+		cw.line(e.dateProperty("DTEND", e.End))
+	}
+	if e.RRule != "" {
+		cw.line("RRULE:" + e.RRule)
+	}
+	for _, ex := range e.ExDates {
+		cw.line(e.dateProperty("EXDATE", ex))
+	}
+	cw.line("SUMMARY:" + EscapeText(e.Summary))
+	if e.Description != "" {
+		cw.line("DESCRIPTION:" + EscapeText(e.Description))
+	}
+	if e.Location != "" {
+		cw.line("LOCATION:" + EscapeText(e.Location))
+	}
+	if e.URL != "" {
+		cw.line("URL:" + e.URL)
+	}
+	if e.Status != "" {
+		cw.line("STATUS:" + e.Status)
+	}
+	if !e.LastModified.IsZero() {
+		cw.line("LAST-MODIFIED:" + formatUTC(e.LastModified))
+	}
+	cw.line("END:VEVENT")
+}
+
+// dateProperty formats a DATE-TIME property in UTC, or in local time with a
+// TZID parameter for events in a time zone
+func (e *Event) dateProperty(name string, t time.Time) string {
+	if e.TZID == "" {
+		return name + ":" + formatUTC(t)
+	}
+	loc, err := time.LoadLocation(e.TZID)
+	if err != nil {
+		return name + ":" + formatUTC(t)
+	}
+	return name + ";TZID=" + e.TZID + ":" + t.In(loc).Format(dateTimeLocal)
+}
+
+func formatUTC(t time.Time) string {
+	return t.UTC().Format(dateTimeUTC)
+}
+
+// EscapeText escapes a TEXT property value according to RFC 5545 3.3.11
+func EscapeText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\':
+			sb.WriteString(`\\`)
+		case ';':
+			sb.WriteString(`\;`)
+		case ',':
+			sb.WriteString(`\,`)
+		case '\n':
+			sb.WriteString(`\n`)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// FoldLine splits a content line into chunks of at most 75 octets, continuing
+// each chunk with CRLF followed by a single space. Multi-byte UTF-8 sequences
+// are never split.
+func FoldLine(line string) string {
+	if len(line) <= maxLineOctets {
+		return line
+	}
+	var sb strings.Builder
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		sb.WriteString(line[:cut])
+		sb.WriteString("\r\n ")
+		line = line[cut:]
+		// the leading space of a continuation line counts towards the limit
+		limit = maxLineOctets - 1
+	}
+	sb.WriteString(line)
+	return sb.String()
+}
+
+type contentWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *contentWriter) line(s string) {
+	if cw.err != nil {
+		return
+	}
+	n, err := fmt.Fprint(cw.w, FoldLine(s), "\r\n")
+	cw.n += int64(n)
+	cw.err = err
+}
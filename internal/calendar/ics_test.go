@@ -0,0 +1,148 @@
+package calendar
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// run `go test ./internal/calendar -update` to rewrite the golden files
+var update = flag.Bool("update", false, "update golden files")
+
+var (
+	created = time.Date(2025, 9, 1, 8, 0, 0, 0, time.UTC)
+	updated = time.Date(2025, 9, 2, 12, 30, 0, 0, time.UTC)
+
+	lecture = models.Event{
+		ID:          7,
+		Title:       "Intro to Transformers",
+		Description: "Bring a laptop; we'll cover attention, tokenizers, and more.\nSnacks provided!",
+		Location:    "Lecture hall F1, KTH Campus, Stockholm",
+		TypeOfEvent: models.EventTypeLecture,
+		StartDate:   time.Date(2025, 11, 3, 17, 0, 0, 0, time.UTC),
+		EndDate:     time.Date(2025, 11, 3, 19, 0, 0, 0, time.UTC),
+		CreatedAt:   created,
+		UpdatedAt:   updated,
+	}
+	hackathon = models.Event{
+		ID:    12,
+		Title: "KTHAIS Hackathon 2025 – Sustainable AI for Smart Cities, Energy & Mobility in Stockholm",
+		Description: "A 24 hour hackathon where teams build prototypes that use machine learning to " +
+			"reduce energy consumption. Prizes: 1st, 2nd & 3rd place. Åsa & Örjan from the " +
+			"sponsoring companies will be judging.",
+		Location:  "Digital Futures Hub; Osquars backe 5",
+		StartDate: time.Date(2025, 11, 15, 9, 0, 0, 0, time.FixedZone("CET", 3600)),
+		EndDate:   time.Date(2025, 11, 16, 9, 0, 0, 0, time.FixedZone("CET", 3600)),
+		CreatedAt: created,
+		UpdatedAt: updated,
+	}
+)
+
+func assertGolden(t *testing.T, name string, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	assert.Equal(t, string(want), got)
+}
+
+func TestSingleEventGolden(t *testing.T) {
+	cal := Calendar{Events: []Event{FromEvent(lecture, "https://kthais.com/events/7")}}
+	assertGolden(t, "single_event.ics", cal.String())
+}
+
+func TestFeedGolden(t *testing.T) {
+	cal := Calendar{
+		Name: "KTH AI Society, Events",
+		Events: []Event{
+			FromEvent(lecture, ""),
+			FromEvent(hackathon, "https://kthais.com/events/12"),
+		},
+	}
+	assertGolden(t, "feed.ics", cal.String())
+}
+
+func TestEscapeText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{"a,b;c", `a\,b\;c`},
+		{`back\slash`, `back\\slash`},
+		{"line1\r\nline2\nline3", `line1\nline2\nline3`},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, EscapeText(tt.in))
+	}
+}
+
+func TestFoldLine(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("åäö", 40)
+	folded := FoldLine(line)
+	for _, l := range strings.Split(folded, "\r\n") {
+		assert.LessOrEqual(t, len(l), maxLineOctets)
+		assert.True(t, strings.ToValidUTF8(l, "") == l, "fold split a multi-byte character")
+	}
+	assert.Equal(t, line, strings.ReplaceAll(folded, "\r\n ", ""))
+}
+
+func TestUIDIsStable(t *testing.T) {
+	changed := lecture
+	changed.Title = "Renamed"
+	changed.UpdatedAt = updated.Add(time.Hour)
+	assert.Equal(t, FromEvent(lecture, "").UID, FromEvent(changed, "").UID)
+}
+
+func TestSeriesGolden(t *testing.T) {
+	loc, _ := time.LoadLocation(models.SeriesTimeZone)
+	seriesID := uint(4)
+	series := models.EventSeries{
+		ID:          seriesID,
+		Title:       "Reading group: Deep Learning",
+		Description: "Weekly chapter discussion",
+		Location:    "KTH Library, room 2",
+		StartDate:   time.Date(2025, 10, 14, 17, 0, 0, 0, loc),
+		EndDate:     time.Date(2025, 10, 14, 18, 30, 0, 0, loc),
+		Recurrence: models.RecurrenceRule{
+			Frequency:  models.RecurrenceWeekly,
+			Count:      6,
+			Exclusions: []time.Time{time.Date(2025, 10, 28, 0, 0, 0, 0, loc)},
+		},
+		CreatedAt: created,
+		UpdatedAt: updated,
+	}
+	original := time.Date(2025, 11, 4, 17, 0, 0, 0, loc)
+	moved := models.Event{
+		ID:             21,
+		Title:          "Reading group: Deep Learning (guest lecture)",
+		Location:       "Lecture hall F1",
+		StartDate:      time.Date(2025, 11, 5, 18, 0, 0, 0, loc),
+		EndDate:        time.Date(2025, 11, 5, 20, 0, 0, 0, loc),
+		SeriesID:       &seriesID,
+		OccurrenceDate: &original,
+		Detached:       true,
+		CreatedAt:      created,
+		UpdatedAt:      updated,
+	}
+
+	cal := Calendar{Events: []Event{
+		FromSeries(series, "https://kthais.com/events/18"),
+		FromOccurrence(moved, "https://kthais.com/events/21"),
+	}}
+	assertGolden(t, "series.ics", cal.String())
+}
@@ -1,9 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strings"
+	"time"
 )
 
 type Config struct {
@@ -21,9 +23,11 @@ type Config struct {
 	OAuth struct {
 		GoogleClientID     string
 		GoogleClientSecret string
+		AllowedDomains     []string // hosted or email domains allowed to log in, with Google or by email, anyone if empty
 	}
 	AllowedOrigins []string
 	BackendURL     string
+	FrontendURL    string
 	Redis          struct {
 		Host     string
 		Port     string
@@ -38,6 +42,7 @@ type Config struct {
 		ListID string
 	}
 	JwtSigningKey    string
+	TicketSigningKey string // signs the tokens in ticket QR codes
 	R2_bucket_name   string
 	R2_access_key    string
 	R2_access_key_id string
@@ -49,6 +54,8 @@ type Config struct {
 		Sender  string
 		ReplyTo string
 	}
+	ReminderOffsets []time.Duration // how long before an event reminders are sent
+	SurveyDelay     time.Duration   // how long after an event ends attendees get the feedback survey
 }
 
 func LoadConfig() (*Config, error) {
@@ -79,6 +86,7 @@ func LoadConfig() (*Config, error) {
 	}
 
 	cfg.BackendURL = getEnv("BACKEND_URL", "http://localhost:8080")
+	cfg.FrontendURL = strings.TrimSuffix(getEnv("FRONTEND_URL", "http://localhost:3000"), "/")
 
 	// Mailchimp config
 	cfg.Mailchimp.APIKey = getEnv("MAILCHIMP_API_KEY", "")
@@ -88,11 +96,22 @@ func LoadConfig() (*Config, error) {
 	// OAuth config
 	cfg.OAuth.GoogleClientID = getEnv("GOOGLE_CLIENT_ID", "")
 	cfg.OAuth.GoogleClientSecret = getEnv("GOOGLE_CLIENT_SECRET", "")
+	// comma-separated, e.g. "kth.se,kthais.com"
+	for _, domain := range strings.Split(getEnv("GOOGLE_ALLOWED_DOMAINS", ""), ",") {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			cfg.OAuth.AllowedDomains = append(cfg.OAuth.AllowedDomains, domain)
+		}
+	}
 
 	cfg.SessionKey = getEnv("SESSION_KEY", "")
 	cfg.DevelopmentMode = getEnv("DEVELOPMENT", "true") == "true"
 
 	cfg.JwtSigningKey = getEnv("JWTSigningKey", "test1234566")
+	// tickets are signed with the JWT key unless they have a key of their own
+	cfg.TicketSigningKey = getEnv("TICKET_SIGNING_KEY", "")
+	if cfg.TicketSigningKey == "" {
+		cfg.TicketSigningKey = cfg.JwtSigningKey
+	}
 	//Cloudflare R2
 	cfg.R2_bucket_name = getEnv("R2_Bucket", "")
 	cfg.R2_access_key = getEnv("R2_Secret_Access_Key", "off key scraper")
@@ -119,6 +138,25 @@ func LoadConfig() (*Config, error) {
 		log.Println("Warning: Sender email is not set.")
 	}
 
+	// Event reminders, comma-separated durations such as "24h,1h"
+	for _, s := range strings.Split(getEnv("REMINDER_OFFSETS", "24h,1h"), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		offset, err := time.ParseDuration(s)
+		if err != nil || offset <= 0 {
+			return nil, fmt.Errorf("invalid REMINDER_OFFSETS entry %q", s)
+		}
+		cfg.ReminderOffsets = append(cfg.ReminderOffsets, offset)
+	}
+
+	// Feedback surveys, a duration such as "2h"
+	surveyDelay, err := time.ParseDuration(getEnv("SURVEY_DELAY", "1h"))
+	if err != nil || surveyDelay < 0 {
+		return nil, fmt.Errorf("invalid SURVEY_DELAY %q", getEnv("SURVEY_DELAY", "1h"))
+	}
+	cfg.SurveyDelay = surveyDelay
+
 	return cfg, nil
 }
 
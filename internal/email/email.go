@@ -61,11 +61,14 @@ func InitEmailService(cfg *config.Config) error {
 // Email data struct, contains all fields used in emails
 type EmailData struct {
 	EmailConfig
-	Profile  models.Profile
-	Event    models.Event // For event emails
-	URL      string       // For registration, password reset, and event emails
-	ImageURL string
-	Text     string // For custom text used in event survey and custom emails
+	Profile      models.Profile
+	Event        models.Event        // For event emails
+	Registration models.Registration // For registration confirmation emails
+	Counterpart  models.Profile      // For transfers, the member on the other end
+	URL          string              // For registration, password reset, and event emails
+	ImageURL     string
+	Text         string     // For custom text used in event survey and custom emails
+	Deadline     *time.Time // For offers that must be confirmed in time
 }
 
 // Helper function to create a new EmailData struct with default values
@@ -183,7 +186,7 @@ func SendRegistrationEmail(profile models.Profile, verificationURL string) error
 	return sendEmail(recipient, subject, htmlBody.String())
 }
 
-// Sends a login email
+// Sends a magic link to log in with
 //
 // Parameters:
 //   - profile: The profile struct for the recipient
@@ -191,7 +194,7 @@ func SendRegistrationEmail(profile models.Profile, verificationURL string) error
 //
 // Returns:
 //   - error: nil if the email was sent successfully, or an error if it failed
-func sendLoginEmail(profile models.Profile, loginURL string) error {
+func SendLoginEmail(profile models.Profile, loginURL string) error {
 	// Parse both base and password templates
 	tmpl, err := template.New("base").Funcs(templateFuncs).ParseFiles(
 		"templates/base.html",
@@ -215,20 +218,24 @@ func sendLoginEmail(profile models.Profile, loginURL string) error {
 
 	// Define email parameters
 	recipient := profile.Email
-	subject := "Reset Your KTHAIS Password"
+	subject := "Your KTHAIS login link"
 
 	return sendEmail(recipient, subject, htmlBody.String())
 }
 
-// Sends an event registration confirmation email
+// Sends an email confirming an event registration or telling the registrant
+// about its new status. Approved registrations come with the ticket QR code.
 //
 // Parameters:
 //   - profile: The profile struct for the recipient
 //   - event: The struct for the event
+//   - registration: The registration, its status decides the message
+//   - eventURL: The URL of the event page
+//   - ticketQRURL: The URL of the ticket QR code image (empty for no ticket)
 //
 // Returns:
 //   - error: nil if the email was sent successfully, or an error if it failed
-func sendEventRegistrationEmail(profile models.Profile, event models.Event) error {
+func SendEventRegistrationEmail(profile models.Profile, event models.Event, registration models.Registration, eventURL, ticketQRURL string) error {
 	// Parse both base and password templates
 	tmpl, err := template.New("base").Funcs(templateFuncs).ParseFiles(
 		"templates/base.html",
@@ -242,8 +249,9 @@ func sendEventRegistrationEmail(profile models.Profile, event models.Event) erro
 	data := newEmailData()
 	data.Profile = profile
 	data.Event = event
-	data.URL = "google.se" // Placeholder
-	data.Event = event
+	data.Registration = registration
+	data.URL = eventURL
+	data.ImageURL = ticketQRURL
 
 	// Render the template into a buffer
 	var htmlBody bytes.Buffer
@@ -254,20 +262,35 @@ func sendEventRegistrationEmail(profile models.Profile, event models.Event) erro
 
 	// Define email parameters
 	recipient := profile.Email
-	subject := "Your registration to " + event.Title
+	subject := registrationSubject(event, registration.Status)
 
 	return sendEmail(recipient, subject, htmlBody.String())
 }
 
+// registrationSubject returns the subject of a registration email
+func registrationSubject(event models.Event, status models.RegistrationStatus) string {
+	switch status {
+	case models.RegistrationStatusApproved:
+		return "You're going to " + event.Title + "!"
+	case models.RegistrationStatusWaitlisted:
+		return "You're on the waitlist for " + event.Title
+	case models.RegistrationStatusRejected:
+		return "Update on your registration to " + event.Title
+	default:
+		return "Your registration to " + event.Title
+	}
+}
+
 // Sends an event reminder email
 //
 // Parameters:
 //   - profile: The profile struct for the recipient
 //   - event: The struct for the event
+//   - eventURL: The URL of the event page
 //
 // Returns:
 //   - error: nil if the email was sent successfully, or an error if it failed
-func sendEventReminderEmail(profile models.Profile, event models.Event) error {
+func SendEventReminderEmail(profile models.Profile, event models.Event, eventURL string) error {
 	// Parse both base and password templates
 	tmpl, err := template.New("base").Funcs(templateFuncs).ParseFiles(
 		"templates/base.html",
@@ -281,8 +304,7 @@ func sendEventReminderEmail(profile models.Profile, event models.Event) error {
 	data := newEmailData()
 	data.Profile = profile
 	data.Event = event
-	data.URL = "google.se" // Placeholder
-	data.Event = event
+	data.URL = eventURL
 
 	// Render the template into a buffer
 	var htmlBody bytes.Buffer
@@ -298,15 +320,16 @@ func sendEventReminderEmail(profile models.Profile, event models.Event) error {
 	return sendEmail(recipient, subject, htmlBody.String())
 }
 
-// Sends an event cancelation email
+// Sends an email telling a registrant that an event has been cancelled
 //
 // Parameters:
 //   - profile: The profile struct for the recipient
 //   - event: The struct for the event
+//   - frontendURL: The URL of the frontend, the button links to its events page
 //
 // Returns:
 //   - error: nil if the email was sent successfully, or an error if it failed
-func sendEventCancelEmail(profile models.Profile, event models.Event) error {
+func SendEventCancelEmail(profile models.Profile, event models.Event, frontendURL string) error {
 	// Parse both base and password templates
 	tmpl, err := template.New("base").Funcs(templateFuncs).ParseFiles(
 		"templates/base.html",
@@ -320,8 +343,48 @@ func sendEventCancelEmail(profile models.Profile, event models.Event) error {
 	data := newEmailData()
 	data.Profile = profile
 	data.Event = event
-	data.URL = "google.se" // Placeholder
+	data.URL = frontendURL
+
+	// Render the template into a buffer
+	var htmlBody bytes.Buffer
+	err = tmpl.ExecuteTemplate(&htmlBody, "base", data)
+	if err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	// Define email parameters
+	recipient := profile.Email
+	subject := event.Title + " has been cancelled"
+
+	return sendEmail(recipient, subject, htmlBody.String())
+}
+
+// Sends an email telling a user that they got a spot from the waitlist
+//
+// Parameters:
+//   - profile: The profile struct for the recipient
+//   - event: The struct for the event
+//   - eventURL: The URL of the event page
+//   - deadline: When the spot must be confirmed by (nil if no confirmation is needed)
+//
+// Returns:
+//   - error: nil if the email was sent successfully, or an error if it failed
+func SendWaitlistPromotionEmail(profile models.Profile, event models.Event, eventURL string, deadline *time.Time) error {
+	// Parse both base and waitlist templates
+	tmpl, err := template.New("base").Funcs(templateFuncs).ParseFiles(
+		"templates/base.html",
+		"templates/event/waitlist.html",
+	)
+	if err != nil {
+		return fmt.Errorf("failed to parse templates: %w", err)
+	}
+
+	// Prepare data for the email template
+	data := newEmailData()
+	data.Profile = profile
 	data.Event = event
+	data.URL = eventURL
+	data.Deadline = deadline
 
 	// Render the template into a buffer
 	var htmlBody bytes.Buffer
@@ -332,7 +395,130 @@ func sendEventCancelEmail(profile models.Profile, event models.Event) error {
 
 	// Define email parameters
 	recipient := profile.Email
-	subject := "Remember " + event.Title + "?"
+	subject := "You got a spot at " + event.Title
+
+	return sendEmail(recipient, subject, htmlBody.String())
+}
+
+// Sends an attendee the link to the feedback survey of an event
+//
+// Parameters:
+//   - profile: The profile struct for the recipient
+//   - event: The struct for the event
+//   - surveyURL: The personal URL of the survey
+//
+// Returns:
+//   - error: nil if the email was sent successfully, or an error if it failed
+func SendEventSurveyEmail(profile models.Profile, event models.Event, surveyURL string) error {
+	// Parse both base and survey templates
+	tmpl, err := template.New("base").Funcs(templateFuncs).ParseFiles(
+		"templates/base.html",
+		"templates/event/survey.html",
+	)
+	if err != nil {
+		return fmt.Errorf("failed to parse templates: %w", err)
+	}
+
+	// Prepare data for the email template
+	data := newEmailData()
+	data.Profile = profile
+	data.Event = event
+	data.URL = surveyURL
+
+	// Render the template into a buffer
+	var htmlBody bytes.Buffer
+	err = tmpl.ExecuteTemplate(&htmlBody, "base", data)
+	if err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	// Define email parameters
+	recipient := profile.Email
+	subject := "How was " + event.Title + "?"
+
+	return sendEmail(recipient, subject, htmlBody.String())
+}
+
+// Sends a member the offer of someone else's spot at an event
+//
+// Parameters:
+//   - profile: The profile struct for the recipient
+//   - event: The struct for the event
+//   - from: The profile of the member giving away their spot
+//   - acceptURL: The URL to accept the spot with
+//   - deadline: When the offer closes
+//
+// Returns:
+//   - error: nil if the email was sent successfully, or an error if it failed
+func SendTransferOfferEmail(profile models.Profile, event models.Event, from models.Profile, acceptURL string, deadline *time.Time) error {
+	// Parse both base and transfer templates
+	tmpl, err := template.New("base").Funcs(templateFuncs).ParseFiles(
+		"templates/base.html",
+		"templates/event/transfer_offer.html",
+	)
+	if err != nil {
+		return fmt.Errorf("failed to parse templates: %w", err)
+	}
+
+	// Prepare data for the email template
+	data := newEmailData()
+	data.Profile = profile
+	data.Event = event
+	data.Counterpart = from
+	data.URL = acceptURL
+	data.Deadline = deadline
+
+	// Render the template into a buffer
+	var htmlBody bytes.Buffer
+	err = tmpl.ExecuteTemplate(&htmlBody, "base", data)
+	if err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	// Define email parameters
+	recipient := profile.Email
+	subject := from.FirstName + " wants to give you their spot at " + event.Title
+
+	return sendEmail(recipient, subject, htmlBody.String())
+}
+
+// Sends an email telling a member that their spot at an event was taken over
+//
+// Parameters:
+//   - profile: The profile struct for the recipient
+//   - event: The struct for the event
+//   - to: The profile of the member who took over the spot
+//   - frontendURL: The URL of the frontend, the button links to its events page
+//
+// Returns:
+//   - error: nil if the email was sent successfully, or an error if it failed
+func SendTransferDoneEmail(profile models.Profile, event models.Event, to models.Profile, frontendURL string) error {
+	// Parse both base and transfer templates
+	tmpl, err := template.New("base").Funcs(templateFuncs).ParseFiles(
+		"templates/base.html",
+		"templates/event/transfer_done.html",
+	)
+	if err != nil {
+		return fmt.Errorf("failed to parse templates: %w", err)
+	}
+
+	// Prepare data for the email template
+	data := newEmailData()
+	data.Profile = profile
+	data.Event = event
+	data.Counterpart = to
+	data.URL = frontendURL
+
+	// Render the template into a buffer
+	var htmlBody bytes.Buffer
+	err = tmpl.ExecuteTemplate(&htmlBody, "base", data)
+	if err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	// Define email parameters
+	recipient := profile.Email
+	subject := "Your spot at " + event.Title + " was transferred"
 
 	return sendEmail(recipient, subject, htmlBody.String())
 }
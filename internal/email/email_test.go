@@ -77,25 +77,55 @@ func TestSendRegistrationEmail(t *testing.T) {
 }
 
 func TestSendLoginEmail(t *testing.T) {
-	passwordResetURL := "http://kthais.com"
+	loginURL := "http://kthais.com/auth/magic-link?token=abc"
 
-	err := sendLoginEmail(mockProfile, passwordResetURL)
-	assert.Nil(t, err, "sendLoginEmail should not return an error")
+	err := SendLoginEmail(mockProfile, loginURL)
+	assert.Nil(t, err, "SendLoginEmail should not return an error")
 }
 
 func TestSendEventRegistrationEmail(t *testing.T) {
-	err := sendEventRegistrationEmail(mockProfile, mockEvent)
-	assert.Nil(t, err, "sendEventRegistrationEmail should not return an error")
+	for _, status := range []models.RegistrationStatus{
+		models.RegistrationStatusPending,
+		models.RegistrationStatusWaitlisted,
+		models.RegistrationStatusRejected,
+	} {
+		registration := models.Registration{Status: status, WaitlistPosition: 3}
+		err := SendEventRegistrationEmail(mockProfile, mockEvent, registration, "https://kthais.com/events/1", "")
+		assert.Nil(t, err, "SendEventRegistrationEmail should not return an error")
+	}
+
+	approved := models.Registration{Status: models.RegistrationStatusApproved}
+	err := SendEventRegistrationEmail(mockProfile, mockEvent, approved, "https://kthais.com/events/1", "https://kthais.com/ticket.png")
+	assert.Nil(t, err, "SendEventRegistrationEmail should not return an error")
 }
 
 func TestSendEventReminderEmail(t *testing.T) {
-	err := sendEventReminderEmail(mockProfile, mockEvent)
-	assert.Nil(t, err, "sendEventReminderEmail should not return an error")
+	err := SendEventReminderEmail(mockProfile, mockEvent, "https://kthais.com/events/1")
+	assert.Nil(t, err, "SendEventReminderEmail should not return an error")
 }
 
 func TestSendEventCancelEmail(t *testing.T) {
-	err := sendEventCancelEmail(mockProfile, mockEvent)
-	assert.Nil(t, err, "sendEventCancelEmail should not return an error")
+	err := SendEventCancelEmail(mockProfile, mockEvent, "https://kthais.com")
+	assert.Nil(t, err, "SendEventCancelEmail should not return an error")
+}
+
+func TestSendWaitlistPromotionEmail(t *testing.T) {
+	deadline := time.Now().Add(24 * time.Hour)
+	err := SendWaitlistPromotionEmail(mockProfile, mockEvent, "https://kthais.com", &deadline)
+	assert.Nil(t, err, "SendWaitlistPromotionEmail should not return an error")
+}
+
+func TestSendEventSurveyEmail(t *testing.T) {
+	err := SendEventSurveyEmail(mockProfile, mockEvent, "https://kthais.com/surveys/token")
+	assert.Nil(t, err, "SendEventSurveyEmail should not return an error")
+}
+
+func TestSendTransferEmails(t *testing.T) {
+	deadline := time.Now().Add(24 * time.Hour)
+	err := SendTransferOfferEmail(mockProfile, mockEvent, mockProfile, "https://kthais.com/transfers/token", &deadline)
+	assert.Nil(t, err, "SendTransferOfferEmail should not return an error")
+	err = SendTransferDoneEmail(mockProfile, mockEvent, mockProfile, "https://kthais.com")
+	assert.Nil(t, err, "SendTransferDoneEmail should not return an error")
 }
 
 func TestSendCustomEmail(t *testing.T) {
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// attendanceWindow reads the window_days query parameter, how many days back
+// attendance is looked at
+func attendanceWindow(c *gin.Context) (time.Time, time.Time, bool) {
+	until := time.Now()
+	window := models.DefaultAttendanceWindow
+	if param := c.Query("window_days"); param != "" {
+		days, err := strconv.Atoi(param)
+		if err != nil || days <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "window_days must be a positive number"})
+			return until, until, false
+		}
+		window = time.Duration(days) * 24 * time.Hour
+	}
+	return until.Add(-window), until, true
+}
+
+// checkNoShowPolicy applies the no-show policy of an event to a user who is
+// about to get a spot: registrants who often don't show up are turned away or
+// put last in line. It responds with an error and returns false if they are
+// turned away, otherwise it returns whether they are deprioritized.
+func (h *RegistrationHandler) checkNoShowPolicy(c *gin.Context, event models.Event, userID uint) (bool, bool) {
+	stats, applies, err := models.CheckNoShowPolicy(h.db, event, userID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false, false
+	}
+	if applies && event.NoShowPolicy.Action == models.NoShowBlock {
+		missed, total := event.NoShowPolicy.Missed(stats)
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf(
+			"You missed %d of the %d events you had a spot at recently, this event only accepts registrants "+
+				"who missed at most %.0f%%. Contact the organizers if you think this is a mistake.",
+			missed, total, event.NoShowPolicy.MaxRate*100)})
+		return false, false
+	}
+	return applies, true
+}
+
+// ListAttendance returns the attendance of everyone who had a spot at an
+// event in the window, worst no-show rate first. min_rate leaves out users
+// with a lower no-show rate.
+func (h *RegistrationHandler) ListAttendance(c *gin.Context) {
+	since, until, ok := attendanceWindow(c)
+	if !ok {
+		return
+	}
+	minRate := 0.0
+	if param := c.Query("min_rate"); param != "" {
+		var err error
+		if minRate, err = strconv.ParseFloat(param, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_rate must be a number"})
+			return
+		}
+	}
+
+	stats, err := models.AttendanceOfUsers(h.db, since, until)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	filtered := make([]models.AttendanceStats, 0, len(stats))
+	for _, s := range stats {
+		if s.NoShowRate >= minRate {
+			filtered = append(filtered, s)
+		}
+	}
+	c.JSON(http.StatusOK, filtered)
+}
+
+// GetAttendance returns the attendance of one user along with their
+// overrides of the no-show policy
+func (h *RegistrationHandler) GetAttendance(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	since, until, ok := attendanceWindow(c)
+	if !ok {
+		return
+	}
+
+	stats, err := models.UserAttendance(h.db, uint(userID), since, until)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var overrides []models.PolicyOverride
+	if err := h.db.Where("user_id = ?", userID).Order("id").Find(&overrides).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"attendance": stats, "overrides": overrides})
+}
+
+// CreatePolicyOverride exempts a user from the no-show policy of an event,
+// or of every event if no event is given
+func (h *RegistrationHandler) CreatePolicyOverride(c *gin.Context) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		UserID  uint   `json:"user_id" binding:"required"`
+		EventID *uint  `json:"event_id"`
+		Reason  string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.db.First(&models.User{}, input.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if input.EventID != nil {
+		if err := h.db.First(&models.Event{}, *input.EventID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+	}
+
+	override := models.PolicyOverride{
+		UserID:    input.UserID,
+		EventID:   input.EventID,
+		Reason:    input.Reason,
+		CreatedBy: adminID,
+	}
+	if err := h.db.Create(&override).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, override)
+}
+
+// DeletePolicyOverride puts a user back under the no-show policy
+func (h *RegistrationHandler) DeletePolicyOverride(c *gin.Context) {
+	var override models.PolicyOverride
+	if err := h.db.First(&override, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Override not found"})
+		return
+	}
+	if err := h.db.Delete(&override).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Override deleted"})
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newNoShow creates a user who didn't show up to two of three past events.
+// Someone else was checked in at each of them, so attendance was taken.
+func newNoShow(t *testing.T, db *gorm.DB) models.User {
+	t.Helper()
+	user := newTestUser(t, db, "flaky@kthais.com")
+	punctual := newTestUser(t, db, "always-there@kthais.com")
+	for i, attended := range []bool{false, true, false} {
+		start := time.Now().Add(-time.Duration(i+1) * 24 * time.Hour)
+		event := models.Event{Title: "Lecture", StartDate: start, EndDate: start.Add(2 * time.Hour)}
+		require.NoError(t, db.Create(&event).Error)
+		require.NoError(t, db.Create(&models.Registration{EventID: event.ID, UserID: user.ID,
+			Status: models.RegistrationStatusApproved, Attended: attended}).Error)
+		require.NoError(t, db.Create(&models.Registration{EventID: event.ID, UserID: punctual.ID,
+			Status: models.RegistrationStatusApproved, Attended: true, CheckedInAt: &start}).Error)
+	}
+	return user
+}
+
+func TestNoShowPolicyBlocks(t *testing.T) {
+	db := newTestDB(t)
+	captureRegistrationEmails(t, nil)
+	h := NewRegistrationHandler(db, newTestConfig())
+	flaky := newNoShow(t, db)
+	event := models.Event{Title: "Hackathon", NoShowPolicy: models.NoShowPolicy{Action: models.NoShowBlock, MaxRate: 0.5}}
+	require.NoError(t, db.Create(&event).Error)
+	register := fmt.Sprintf("/register/%d", event.ID)
+
+	w := doRequest(actingAs(h, flaky.ID), testRequest{method: http.MethodPost, path: register})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "2 of the 3 events")
+
+	admin := actingAs(h, newTestUser(t, db, "admin@kthais.com").ID)
+	w = doRequest(admin, testRequest{method: http.MethodPost, path: "/admin/overrides",
+		body: gin.H{"user_id": flaky.ID, "event_id": event.ID, "reason": "was ill"}})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	override := decodeBody[models.PolicyOverride](t, w)
+	assert.NotZero(t, override.CreatedBy)
+
+	w = doRequest(actingAs(h, flaky.ID), testRequest{method: http.MethodPost, path: register})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.False(t, decodeBody[models.Registration](t, w).Deprioritized)
+
+	w = doRequest(admin, testRequest{method: http.MethodDelete, path: fmt.Sprintf("/admin/overrides/%d", override.ID)})
+	assert.Equal(t, http.StatusOK, w.Code)
+	exempt, err := models.HasPolicyOverride(db, flaky.ID, event.ID)
+	require.NoError(t, err)
+	assert.False(t, exempt)
+}
+
+func TestNoShowPolicyDeprioritizes(t *testing.T) {
+	db := newTestDB(t)
+	captureRegistrationEmails(t, nil)
+	h := NewRegistrationHandler(db, newTestConfig())
+	flaky := newNoShow(t, db)
+	event := models.Event{Title: "Workshop", RegistrationMax: 1,
+		NoShowPolicy: models.NoShowPolicy{Action: models.NoShowDeprioritize, MaxRate: 0.5}}
+	require.NoError(t, db.Create(&event).Error)
+	seedBulkRegistrations(t, db, event, models.RegistrationStatusApproved)
+	register := fmt.Sprintf("/register/%d", event.ID)
+
+	w := doRequest(actingAs(h, flaky.ID), testRequest{method: http.MethodPost, path: register})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	registration := decodeBody[models.Registration](t, w)
+	assert.True(t, registration.Deprioritized)
+	assert.Equal(t, 1, registration.WaitlistPosition)
+
+	// whoever comes later still goes ahead of them
+	punctual := newTestUser(t, db, "punctual@kthais.com")
+	w = doRequest(actingAs(h, punctual.ID), testRequest{method: http.MethodPost, path: register})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, 1, decodeBody[models.Registration](t, w).WaitlistPosition)
+	position, err := models.WaitlistPosition(db, registration)
+	require.NoError(t, err)
+	assert.Equal(t, 2, position)
+}
+
+func TestAttendanceStats(t *testing.T) {
+	db := newTestDB(t)
+	h := NewRegistrationHandler(db, newTestConfig())
+	flaky := newNoShow(t, db)
+	admin := actingAs(h, 0)
+
+	w := doRequest(admin, testRequest{method: http.MethodGet, path: fmt.Sprintf("/admin/attendance/%d", flaky.ID)})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	body := decodeBody[struct {
+		Attendance models.AttendanceStats
+		Overrides  []models.PolicyOverride
+	}](t, w)
+	assert.Equal(t, 3, body.Attendance.Registered)
+	assert.Equal(t, 2, body.Attendance.NoShows)
+	assert.Empty(t, body.Overrides)
+
+	// the last two days only have an event they missed
+	w = doRequest(admin, testRequest{method: http.MethodGet, path: fmt.Sprintf("/admin/attendance/%d?window_days=2", flaky.ID)})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.InDelta(t, 1.0, decodeBody[struct{ Attendance models.AttendanceStats }](t, w).Attendance.NoShowRate, 0.001)
+
+	w = doRequest(admin, testRequest{method: http.MethodGet, path: "/admin/attendance?min_rate=0.5"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, decodeBody[[]models.AttendanceStats](t, w), 1)
+	w = doRequest(admin, testRequest{method: http.MethodGet, path: "/admin/attendance?min_rate=0.9"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, decodeBody[[]models.AttendanceStats](t, w))
+
+	w = doRequest(admin, testRequest{method: http.MethodGet, path: "/admin/attendance?window_days=soon"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
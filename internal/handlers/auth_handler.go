@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -14,7 +16,9 @@ import (
 	"backend/internal/utils"
 
 	"backend/internal/config"
+	"backend/internal/database"
 	"backend/internal/middleware"
+	"backend/internal/refresh"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
@@ -28,13 +32,38 @@ import (
 type AuthHandler struct {
 	db            *gorm.DB
 	mailchimp     *mailchimp.MailchimpAPI
+	cfg           *config.Config
 	jwtSigningKey string
+	refresh       *refresh.Manager
+	googleKeys    utils.KeySource // verify Google ID tokens
+
+	// limit magic link requests, nil without Redis
+	ipLimiter    middleware.Limiter
+	emailLimiter middleware.Limiter
 }
 
-func NewAuthHandler(db *gorm.DB, mailchimp *mailchimp.MailchimpAPI, skey string) *AuthHandler {
-	return &AuthHandler{db: db, mailchimp: mailchimp, jwtSigningKey: skey}
+func NewAuthHandler(db *gorm.DB, mailchimp *mailchimp.MailchimpAPI, cfg *config.Config, googleKeys utils.KeySource) *AuthHandler {
+	h := &AuthHandler{db: db, mailchimp: mailchimp, cfg: cfg, jwtSigningKey: cfg.JwtSigningKey, googleKeys: googleKeys}
+	// refresh tokens and magic link limits are kept in Redis, nobody can log in without it
+	client, err := database.GetRedisClient(cfg)
+	if err == nil {
+		err = client.Ping(context.Background()).Err()
+	}
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis at %s:%s, it is required for logins: %v", cfg.Redis.Host, cfg.Redis.Port, err)
+	}
+	h.refresh = refresh.NewManager(refresh.NewRedisStore(client), refresh.TTL)
+	h.ipLimiter = middleware.NewRateLimiter(client, magicLinksPerIP, time.Hour)
+	h.emailLimiter = middleware.NewRateLimiter(client, magicLinksPerEmail, models.LoginTokenTTL)
+	return h
 }
 
+const (
+	accessTokenMinutes = 15
+	refreshCookie      = "refresh_token"
+	refreshCookiePath  = "/api/v1/auth"
+)
+
 // Update Register method to match the Handler interface
 func (h *AuthHandler) Register(r *gin.RouterGroup) {
 	auth := r.Group("/auth")
@@ -47,6 +76,9 @@ func (h *AuthHandler) Register(r *gin.RouterGroup) {
 			oauth.GET("/google/callback", h.GoogleCallback)
 		}
 
+		auth.POST("/magic-link", h.RequestMagicLink)
+		auth.POST("/magic-link/verify", h.VerifyMagicLink)
+
 		// Keep only these essential routes
 		auth.GET("/status", h.Status)
 		auth.GET("/refresh_token", h.RefreshToken)
@@ -79,23 +111,40 @@ func isOriginAllowed(origin, allowedOrigin string) bool {
 	return origin == allowedOrigin
 }
 
-// viv - usually a separate refresh token is used but I don't know why that is necessary
+// RefreshToken exchanges the refresh token cookie for a new JWT and the next
+// refresh token. Roles changed since the last refresh take effect.
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	old_token := utils.GetJWT(c)
-	claims := utils.GetClaims(old_token)
-	userId, err := uuid.Parse(claims["user_id"].(string))
+	token, err := c.Cookie(refreshCookie)
 	if err != nil {
-		log.Printf("Refresh failed\n")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to parse user id"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
+	next, userID, err := h.refresh.Rotate(c.Request.Context(), token)
+	if errors.Is(err, refresh.ErrTokenReused) {
+		log.Printf("Refresh token reused, logging out user %s", userID)
+	}
+	if errors.Is(err, refresh.ErrTokenReused) || errors.Is(err, refresh.ErrInvalidToken) {
+		h.clearCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
 	var user models.User
-	result := h.db.Where("user_id = ?", userId).First(&user)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not retreive user info"})
+	if err := h.db.Where("user_id = ?", userID).First(&user).Error; err != nil {
+		// the account is gone, so is the login
+		if err := h.refresh.Revoke(c.Request.Context(), next); err != nil {
+			log.Printf("Failed to revoke refresh token: %v", err)
+		}
+		h.clearCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
 	}
-	newToken := utils.WriteJWT(user.Email, user.Roles, user.UserId, h.jwtSigningKey, 15)
-	c.SetCookie("jwt", newToken, 3600, "/", "localhost:3000", false, false)
+	h.setCookies(c, user, next)
+	c.JSON(http.StatusOK, gin.H{"message": "Token refreshed"})
 }
 
 func InitAuth(cfg *config.Config) error {
@@ -132,9 +181,7 @@ func InitAuth(cfg *config.Config) error {
 }
 
 func (h *AuthHandler) Status(c *gin.Context) {
-	token_str := utils.GetJWTString(c)
-	valid, _ := utils.ParseAndVerify(token_str, h.jwtSigningKey)
-	if !valid {
+	if _, ok := middleware.Authenticate(c, h.cfg); !ok {
 		c.JSON(401, gin.H{"authenticate": false})
 	} else {
 		c.JSON(200, gin.H{"authenticate": true})
@@ -261,15 +308,20 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 		return
 	}
 	gSession := gothSession.(*google.Session)
-	// parse token here
-	valid, token := utils.ParseAndVerifyGoogle(gSession.IDToken)
-	if token == nil {
-		log.Printf("Error parsing google jwt: %v\n", gSession.IDToken)
+	token_data, err := utils.VerifyGoogleIDToken(c.Request.Context(), h.googleKeys, gSession.IDToken, utils.GoogleTokenChecks{
+		ClientID:       h.cfg.OAuth.GoogleClientID,
+		AllowedDomains: h.cfg.OAuth.AllowedDomains,
+	})
+	if errors.Is(err, utils.ErrDomainNotAllowed) {
+		log.Printf("Rejected Google login: %v", err)
+		redirectWithError(c, "Logging in is only open to accounts of the allowed domains")
+		return
 	}
-	if !valid {
-		log.Printf("Invalid Google Token\n")
+	if err != nil {
+		log.Printf("Invalid Google token: %v", err)
+		redirectWithError(c, "Authentication failed")
+		return
 	}
-	token_data := utils.GetClaims(token)
 	// log.Printf("Google ID: %v\n", gSession.IDToken)
 	// log.Printf("Google Access: %v\n", gSession.AccessToken)
 	// Extract name from RawData
@@ -301,47 +353,13 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 			}
 		}
 	}
-	// Check if user exists
-	var user models.User
-	result := h.db.Where("email = ?", email).First(&user)
-
-	if result.Error == gorm.ErrRecordNotFound {
-		// Create new user
-		user = models.User{
-			Email:     email,
-			Provider:  "google",
-			Roles:     []string{"user"},
-			UserId:    uuid.New(),
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		}
-
-		if err := h.db.Create(&user).Error; err != nil {
-			log.Printf("Failed to create user: %v", err)
-			redirectWithError(c, "Failed to create account")
-			return
-		}
-	} else if result.Error != nil {
-		// Database error (not "record not found")
-		log.Printf("Database error: %v", result.Error)
-		redirectWithError(c, "Database error")
+	user, err := findOrCreateUser(h.db, email, "google")
+	if err != nil {
+		log.Printf("Failed to find or create user: %v", err)
+		redirectWithError(c, "Failed to create account")
 		return
 	}
-	// If no error, user already exists and was loaded successfully
-
-	// Check if profile exists
-	var profile models.Profile
-	profileExists := h.db.Where("user_id = ?", user.UserId).First(&profile).Error == nil
-	if !profileExists {
-		profile.UserID = user.UserId
-		profile.Email = user.Email
-		profile.FirstName = firstName
-		profile.LastName = lastName
-		profile.Registered = false
-		if err := h.db.Create(&profile).Error; err != nil {
-			log.Printf("Failed to create profile for user: %v\n", profile)
-		}
-	}
+	profile := ensureProfile(h.db, user, firstName, lastName)
 
 	// Set session for the user
 	session = sessions.Default(c)
@@ -357,7 +375,7 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 
 	// Redirect based on whether profile exists
 	var dashboardURL string
-	if profileExists && profile.Registered {
+	if profile.Registered {
 		// Profile exists, redirect to dashboard
 		dashboardURL = fmt.Sprintf("%s/dashboard?auth=success", frontendURL)
 	} else {
@@ -371,11 +389,77 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 	// } else {
 	// 	roles = []string{"user"}
 	// }
-	authJwt := utils.WriteJWT(email, user.Roles, user.UserId, h.jwtSigningKey, 15)
-	c.SetCookie("jwt", authJwt, 3600, "/", "localhost:3000", false, false)
+	if err := h.logIn(c, user); err != nil {
+		log.Printf("Failed to issue refresh token: %v", err)
+		redirectWithError(c, "Failed to create session")
+		return
+	}
 	c.Redirect(http.StatusTemporaryRedirect, dashboardURL)
 }
 
+// findOrCreateUser returns the user with an email address, signing them up
+// with the provider if there is none yet
+func findOrCreateUser(db *gorm.DB, email, provider string) (models.User, error) {
+	var user models.User
+	err := db.Where("email = ?", email).First(&user).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, err
+	}
+	user = models.User{
+		Email:     email,
+		Provider:  provider,
+		Roles:     []string{models.RoleUser},
+		UserId:    uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	err = db.Create(&user).Error
+	return user, err
+}
+
+// ensureProfile returns the profile of a user, creating an unregistered one
+// if they have none. The user completes it when registering.
+func ensureProfile(db *gorm.DB, user models.User, firstName, lastName string) models.Profile {
+	var profile models.Profile
+	if db.Where("user_id = ?", user.UserId).First(&profile).Error == nil {
+		return profile
+	}
+	profile = models.Profile{
+		UserID:     user.UserId,
+		Email:      user.Email,
+		FirstName:  firstName,
+		LastName:   lastName,
+		Registered: false,
+	}
+	if err := db.Create(&profile).Error; err != nil {
+		log.Printf("Failed to create profile for user: %v\n", profile)
+	}
+	return profile
+}
+
+// logIn starts a new login of the user, with a short-lived JWT and a refresh
+// token to get new ones with
+func (h *AuthHandler) logIn(c *gin.Context, user models.User) error {
+	token, err := h.refresh.Issue(c.Request.Context(), user.UserId)
+	if err != nil {
+		return err
+	}
+	h.setCookies(c, user, token)
+	return nil
+}
+
+func (h *AuthHandler) setCookies(c *gin.Context, user models.User, refreshToken string) {
+	authJwt := utils.WriteJWT(user.Email, user.Roles, user.UserId, h.jwtSigningKey, accessTokenMinutes)
+	c.SetCookie("jwt", authJwt, 3600, "/", "localhost:3000", false, false)
+	// only ever sent to the auth routes and never readable by scripts
+	c.SetCookie(refreshCookie, refreshToken, int(refresh.TTL.Seconds()), refreshCookiePath, "", !h.cfg.DevelopmentMode, true)
+}
+
+func (h *AuthHandler) clearCookies(c *gin.Context) {
+	c.SetCookie("jwt", "", -1, "/", "localhost:3000", false, false)
+	c.SetCookie(refreshCookie, "", -1, refreshCookiePath, "", !h.cfg.DevelopmentMode, true)
+}
+
 // Update the redirectWithError function to use the frontend URL from state
 func redirectWithError(c *gin.Context, message string) {
 	// Get the state from the query parameters
@@ -397,6 +481,13 @@ func redirectWithError(c *gin.Context, message string) {
 }
 
 func (h *AuthHandler) Logout(c *gin.Context) {
+	if token, err := c.Cookie(refreshCookie); err == nil {
+		if err := h.refresh.Revoke(c.Request.Context(), token); err != nil {
+			log.Printf("Failed to revoke refresh token: %v", err)
+		}
+	}
+	h.clearCookies(c)
+
 	session := sessions.Default(c)
 	session.Clear()
 	session.Save()
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"backend/internal/utils"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newAuthRouter(db *gorm.DB) (*gin.Engine, *AuthHandler) {
+	h := newTestAuthHandler(db)
+	r := gin.New()
+	r.Use(sessions.Sessions("test_session", cookie.NewStore([]byte("test-session-key"))))
+	r.GET("/auth/refresh_token", h.RefreshToken)
+	r.GET("/auth/logout", h.Logout)
+	return r, h
+}
+
+// responseCookie returns the value of a cookie set by a response, empty if it
+// was cleared
+func responseCookie(t *testing.T, resp *http.Response, name string) string {
+	t.Helper()
+	for _, c := range resp.Cookies() {
+		if c.Name == name {
+			return c.Value
+		}
+	}
+	t.Fatalf("cookie %s was not set", name)
+	return ""
+}
+
+func refreshWith(r http.Handler, token string) *http.Response {
+	w := doRequest(r, testRequest{method: http.MethodGet, path: "/auth/refresh_token",
+		cookies: []*http.Cookie{{Name: refreshCookie, Value: token}}})
+	return w.Result()
+}
+
+func TestRefreshTokenRotates(t *testing.T) {
+	db := newTestDB(t)
+	r, h := newAuthRouter(db)
+	user := newTestUser(t, db, "ada@kthais.com")
+	first, err := h.refresh.Issue(context.Background(), user.UserId)
+	require.NoError(t, err)
+
+	resp := refreshWith(r, first)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, user.UserId.String(), jwtClaims(t, resp)["user_id"])
+	second := responseCookie(t, resp, refreshCookie)
+	assert.NotEqual(t, first, second)
+
+	resp = refreshWith(r, second)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	third := responseCookie(t, resp, refreshCookie)
+
+	// a stolen token is replayed: the whole login is revoked
+	resp = refreshWith(r, first)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Empty(t, responseCookie(t, resp, refreshCookie))
+	assert.Equal(t, http.StatusUnauthorized, refreshWith(r, third).StatusCode)
+}
+
+func TestRefreshTokenRejectsForgedTokens(t *testing.T) {
+	db := newTestDB(t)
+	r, _ := newAuthRouter(db)
+	user := newTestUser(t, db, "ada@kthais.com")
+
+	// a JWT alone, even a valid one, doesn't get a new one
+	forged := utils.WriteJWT(user.Email, []string{"admin"}, user.UserId, "not-our-key", 15)
+	w := doRequest(r, testRequest{method: http.MethodGet, path: "/auth/refresh_token",
+		cookies: []*http.Cookie{{Name: "jwt", Value: forged}}})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequest(r, testRequest{method: http.MethodGet, path: "/auth/refresh_token", cookies: authCookies(user)})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	assert.Equal(t, http.StatusUnauthorized, refreshWith(r, "made-up").StatusCode)
+}
+
+func TestRefreshTokenOfDeletedUser(t *testing.T) {
+	db := newTestDB(t)
+	r, h := newAuthRouter(db)
+	token, err := h.refresh.Issue(context.Background(), uuid.New())
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusUnauthorized, refreshWith(r, token).StatusCode)
+}
+
+func TestLogoutRevokesRefreshToken(t *testing.T) {
+	db := newTestDB(t)
+	r, h := newAuthRouter(db)
+	user := newTestUser(t, db, "ada@kthais.com")
+	token, err := h.refresh.Issue(context.Background(), user.UserId)
+	require.NoError(t, err)
+
+	w := doRequest(r, testRequest{method: http.MethodGet, path: "/auth/logout",
+		cookies: []*http.Cookie{{Name: refreshCookie, Value: token}}})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, responseCookie(t, w.Result(), "jwt"))
+
+	assert.Equal(t, http.StatusUnauthorized, refreshWith(r, token).StatusCode)
+}
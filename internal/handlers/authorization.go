@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"backend/internal/config"
+	"backend/internal/middleware"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// requester is the authenticated user behind a request, with the roles of their JWT
+type requester struct {
+	user  models.User
+	roles []string
+}
+
+func (r requester) hasRole(role string) bool {
+	return slices.Contains(r.roles, role)
+}
+
+// currentRequester loads the user of a request that passed AuthRequiredJWT
+func currentRequester(c *gin.Context, db *gorm.DB) (requester, error) {
+	p, ok := middleware.CurrentPrincipal(c)
+	if !ok {
+		return requester{}, fmt.Errorf("not authenticated")
+	}
+	var user models.User
+	if err := db.Where("user_id = ?", p.UserID).First(&user).Error; err != nil {
+		return requester{}, err
+	}
+	return requester{user: user, roles: p.Roles}, nil
+}
+
+// currentUserID returns the ID of the user's row. It responds with 401 if
+// the route isn't behind RegisteredUserRequired, so nobody acts as user 0.
+func currentUserID(c *gin.Context) (uint, bool) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+	}
+	return userID, ok
+}
+
+// optionalRequester returns the requester of a public route, if they sent a
+// valid JWT
+func optionalRequester(c *gin.Context, db *gorm.DB, cfg *config.Config) (requester, bool) {
+	if _, ok := middleware.Authenticate(c, cfg); !ok {
+		return requester{}, false
+	}
+	r, err := currentRequester(c, db)
+	return r, err == nil
+}
+
+// canManage reports whether the requester is an admin or one of the
+// organizers of the event
+func (r requester) canManage(db *gorm.DB, event models.Event) (bool, error) {
+	if r.hasRole(models.RoleAdmin) {
+		return true, nil
+	}
+	if !r.hasRole(models.RoleOrganizer) {
+		return false, nil
+	}
+	return event.IsOrganizer(db, r.user.ID)
+}
+
+// canView reports whether the event is public or the request comes from
+// someone who can manage it
+func (h *EventHandler) canView(c *gin.Context, event models.Event) bool {
+	if event.IsPublic() {
+		return true
+	}
+	r, ok := optionalRequester(c, h.db, h.cfg)
+	if !ok {
+		return false
+	}
+	can, err := r.canManage(h.db, event)
+	return err == nil && can
+}
+
+// authorizeEvent checks that the requester is an admin or one of the
+// organizers of the event. Otherwise it responds with an error and returns false.
+func (h *EventHandler) authorizeEvent(c *gin.Context, event models.Event) (requester, bool) {
+	r, err := currentRequester(c, h.db)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return r, false
+	}
+	can, err := r.canManage(h.db, event)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return r, false
+	}
+	if !can {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only manage events you organize"})
+		return r, false
+	}
+	return r, true
+}
+
+// authorizeSeries checks that the requester is an admin, created the series
+// or organizes all of its occurrences
+func (h *EventHandler) authorizeSeries(c *gin.Context, series models.EventSeries) (requester, bool) {
+	r, err := currentRequester(c, h.db)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return r, false
+	}
+	can := r.hasRole(models.RoleAdmin)
+	if !can && r.hasRole(models.RoleOrganizer) {
+		if can, err = series.IsOrganizer(h.db, r.user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return r, false
+		}
+	}
+	if !can {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only manage series you organize"})
+		return r, false
+	}
+	return r, true
+}
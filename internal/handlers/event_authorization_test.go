@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// authFixture is an event and a series created by an organizer, with one
+// co-organizer of the event and of the first occurrence of the series
+type authFixture struct {
+	router      http.Handler
+	event       models.Event
+	series      models.EventSeries
+	occurrences []models.Event // of the series
+	cookies     map[string][]*http.Cookie
+	users       map[string]models.User
+}
+
+func newAuthFixture(t *testing.T) authFixture {
+	t.Helper()
+	db := newTestDB(t)
+	f := authFixture{
+		router:  newTestRouter(NewEventHandler(db, newTestConfig())),
+		cookies: map[string][]*http.Cookie{"anonymous": nil},
+		users:   map[string]models.User{},
+	}
+	for name, roles := range map[string][]string{
+		"user":        {models.RoleUser},
+		"owner":       {models.RoleUser, models.RoleOrganizer},
+		"coorganizer": {models.RoleUser, models.RoleOrganizer},
+		"organizer":   {models.RoleUser, models.RoleOrganizer},
+		"admin":       {models.RoleUser, models.RoleAdmin},
+	} {
+		user := newTestUser(t, db, name+"@kthais.com")
+		f.users[name] = user
+		f.cookies[name] = authCookies(user, roles...)
+	}
+
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	f.event = models.Event{Title: "Workshop", StartDate: start, EndDate: start.Add(time.Hour), CreatedBy: f.users["owner"].ID}
+	require.NoError(t, db.Create(&f.event).Error)
+	require.NoError(t, db.Table("event_organizers").
+		Create(map[string]any{"event_id": f.event.ID, "user_id": f.users["coorganizer"].ID}).Error)
+
+	f.series = models.EventSeries{
+		Title:      "Reading group",
+		StartDate:  start,
+		EndDate:    start.Add(time.Hour),
+		Recurrence: models.RecurrenceRule{Frequency: models.RecurrenceWeekly, Count: 2},
+		CreatedBy:  f.users["owner"].ID,
+	}
+	require.NoError(t, db.Omit("Events").Create(&f.series).Error)
+	f.occurrences = f.series.Occurrences()
+	require.NoError(t, db.Create(&f.occurrences).Error)
+	require.NoError(t, db.Table("event_organizers").
+		Create(map[string]any{"event_id": f.occurrences[0].ID, "user_id": f.users["coorganizer"].ID}).Error)
+	return f
+}
+
+func TestEventRouteAuthorization(t *testing.T) {
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+	newEvent := gin.H{"title": "New", "start_date": start, "end_date": start.Add(time.Hour)}
+	newSeries := gin.H{
+		"title":      "New series",
+		"start_date": start,
+		"end_date":   start.Add(time.Hour),
+		"recurrence": gin.H{"frequency": "weekly", "count": 2},
+	}
+
+	actors := []string{"anonymous", "user", "owner", "coorganizer", "organizer", "admin"}
+	routes := []struct {
+		name string
+		req  func(f authFixture) testRequest
+		want []int // status per actor, in the order of actors
+	}{
+		{
+			name: "get event",
+			req: func(f authFixture) testRequest {
+				return testRequest{method: http.MethodGet, path: fmt.Sprintf("/api/v1/event/%d", f.event.ID)}
+			},
+			want: []int{200, 200, 200, 200, 200, 200},
+		},
+		{
+			name: "create event",
+			req: func(f authFixture) testRequest {
+				return testRequest{method: http.MethodPost, path: "/api/v1/event", body: newEvent}
+			},
+			want: []int{401, 403, 201, 201, 201, 201},
+		},
+		{
+			name: "update event",
+			req: func(f authFixture) testRequest {
+				return testRequest{method: http.MethodPut, path: fmt.Sprintf("/api/v1/event/%d", f.event.ID), body: gin.H{"title": "Renamed"}}
+			},
+			want: []int{401, 403, 200, 200, 403, 200},
+		},
+		{
+			name: "delete event",
+			req: func(f authFixture) testRequest {
+				return testRequest{method: http.MethodDelete, path: fmt.Sprintf("/api/v1/event/%d", f.event.ID)}
+			},
+			want: []int{401, 403, 200, 200, 403, 200},
+		},
+		{
+			name: "list organizers",
+			req: func(f authFixture) testRequest {
+				return testRequest{method: http.MethodGet, path: fmt.Sprintf("/api/v1/event/%d/organizers", f.event.ID)}
+			},
+			want: []int{401, 403, 200, 200, 403, 200},
+		},
+		{
+			name: "survey results",
+			req: func(f authFixture) testRequest {
+				return testRequest{method: http.MethodGet, path: fmt.Sprintf("/api/v1/event/%d/survey", f.event.ID)}
+			},
+			want: []int{401, 403, 200, 200, 403, 200},
+		},
+		{
+			name: "add organizer",
+			req: func(f authFixture) testRequest {
+				return testRequest{method: http.MethodPost, path: fmt.Sprintf("/api/v1/event/%d/organizers", f.event.ID), body: gin.H{"email": "organizer@kthais.com"}}
+			},
+			want: []int{401, 403, 200, 403, 403, 200},
+		},
+		{
+			name: "remove organizer",
+			req: func(f authFixture) testRequest {
+				return testRequest{method: http.MethodDelete, path: fmt.Sprintf("/api/v1/event/%d/organizers/%d", f.event.ID, f.users["coorganizer"].ID)}
+			},
+			want: []int{401, 403, 200, 403, 403, 200},
+		},
+		{
+			name: "create series",
+			req: func(f authFixture) testRequest {
+				return testRequest{method: http.MethodPost, path: "/api/v1/event/series", body: newSeries}
+			},
+			want: []int{401, 403, 201, 201, 201, 201},
+		},
+		{
+			name: "update series",
+			req: func(f authFixture) testRequest {
+				return testRequest{method: http.MethodPut, path: fmt.Sprintf("/api/v1/event/series/%d", f.series.ID), body: gin.H{"title": "Renamed"}}
+			},
+			want: []int{401, 403, 200, 403, 403, 200},
+		},
+		{
+			name: "delete series",
+			req: func(f authFixture) testRequest {
+				return testRequest{method: http.MethodDelete, path: fmt.Sprintf("/api/v1/event/series/%d", f.series.ID)}
+			},
+			want: []int{401, 403, 200, 403, 403, 200},
+		},
+	}
+
+	for _, route := range routes {
+		for i, actor := range actors {
+			t.Run(route.name+"/"+actor, func(t *testing.T) {
+				f := newAuthFixture(t)
+				req := route.req(f)
+				req.cookies = f.cookies[actor]
+				w := doRequest(f.router, req)
+				assert.Equal(t, route.want[i], w.Code, w.Body.String())
+			})
+		}
+	}
+}
+
+func TestCreatedByComesFromJWT(t *testing.T) {
+	f := newAuthFixture(t)
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+
+	w := doRequest(f.router, testRequest{
+		method:  http.MethodPost,
+		path:    "/api/v1/event",
+		cookies: f.cookies["organizer"],
+		body:    gin.H{"title": "New", "start_date": start, "end_date": start, "created_by": f.users["admin"].ID},
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	created := decodeBody[models.Event](t, w)
+	assert.Equal(t, f.users["organizer"].ID, created.CreatedBy)
+
+	// the creator can't be changed by an update either
+	w = doRequest(f.router, testRequest{
+		method:  http.MethodPut,
+		path:    fmt.Sprintf("/api/v1/event/%d", created.ID),
+		cookies: f.cookies["organizer"],
+		body:    gin.H{"created_by": f.users["owner"].ID},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, f.users["organizer"].ID, decodeBody[models.Event](t, w).CreatedBy)
+}
+
+func TestAddedOrganizerCanManageEvent(t *testing.T) {
+	f := newAuthFixture(t)
+	path := fmt.Sprintf("/api/v1/event/%d", f.event.ID)
+
+	w := doRequest(f.router, testRequest{method: http.MethodPut, path: path, cookies: f.cookies["organizer"], body: gin.H{"title": "Renamed"}})
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	w = doRequest(f.router, testRequest{
+		method:  http.MethodPost,
+		path:    path + "/organizers",
+		cookies: f.cookies["owner"],
+		body:    gin.H{"email": "organizer@kthais.com"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Len(t, decodeBody[[]models.User](t, w), 2)
+
+	w = doRequest(f.router, testRequest{method: http.MethodPut, path: path, cookies: f.cookies["organizer"], body: gin.H{"title": "Renamed"}})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestOrganizerOfAllOccurrencesCanManageSeries(t *testing.T) {
+	f := newAuthFixture(t)
+	path := fmt.Sprintf("/api/v1/event/series/%d", f.series.ID)
+
+	w := doRequest(f.router, testRequest{method: http.MethodPut, path: path, cookies: f.cookies["coorganizer"], body: gin.H{"title": "Renamed"}})
+	require.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+
+	w = doRequest(f.router, testRequest{
+		method:  http.MethodPost,
+		path:    fmt.Sprintf("/api/v1/event/%d/organizers", f.occurrences[1].ID),
+		cookies: f.cookies["owner"],
+		body:    gin.H{"email": "coorganizer@kthais.com"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = doRequest(f.router, testRequest{method: http.MethodPut, path: path, cookies: f.cookies["coorganizer"], body: gin.H{
+		"title":      "Renamed",
+		"start_date": f.series.StartDate,
+		"end_date":   f.series.EndDate,
+		"recurrence": f.series.Recurrence,
+	}})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestFutureScopeNeedsTheSeries(t *testing.T) {
+	f := newAuthFixture(t)
+	first := f.occurrences[0]
+
+	// the co-organizer of the first occurrence can edit it alone, not the ones after it
+	w := doRequest(f.router, testRequest{method: http.MethodPut, path: fmt.Sprintf("/api/v1/event/%d?scope=future", first.ID),
+		cookies: f.cookies["coorganizer"], body: gin.H{"title": "Renamed", "start_date": first.StartDate, "end_date": first.EndDate}})
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	w = doRequest(f.router, testRequest{method: http.MethodGet, cookies: f.cookies["owner"], path: fmt.Sprintf("/api/v1/event/series/%d", f.series.ID)})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	for _, e := range decodeBody[models.EventSeries](t, w).Events {
+		assert.Equal(t, "Reading group", e.Title)
+	}
+
+	w = doRequest(f.router, testRequest{method: http.MethodPut, path: fmt.Sprintf("/api/v1/event/%d", first.ID),
+		cookies: f.cookies["coorganizer"], body: gin.H{"title": "Renamed", "start_date": first.StartDate, "end_date": first.EndDate}})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
@@ -1,50 +1,199 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"slices"
+	"time"
 
+	"backend/internal/calendar"
+	"backend/internal/config"
+	"backend/internal/middleware"
 	"backend/internal/models"
+	"backend/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type EventHandler struct {
-	db *gorm.DB
+	db    *gorm.DB
+	cfg   *config.Config
+	blobs utils.BlobStore // nil if R2 couldn't be initialized
 }
 
-func NewEventHandler(db *gorm.DB) *EventHandler {
-	return &EventHandler{db: db}
+func NewEventHandler(db *gorm.DB, cfg *config.Config) *EventHandler {
+	h := &EventHandler{db: db, cfg: cfg}
+	r2, err := utils.InitS3SDK(cfg)
+	if err != nil {
+		log.Printf("Failed to init r2, event images are disabled: %s\n", err)
+	} else {
+		h.blobs = r2
+	}
+	return h
 }
 
 func (h *EventHandler) Register(r *gin.RouterGroup) {
+	// Public, the token in the path is the secret
+	r.GET("/surveys/:token", h.GetSurvey)
+	r.POST("/surveys/:token", h.SubmitSurvey)
+
 	events := r.Group("/event")
 	{
 		events.GET("", h.List)
-		events.POST("", h.Create)
+		events.GET("/calendar.ics", h.CalendarFeed)
 		events.GET("/:id", h.Get)
-		events.PUT("/:id", h.Update)
-		events.DELETE("/:id", h.Delete)
+		events.GET("/:id/ics", h.GetICS)
+		events.GET("/:id/image", h.GetImage)
+		events.GET("/series/:id", h.GetSeries)
+		events.GET("/series/:id/ics", h.GetSeriesICS)
+
+		// Organizer endpoints, organizers can only change events they organize
+		manage := events.Group("")
+		manage.Use(middleware.AuthRequiredJWT(h.cfg))
+		manage.Use(middleware.RoleRequired(h.cfg, models.RoleAdmin, models.RoleOrganizer))
+		manage.POST("", h.Create)
+		manage.PUT("/:id", h.Update)
+		manage.DELETE("/:id", h.Delete)
+		manage.PUT("/:id/status", h.UpdateStatus)
+		manage.GET("/:id/organizers", h.ListOrganizers)
+		manage.POST("/:id/organizers", h.AddOrganizer)
+		manage.DELETE("/:id/organizers/:userId", h.RemoveOrganizer)
+		manage.POST("/:id/checkin", h.CheckIn)
+		manage.GET("/:id/answers", h.GetAnswers)
+		manage.GET("/:id/registrations/export", h.ExportRegistrations)
+		manage.POST("/:id/lottery", h.DrawLottery)
+		manage.GET("/:id/lottery", h.GetLottery)
+		manage.GET("/:id/lottery/verify", h.VerifyLottery)
+		manage.GET("/:id/survey", h.GetSurveyResults)
+		manage.POST("/series", h.CreateSeries)
+		manage.PUT("/series/:id", h.UpdateSeries)
+		manage.DELETE("/series/:id", h.DeleteSeries)
 	}
 }
 
+// List returns a page of events.
+//
+// Query parameters:
+//   - type_of_event: comma-separated list of event types
+//   - status: comma-separated list of statuses, defaults to all but draft. Drafts
+//     are only listed for admins and for organizers of the event.
+//   - from, to: only events starting within the range (RFC 3339 or YYYY-MM-DD)
+//   - when: "upcoming" or "past"
+//   - q: case-insensitive search in title and description
+//   - sort: start_date, title or created_at, prefixed with "-" for descending order
+//   - limit, offset: pagination, limit defaults to 20 and is capped at 100
 func (h *EventHandler) List(c *gin.Context) {
-	var events []models.Event
-	if err := h.db.Find(&events).Error; err != nil {
+	query, err := parseEventListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if slices.Contains(query.statuses, models.EventStatusDraft) {
+		r, ok := optionalRequester(c, h.db, h.cfg)
+		switch {
+		case !ok:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		case r.hasRole(models.RoleAdmin):
+		case r.hasRole(models.RoleOrganizer):
+			query.draftsOf = &r.user.ID
+		default:
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only organizers can list drafts"})
+			return
+		}
+	}
+
+	// new session so the filtered statement can be reused for both count and find
+	filtered := query.filter(h.db.Model(&models.Event{}), time.Now()).Session(&gorm.Session{})
+
+	var total int64
+	if err := filtered.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	events := []models.Event{}
+	if err := filtered.Order(query.orderBy()).Limit(query.limit).Offset(query.offset).
+		Find(&events).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, events)
+
+	var next, prev *string
+	if int64(query.offset+len(events)) < total {
+		link := pageURL(c, query.limit, query.offset+query.limit)
+		next = &link
+	}
+	if query.offset > 0 {
+		link := pageURL(c, query.limit, max(query.offset-query.limit, 0))
+		prev = &link
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"total":  total,
+		"limit":  query.limit,
+		"offset": query.offset,
+		"next":   next,
+		"prev":   prev,
+	})
 }
 
+// Create creates an event from a JSON body, or from a multipart form with the
+// event as JSON in the "event" field and its image in the "image" field
 func (h *EventHandler) Create(c *gin.Context) {
 	var event models.Event
-	if err := c.ShouldBindJSON(&event); err != nil {
+	image, err := bindEvent(c, &event)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	// images can only be set by uploading them
+	event.Image = ""
+	event.ImageBlobID = nil
 
-	if err := h.db.Create(&event).Error; err != nil {
+	r, err := currentRequester(c, h.db)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	event.CreatedBy = r.user.ID
+	event.User = models.User{}
+	event.Organizers = nil
+	// events stay hidden until they are published through the status endpoint
+	event.Status = models.EventStatusDraft
+
+	upload, err := h.uploadImage(&event, image)
+	if err == nil {
+		err = h.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&event).Error; err != nil {
+				return err
+			}
+
+			// the calendar file needs the generated ID, so it can only be set after creation
+			event.ICSFileEndpoint = h.icsURL(event.ID)
+			if err := tx.Model(&event).Update("ics_file_endpoint", event.ICSFileEndpoint).Error; err != nil {
+				return err
+			}
+
+			if upload != nil {
+				_, err := h.storeImage(tx, &event, upload)
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			h.discardUpload(upload)
+		}
+	}
+	if errors.Is(err, errNoBlobStore) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -57,32 +206,183 @@ func (h *EventHandler) Get(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
+	if !h.canView(c, event) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
 	c.JSON(http.StatusOK, event)
 }
 
+// Update updates an event. For occurrences of a recurring series the scope
+// query parameter decides whether only this occurrence ("this", default) or
+// this and all following occurrences ("future") are changed.
 func (h *EventHandler) Update(c *gin.Context) {
 	var event models.Event
 	if err := h.db.First(&event, c.Param("id")).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
+	if _, ok := h.authorizeEvent(c, event); !ok {
+		return
+	}
+	original := event
 
-	if err := c.ShouldBindJSON(&event); err != nil {
+	image, err := bindEvent(c, &event)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	event.ID = original.ID
+	event.UUID = original.UUID
+	event.Image = original.Image
+	event.ImageBlobID = original.ImageBlobID
+	event.CreatedBy = original.CreatedBy
+	event.Status = original.Status
+	event.User = models.User{}
+	event.Organizers = nil
 
-	if err := h.db.Save(&event).Error; err != nil {
+	// series membership is managed through the series endpoints
+	event.SeriesID = original.SeriesID
+	event.OccurrenceDate = original.OccurrenceDate
+	event.Detached = original.Detached
+	if event.SeriesID != nil {
+		switch c.DefaultQuery("scope", "this") {
+		case "this":
+			event.Detached = true
+		case "future":
+			if image != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "images can only be uploaded for a single occurrence"})
+				return
+			}
+			h.updateFutureOccurrences(c, original, event)
+			return
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be this or future"})
+			return
+		}
+	}
+
+	var replaced *models.BlobData
+	upload, err := h.uploadImage(&event, image)
+	if err == nil {
+		err = h.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&event).Error; err != nil {
+				return err
+			}
+			if upload != nil {
+				var err error
+				replaced, err = h.storeImage(tx, &event, upload)
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			h.discardUpload(upload)
+		}
+	}
+	if errors.Is(err, errNoBlobStore) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.deleteBlob(replaced)
 	c.JSON(http.StatusOK, event)
 }
 
 func (h *EventHandler) Delete(c *gin.Context) {
-	if err := h.db.Delete(&models.Event{}, c.Param("id")).Error; err != nil {
+	var event models.Event
+	if err := h.db.First(&event, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if _, ok := h.authorizeEvent(c, event); !ok {
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		// deleting an occurrence excludes it from the series so calendars drop it as well
+		if event.SeriesID != nil && event.OccurrenceDate != nil {
+			if err := excludeOccurrence(tx, event); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&event).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Event deleted"})
 }
+
+// GetICS returns a single event as an iCalendar file
+func (h *EventHandler) GetICS(c *gin.Context) {
+	var event models.Event
+	if err := h.db.First(&event, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if !h.canView(c, event) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	cal := calendar.Calendar{Events: []calendar.Event{calendar.FromEvent(event, h.eventURL(event.ID))}}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="event-%d.ics"`, event.ID))
+	h.writeCalendar(c, &cal)
+}
+
+// CalendarFeed returns all upcoming events as a calendar that can be subscribed to
+func (h *EventHandler) CalendarFeed(c *gin.Context) {
+	var events []models.Event
+	if err := h.db.Where("end_date >= ? OR start_date >= ?", time.Now(), time.Now()).
+		Where("status IN ?", models.PublicEventStatuses).Order("start_date ASC").Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	cal := calendar.Calendar{Name: "KTH AI Society"}
+	var seriesIDs []uint
+	for _, event := range events {
+		// occurrences of a series are exported as one recurring event
+		if event.SeriesID != nil {
+			if !slices.Contains(seriesIDs, *event.SeriesID) {
+				seriesIDs = append(seriesIDs, *event.SeriesID)
+			}
+			continue
+		}
+		cal.Events = append(cal.Events, calendar.FromEvent(event, h.eventURL(event.ID)))
+	}
+	if len(seriesIDs) > 0 {
+		var series []models.EventSeries
+		if err := h.db.Preload("Events").Find(&series, seriesIDs).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for _, s := range series {
+			cal.Events = append(cal.Events, h.seriesComponents(s)...)
+		}
+	}
+	// calendar clients poll subscriptions, no need to hit the database on every poll
+	c.Header("Cache-Control", "public, max-age=900")
+	h.writeCalendar(c, &cal)
+}
+
+func (h *EventHandler) writeCalendar(c *gin.Context, cal *calendar.Calendar) {
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	if _, err := cal.WriteTo(c.Writer); err != nil {
+		c.Error(err)
+	}
+}
+
+func (h *EventHandler) eventURL(id uint) string {
+	return fmt.Sprintf("%s/events/%d", h.cfg.FrontendURL, id)
+}
+
+func (h *EventHandler) icsURL(id uint) string {
+	return fmt.Sprintf("%s/api/v1/event/%d/ics", h.cfg.BackendURL, id)
+}
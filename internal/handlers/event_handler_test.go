@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type eventListResponse struct {
+	Events []models.Event `json:"events"`
+	Total  int64          `json:"total"`
+	Next   *string        `json:"next"`
+	Prev   *string        `json:"prev"`
+}
+
+func eventTitles(events []models.Event) []string {
+	titles := []string{}
+	for _, e := range events {
+		titles = append(titles, e.Title)
+	}
+	return titles
+}
+
+func TestListEvents(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now()
+	day := 24 * time.Hour
+	events := []models.Event{
+		{Title: "Old lecture", Description: "about GANs", TypeOfEvent: models.EventTypeLecture, StartDate: now.Add(-10 * day), EndDate: now.Add(-10*day + time.Hour)},
+		{Title: "Last week workshop", Description: "pytorch basics", TypeOfEvent: models.EventTypeWorkshop, StartDate: now.Add(-7 * day), EndDate: now.Add(-7*day + time.Hour)},
+		{Title: "Ongoing hackathon", Description: "24h of building", TypeOfEvent: models.EventTypeSeminar, StartDate: now.Add(-time.Hour), EndDate: now.Add(20 * time.Hour)},
+		{Title: "Transformers lecture", Description: "attention is all you need", TypeOfEvent: models.EventTypeLecture, StartDate: now.Add(3 * day), EndDate: now.Add(3*day + time.Hour)},
+		{Title: "Job fair", Description: "meet companies", TypeOfEvent: models.EventTypeJobFair, StartDate: now.Add(5 * day), EndDate: now.Add(5*day + time.Hour)},
+	}
+	require.NoError(t, db.Create(&events).Error)
+	r := newTestRouter(NewEventHandler(db, newTestConfig()))
+
+	tests := []struct {
+		name   string
+		query  string
+		titles []string
+		total  int64
+	}{
+		{"default sorts by start date", "", []string{"Old lecture", "Last week workshop", "Ongoing hackathon", "Transformers lecture", "Job fair"}, 5},
+		{"upcoming includes ongoing events", "?when=upcoming", []string{"Ongoing hackathon", "Transformers lecture", "Job fair"}, 3},
+		{"past is newest first", "?when=past", []string{"Last week workshop", "Old lecture"}, 2},
+		{"filter by type", "?type_of_event=lecture", []string{"Old lecture", "Transformers lecture"}, 2},
+		{"filter by several types", "?type_of_event=lecture,job%20fair&when=upcoming", []string{"Transformers lecture", "Job fair"}, 2},
+		{"search title and description", "?q=ATTENTION", []string{"Transformers lecture"}, 1},
+		{"search combined with or filter", "?q=lecture&when=upcoming", []string{"Transformers lecture"}, 1},
+		{"date range", "?from=" + now.Add(-8*day).Format(time.RFC3339) + "&to=" + now.Add(4*day).Format(time.RFC3339), []string{"Last week workshop", "Ongoing hackathon", "Transformers lecture"}, 3},
+		{"date range of plain dates includes the last day", "?from=" + now.Add(-8*day).UTC().Format(time.DateOnly) + "&to=" + now.Add(3*day).UTC().Format(time.DateOnly), []string{"Last week workshop", "Ongoing hackathon", "Transformers lecture"}, 3},
+		{"sort by title descending", "?sort=-title&limit=2", []string{"Transformers lecture", "Ongoing hackathon"}, 5},
+		{"offset", "?limit=2&offset=4", []string{"Job fair"}, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(r, testRequest{method: http.MethodGet, path: "/api/v1/event" + tt.query})
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			resp := decodeBody[eventListResponse](t, w)
+			assert.Equal(t, tt.titles, eventTitles(resp.Events))
+			assert.Equal(t, tt.total, resp.Total)
+		})
+	}
+}
+
+func TestSearchEventsMatchesWildcardsLiterally(t *testing.T) {
+	db := newTestDB(t)
+	for _, title := range []string{"100% attendance", "1000 attendees", "ML_ops", "MLflow", `C:\path`} {
+		require.NoError(t, db.Create(&models.Event{Title: title}).Error)
+	}
+	r := newTestRouter(NewEventHandler(db, newTestConfig()))
+
+	for query, titles := range map[string][]string{
+		"%":        {"100% attendance"},
+		"100% att": {"100% attendance"},
+		"%%":       {},
+		"ml_":      {"ML_ops"},
+		"_":        {"ML_ops"},
+		`c:\`:      {`C:\path`},
+	} {
+		w := doRequest(r, testRequest{method: http.MethodGet, path: "/api/v1/event?q=" + url.QueryEscape(query)})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, titles, eventTitles(decodeBody[eventListResponse](t, w).Events), query)
+	}
+}
+
+func TestListEventsPagination(t *testing.T) {
+	db := newTestDB(t)
+	start := time.Now().Add(24 * time.Hour)
+	for i := range 5 {
+		require.NoError(t, db.Create(&models.Event{Title: "Event", StartDate: start.Add(time.Duration(i) * time.Hour)}).Error)
+	}
+	r := newTestRouter(NewEventHandler(db, newTestConfig()))
+
+	var seen []uint
+	path := "/api/v1/event?when=upcoming&limit=2"
+	for pages := 0; pages < 10; pages++ {
+		w := doRequest(r, testRequest{method: http.MethodGet, path: path})
+		require.Equal(t, http.StatusOK, w.Code)
+		resp := decodeBody[eventListResponse](t, w)
+		for _, e := range resp.Events {
+			seen = append(seen, e.ID)
+		}
+		if pages == 0 {
+			assert.Nil(t, resp.Prev)
+		}
+		if resp.Next == nil {
+			break
+		}
+		path = *resp.Next
+	}
+	assert.Equal(t, []uint{1, 2, 3, 4, 5}, seen)
+}
+
+func TestListEventsInvalidQuery(t *testing.T) {
+	r := newTestRouter(NewEventHandler(newTestDB(t), newTestConfig()))
+	for _, query := range []string{"?when=soon", "?sort=location", "?limit=0", "?offset=-1", "?from=yesterday"} {
+		w := doRequest(r, testRequest{method: http.MethodGet, path: "/api/v1/event" + query})
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
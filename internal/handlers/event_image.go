@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxImageSize limits the size of uploaded event images
+const maxImageSize = 5 << 20
+
+// imageTypes are the content types accepted for event images
+var imageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+var errNoBlobStore = errors.New("image storage is not available")
+
+// eventImage is an uploaded image that has been read and validated
+type eventImage struct {
+	name string
+	data []byte
+}
+
+// bindEvent binds an event from either a JSON body or a multipart form with
+// the event as JSON in the "event" field and the image file in "image".
+// The image is nil if none was uploaded.
+func bindEvent(c *gin.Context, event *models.Event) (*eventImage, error) {
+	if c.ContentType() != "multipart/form-data" {
+		if err := c.ShouldBindJSON(event); err != nil {
+			return nil, err
+		}
+		return nil, event.ValidateRegistration()
+	}
+	if data := c.PostForm("event"); data != "" {
+		if err := json.Unmarshal([]byte(data), event); err != nil {
+			return nil, err
+		}
+	}
+	if err := event.ValidateRegistration(); err != nil {
+		return nil, err
+	}
+	file, err := c.FormFile("image")
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return readImage(file)
+}
+
+func readImage(file *multipart.FileHeader) (*eventImage, error) {
+	if file.Size > maxImageSize {
+		return nil, fmt.Errorf("image can't be larger than %d MB", maxImageSize>>20)
+	}
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageSize {
+		return nil, fmt.Errorf("image can't be larger than %d MB", maxImageSize>>20)
+	}
+	if contentType := http.DetectContentType(data); !imageTypes[contentType] {
+		return nil, fmt.Errorf("image must be a png, jpeg, gif or webp, not %s", contentType)
+	}
+	return &eventImage{name: file.Filename, data: data}, nil
+}
+
+// uploadImage puts an image of the event into storage. It is done before the
+// transaction that saves the event, so the transaction doesn't wait on the
+// upload, and the upload is discarded if the transaction fails. Returns nil if
+// there is no image.
+func (h *EventHandler) uploadImage(event *models.Event, image *eventImage) (*models.BlobData, error) {
+	if image == nil {
+		return nil, nil
+	}
+	if h.blobs == nil {
+		return nil, errNoBlobStore
+	}
+	if event.UUID == uuid.Nil {
+		event.UUID = uuid.New()
+	}
+	name := strings.TrimSuffix(image.name, filepath.Ext(image.name))
+	ftype := strings.TrimPrefix(filepath.Ext(image.name), ".")
+	blob, err := models.UploadBlob(name, ftype, event.UUID, image.data, h.blobs)
+	if err != nil {
+		return nil, err
+	}
+	return &blob, nil
+}
+
+// storeImage makes an uploaded image the image of the event. Returns the
+// previous image blob, which should be deleted once the transaction commits.
+func (h *EventHandler) storeImage(tx *gorm.DB, event *models.Event, blob *models.BlobData) (*models.BlobData, error) {
+	if err := tx.Create(blob).Error; err != nil {
+		return nil, err
+	}
+
+	var previous *models.BlobData
+	if event.ImageBlobID != nil {
+		var old models.BlobData
+		if err := tx.Where("blob_id = ?", *event.ImageBlobID).First(&old).Error; err == nil {
+			previous = &old
+		}
+	}
+
+	event.ImageBlobID = &blob.BlobId
+	event.Image = h.imageURL(event.ID)
+	err := tx.Model(event).Updates(map[string]any{
+		"image":         event.Image,
+		"image_blob_id": event.ImageBlobID,
+	}).Error
+	return previous, err
+}
+
+// discardUpload removes an uploaded image whose transaction failed. Its row
+// was rolled back, only the object in storage is left.
+func (h *EventHandler) discardUpload(blob *models.BlobData) {
+	if blob == nil {
+		return
+	}
+	if err := h.blobs.DeleteObject(blob.BlobId.String()); err != nil {
+		log.Printf("Failed to delete blob %s: %s\n", blob.BlobId, err)
+	}
+}
+
+// deleteBlob cleans up a replaced image. Failing to do so leaves an orphan in
+// storage but doesn't affect the event, so errors are only logged.
+func (h *EventHandler) deleteBlob(blob *models.BlobData) {
+	if blob == nil {
+		return
+	}
+	if err := blob.Delete(h.db, h.blobs); err != nil {
+		log.Printf("Failed to delete blob %s: %s\n", blob.BlobId, err)
+	}
+}
+
+// GetImage serves the image of an event
+func (h *EventHandler) GetImage(c *gin.Context) {
+	var event models.Event
+	if err := h.db.First(&event, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if !h.canView(c, event) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if event.ImageBlobID == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event has no image"})
+		return
+	}
+	if h.blobs == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": errNoBlobStore.Error()})
+		return
+	}
+
+	var blob models.BlobData
+	if err := h.db.Where("blob_id = ?", *event.ImageBlobID).First(&blob).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+	data, err := blob.GetData(h.blobs)
+	if err != nil {
+		log.Printf("Failed to Fetch Blob: %s\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch image"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Data(http.StatusOK, http.DetectContentType(data), data)
+}
+
+func (h *EventHandler) imageURL(id uint) string {
+	return fmt.Sprintf("%s/api/v1/event/%d/image", h.cfg.BackendURL, id)
+}
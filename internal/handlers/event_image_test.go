@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// memoryBlobStore keeps blobs in memory instead of R2
+type memoryBlobStore map[string][]byte
+
+func (m memoryBlobStore) GetObject(key string) ([]byte, error) {
+	data, ok := m[key]
+	if !ok {
+		return nil, fmt.Errorf("no object %s", key)
+	}
+	return data, nil
+}
+
+func (m memoryBlobStore) PutObject(key string, obj []byte) error {
+	m[key] = obj
+	return nil
+}
+
+func (m memoryBlobStore) DeleteObject(key string) error {
+	delete(m, key)
+	return nil
+}
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func doMultipart(t *testing.T, r http.Handler, auth []*http.Cookie, method, path string, event any, filename string, file []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if event != nil {
+		data, err := json.Marshal(event)
+		require.NoError(t, err)
+		require.NoError(t, form.WriteField("event", string(data)))
+	}
+	if file != nil {
+		part, err := form.CreateFormFile("image", filename)
+		require.NoError(t, err)
+		_, err = part.Write(file)
+		require.NoError(t, err)
+	}
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest(method, path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	for _, cookie := range auth {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func newImageTestHandler(t *testing.T) (*EventHandler, memoryBlobStore) {
+	t.Helper()
+	h := NewEventHandler(newTestDB(t), newTestConfig())
+	store := memoryBlobStore{}
+	h.blobs = store
+	return h, store
+}
+
+func TestCreateEventWithImage(t *testing.T) {
+	h, store := newImageTestHandler(t)
+	r := newTestRouter(h)
+	admin := newTestAdmin(t, h.db)
+	img := testPNG(t)
+
+	w := doMultipart(t, r, admin, http.MethodPost, "/api/v1/event",
+		gin.H{"title": "Workshop", "image": "https://elsewhere.com/ignored.png"}, "poster.png", img)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	event := decodeBody[models.Event](t, w)
+	assert.Equal(t, fmt.Sprintf("http://localhost:8080/api/v1/event/%d/image", event.ID), event.Image)
+	require.NotNil(t, event.ImageBlobID)
+	assert.Len(t, store, 1)
+
+	var blob models.BlobData
+	require.NoError(t, h.db.Where("blob_id = ?", *event.ImageBlobID).First(&blob).Error)
+	assert.Equal(t, event.UUID, blob.AssociationId)
+	assert.Equal(t, "poster", blob.Name)
+	assert.Equal(t, "png", blob.FType)
+
+	// new events are drafts, so only their organizers can see the image yet
+	w = doRequest(r, testRequest{method: http.MethodGet, path: fmt.Sprintf("/api/v1/event/%d/image", event.ID)})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequest(r, testRequest{method: http.MethodGet, cookies: admin, path: fmt.Sprintf("/api/v1/event/%d/image", event.ID)})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, img, w.Body.Bytes())
+}
+
+func TestReplaceEventImageDeletesOldBlob(t *testing.T) {
+	h, store := newImageTestHandler(t)
+	r := newTestRouter(h)
+	admin := newTestAdmin(t, h.db)
+
+	w := doMultipart(t, r, admin, http.MethodPost, "/api/v1/event", gin.H{"title": "Workshop"}, "a.png", testPNG(t))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	first := decodeBody[models.Event](t, w)
+
+	w = doMultipart(t, r, admin, http.MethodPut, fmt.Sprintf("/api/v1/event/%d", first.ID), gin.H{"title": "Renamed"}, "b.png", testPNG(t))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	second := decodeBody[models.Event](t, w)
+	assert.Equal(t, "Renamed", second.Title)
+	assert.NotEqual(t, *first.ImageBlobID, *second.ImageBlobID)
+
+	assert.Len(t, store, 1)
+	assert.Contains(t, store, second.ImageBlobID.String())
+	var count int64
+	h.db.Model(&models.BlobData{}).Count(&count)
+	assert.EqualValues(t, 1, count)
+
+	// a plain JSON update keeps the image
+	w = doRequest(r, testRequest{
+		method:  http.MethodPut,
+		cookies: admin,
+		path:    fmt.Sprintf("/api/v1/event/%d", first.ID),
+		body:    gin.H{"title": "Renamed again", "image": "https://elsewhere.com/x.png"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	third := decodeBody[models.Event](t, w)
+	assert.Equal(t, second.Image, third.Image)
+	assert.Equal(t, *second.ImageBlobID, *third.ImageBlobID)
+}
+
+func TestUploadRejectsNonImages(t *testing.T) {
+	h, store := newImageTestHandler(t)
+	r := newTestRouter(h)
+	admin := newTestAdmin(t, h.db)
+
+	w := doMultipart(t, r, admin, http.MethodPost, "/api/v1/event", gin.H{"title": "Workshop"}, "notes.png", []byte("just some text"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, store)
+
+	var count int64
+	h.db.Model(&models.Event{}).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestGetImageWithoutImage(t *testing.T) {
+	h, _ := newImageTestHandler(t)
+	r := newTestRouter(h)
+	event := models.Event{Title: "No image"}
+	require.NoError(t, h.db.Create(&event).Error)
+
+	w := doRequest(r, testRequest{method: http.MethodGet, path: fmt.Sprintf("/api/v1/event/%d/image", event.ID)})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestFailedSaveDiscardsUpload(t *testing.T) {
+	h, store := newImageTestHandler(t)
+	r := newTestRouter(h)
+	admin := newTestAdmin(t, h.db)
+	event := models.Event{Title: "Workshop"}
+	require.NoError(t, h.db.Create(&event).Error)
+
+	// the image is uploaded, then saving its row fails
+	require.NoError(t, h.db.Callback().Create().Before("gorm:create").Register("fail_blobs", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Dest.(*models.BlobData); ok {
+			tx.AddError(errors.New("disk full"))
+		}
+	}))
+
+	w := doMultipart(t, r, admin, http.MethodPost, "/api/v1/event", gin.H{"title": "Hackathon"}, "a.png", testPNG(t))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	w = doMultipart(t, r, admin, http.MethodPut, fmt.Sprintf("/api/v1/event/%d", event.ID), gin.H{"title": "Renamed"}, "b.png", testPNG(t))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	assert.Empty(t, store)
+	var count int64
+	require.NoError(t, h.db.Model(&models.Event{}).Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+	var stored models.Event
+	require.NoError(t, h.db.First(&stored, event.ID).Error)
+	assert.Equal(t, "Workshop", stored.Title)
+	assert.Nil(t, stored.ImageBlobID)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// ListOrganizers returns the co-organizers of an event
+func (h *EventHandler) ListOrganizers(c *gin.Context) {
+	var event models.Event
+	if err := h.db.First(&event, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if _, ok := h.authorizeEvent(c, event); !ok {
+		return
+	}
+	h.respondWithOrganizers(c, event)
+}
+
+// AddOrganizer adds a co-organizer to an event by email. Only admins and the
+// creator of the event can add co-organizers.
+func (h *EventHandler) AddOrganizer(c *gin.Context) {
+	var input struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	event, ok := h.eventForOrganizerChange(c)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	if err := h.db.Where("email = ?", input.Email).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := h.db.Table("event_organizers").Clauses(clause.OnConflict{DoNothing: true}).
+		Create(map[string]any{"event_id": event.ID, "user_id": user.ID}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.respondWithOrganizers(c, event)
+}
+
+// RemoveOrganizer removes a co-organizer from an event
+func (h *EventHandler) RemoveOrganizer(c *gin.Context) {
+	event, ok := h.eventForOrganizerChange(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Exec("DELETE FROM event_organizers WHERE event_id = ? AND user_id = ?",
+		event.ID, c.Param("userId")).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.respondWithOrganizers(c, event)
+}
+
+// eventForOrganizerChange loads the event of the request and checks that the
+// requester may change its co-organizers
+func (h *EventHandler) eventForOrganizerChange(c *gin.Context) (models.Event, bool) {
+	var event models.Event
+	if err := h.db.First(&event, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return event, false
+	}
+	r, ok := h.authorizeEvent(c, event)
+	if !ok {
+		return event, false
+	}
+	if !r.hasRole(models.RoleAdmin) && event.CreatedBy != r.user.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the creator of the event can change its organizers"})
+		return event, false
+	}
+	return event, true
+}
+
+func (h *EventHandler) respondWithOrganizers(c *gin.Context, event models.Event) {
+	organizers := []models.User{}
+	if err := h.db.Model(&event).Association("Organizers").Find(&organizers); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, organizers)
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultEventPageSize = 20
+	maxEventPageSize     = 100
+)
+
+// sortable columns for the event list, keyed by the value of the sort parameter
+var eventSortColumns = map[string]string{
+	"start_date": "start_date",
+	"title":      "title",
+	"created_at": "created_at",
+}
+
+type eventListQuery struct {
+	types    []models.EventType
+	statuses []models.EventStatus
+	draftsOf *uint // only include drafts organized by this user
+	from     *time.Time
+	to       *time.Time
+	toDay    bool // to is a plain date, which includes the whole day
+	when     string
+	search   string
+	sort     string
+	desc     bool
+	limit    int
+	offset   int
+}
+
+func parseEventListQuery(c *gin.Context) (*eventListQuery, error) {
+	q := &eventListQuery{
+		limit:    defaultEventPageSize,
+		sort:     "start_date",
+		statuses: models.PublicEventStatuses,
+	}
+
+	if types := c.Query("type_of_event"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			q.types = append(q.types, models.EventType(strings.TrimSpace(t)))
+		}
+	}
+
+	if statuses := c.Query("status"); statuses != "" {
+		q.statuses = nil
+		for _, s := range strings.Split(statuses, ",") {
+			status := models.EventStatus(strings.TrimSpace(s))
+			if !status.Valid() {
+				return nil, fmt.Errorf("invalid status: must be draft, published, cancelled or completed")
+			}
+			q.statuses = append(q.statuses, status)
+		}
+	}
+
+	for _, param := range []struct {
+		name string
+		dst  **time.Time
+		day  *bool
+	}{{"from", &q.from, new(bool)}, {"to", &q.to, &q.toDay}} {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		t, day, err := parseQueryTime(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: expected RFC 3339 or YYYY-MM-DD", param.name)
+		}
+		*param.dst, *param.day = &t, day
+	}
+
+	switch when := c.Query("when"); when {
+	case "", "all":
+	case "upcoming", "past":
+		q.when = when
+		// past events are most interesting newest first
+		if when == "past" {
+			q.desc = true
+		}
+	default:
+		return nil, fmt.Errorf("invalid when: must be upcoming, past or all")
+	}
+
+	q.search = strings.TrimSpace(c.Query("q"))
+
+	if sort := c.Query("sort"); sort != "" {
+		q.desc = strings.HasPrefix(sort, "-")
+		sort = strings.TrimPrefix(sort, "-")
+		if _, ok := eventSortColumns[sort]; !ok {
+			return nil, fmt.Errorf("invalid sort: must be one of start_date, title, created_at")
+		}
+		q.sort = sort
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid limit")
+		}
+		q.limit = min(n, maxEventPageSize)
+	}
+	if offset := c.Query("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid offset")
+		}
+		q.offset = n
+	}
+
+	return q, nil
+}
+
+// parseQueryTime accepts both full timestamps and plain dates, reporting
+// which one it got
+func parseQueryTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	return t, true, err
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern used with ESCAPE '\'
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (q *eventListQuery) filter(db *gorm.DB, now time.Time) *gorm.DB {
+	if len(q.types) > 0 {
+		db = db.Where("type_of_event IN ?", q.types)
+	}
+	if q.draftsOf != nil && slices.Contains(q.statuses, models.EventStatusDraft) {
+		organized := db.Session(&gorm.Session{NewDB: true}).Table("event_organizers").
+			Select("event_id").Where("user_id = ?", *q.draftsOf)
+		others := slices.DeleteFunc(slices.Clone(q.statuses), func(s models.EventStatus) bool {
+			return s == models.EventStatusDraft
+		})
+		db = db.Where("status IN ? OR (status = ? AND (created_by = ? OR id IN (?)))",
+			others, models.EventStatusDraft, *q.draftsOf, organized)
+	} else {
+		db = db.Where("status IN ?", q.statuses)
+	}
+	if q.from != nil {
+		db = db.Where("start_date >= ?", *q.from)
+	}
+	if q.to != nil && q.toDay {
+		db = db.Where("start_date < ?", q.to.AddDate(0, 0, 1))
+	} else if q.to != nil {
+		db = db.Where("start_date <= ?", *q.to)
+	}
+	switch q.when {
+	case "upcoming":
+		db = db.Where("end_date >= ? OR start_date >= ?", now, now)
+	case "past":
+		db = db.Where("end_date < ? AND start_date < ?", now, now)
+	}
+	if q.search != "" {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(q.search)) + "%"
+		db = db.Where(`LOWER(title) LIKE ? ESCAPE '\' OR LOWER(description) LIKE ? ESCAPE '\'`, pattern, pattern)
+	}
+	return db
+}
+
+func (q *eventListQuery) orderBy() string {
+	direction := "ASC"
+	if q.desc {
+		direction = "DESC"
+	}
+	// order by id as well so pages are stable when the sort column has duplicates
+	return fmt.Sprintf("%s %s, id %s", eventSortColumns[q.sort], direction, direction)
+}
+
+// pageURL returns the current request URL with limit and offset replaced
+func pageURL(c *gin.Context, limit, offset int) string {
+	u := *c.Request.URL
+	values := u.Query()
+	values.Set("limit", strconv.Itoa(limit))
+	values.Set("offset", strconv.Itoa(offset))
+	u.RawQuery = values.Encode()
+	return u.RequestURI()
+}
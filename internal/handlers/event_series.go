@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"backend/internal/calendar"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateSeries creates a recurring series and all of its occurrences
+func (h *EventHandler) CreateSeries(c *gin.Context) {
+	var series models.EventSeries
+	if err := c.ShouldBindJSON(&series); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateSeries(&series); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	r, err := currentRequester(c, h.db)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	series.CreatedBy = r.user.ID
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Events").Create(&series).Error; err != nil {
+			return err
+		}
+		return h.syncOccurrences(tx, &series, time.Time{}, 0)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondWithSeries(c, http.StatusCreated, series.ID)
+}
+
+func (h *EventHandler) GetSeries(c *gin.Context) {
+	h.respondWithSeries(c, http.StatusOK, c.Param("id"))
+}
+
+// UpdateSeries updates all occurrences of a series that have not started yet,
+// except the ones that were edited on their own
+func (h *EventHandler) UpdateSeries(c *gin.Context) {
+	var series models.EventSeries
+	if err := h.db.First(&series, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+	if _, ok := h.authorizeSeries(c, series); !ok {
+		return
+	}
+	original := series
+
+	if err := c.ShouldBindJSON(&series); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	series.ID = original.ID
+	series.CreatedBy = original.CreatedBy
+	if err := validateSeries(&series); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// moving the series to another weekday moves the exclusions with it
+	shift := models.OccurrenceDayShift(original.StartDate, series.StartDate)
+	unchanged := slices.EqualFunc(series.Recurrence.Exclusions, original.Recurrence.Exclusions, time.Time.Equal)
+	if shift != 0 && unchanged {
+		series.Recurrence.Shift(shift)
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Events").Save(&series).Error; err != nil {
+			return err
+		}
+		return h.syncOccurrences(tx, &series, time.Now(), shift)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondWithSeries(c, http.StatusOK, series.ID)
+}
+
+// DeleteSeries deletes a series and all of its occurrences
+func (h *EventHandler) DeleteSeries(c *gin.Context) {
+	var series models.EventSeries
+	if err := h.db.First(&series, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+	if _, ok := h.authorizeSeries(c, series); !ok {
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("series_id = ?", series.ID).Delete(&models.Event{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&series).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Series deleted"})
+}
+
+// GetSeriesICS returns a series as a single recurring event. Series without
+// published occurrences aren't public yet.
+func (h *EventHandler) GetSeriesICS(c *gin.Context) {
+	var series models.EventSeries
+	if err := h.db.Preload("Events").First(&series, c.Param("id")).Error; err != nil ||
+		!slices.ContainsFunc(series.Events, func(e models.Event) bool { return e.IsPublic() }) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+
+	cal := calendar.Calendar{Events: h.seriesComponents(series)}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="series-%d.ics"`, series.ID))
+	h.writeCalendar(c, &cal)
+}
+
+// updateFutureOccurrences handles editing an occurrence together with all the
+// following ones. The series is split in two: the original one now ends right
+// before the occurrence, and a new series starting at the occurrence takes
+// over the remaining occurrences with the updated fields.
+func (h *EventHandler) updateFutureOccurrences(c *gin.Context, original, updated models.Event) {
+	var series models.EventSeries
+	if err := h.db.First(&series, *original.SeriesID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+	// the following occurrences and the series change too, not just this one
+	if _, ok := h.authorizeSeries(c, series); !ok {
+		return
+	}
+	split := *original.OccurrenceDate
+
+	next := models.EventSeries{
+		Title:              updated.Title,
+		Description:        updated.Description,
+		RegistrationMethod: updated.RegistrationMethod,
+		Location:           updated.Location,
+		RegistrationMax:    updated.RegistrationMax,
+		TypeOfEvent:        updated.TypeOfEvent,
+		StartDate:          updated.StartDate,
+		EndDate:            updated.EndDate,
+		CreatedBy:          series.CreatedBy,
+		Recurrence: models.RecurrenceRule{
+			Frequency:  series.Recurrence.Frequency,
+			Until:      series.Recurrence.Until,
+			Exclusions: slices.Clone(series.Recurrence.Exclusions),
+		},
+	}
+	if series.Recurrence.Count > 0 {
+		// the new series gets whatever is left of the count
+		before := 0
+		for _, t := range series.Recurrence.Dates(series.StartDate) {
+			if t.Before(split) {
+				before++
+			}
+		}
+		next.Recurrence.Count = series.Recurrence.Count - before
+	}
+	shift := models.OccurrenceDayShift(split, updated.StartDate)
+	next.Recurrence.Shift(shift)
+
+	until := split.Add(-time.Second)
+	series.Recurrence.Until = &until
+	series.Recurrence.Count = 0
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Events").Create(&next).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Event{}).Where("series_id = ? AND occurrence_date >= ?", series.ID, split).
+			Update("series_id", next.ID).Error; err != nil {
+			return err
+		}
+		// the edited occurrence follows the series again
+		if err := tx.Model(&models.Event{}).Where("id = ?", original.ID).Update("detached", false).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit("Events").Save(&series).Error; err != nil {
+			return err
+		}
+		return h.syncOccurrences(tx, &next, time.Time{}, shift)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondWithSeries(c, http.StatusOK, next.ID)
+}
+
+// syncOccurrences brings the saved occurrences of a series that start at or
+// after from in line with the series: missing ones are created, ones no longer
+// generated by the rule are deleted and the rest are updated unless detached.
+// Occurrences are matched by date (moved by shift days) so that registrations
+// survive a change of time.
+func (h *EventHandler) syncOccurrences(tx *gorm.DB, series *models.EventSeries, from time.Time, shift int) error {
+	var existing []models.Event
+	if err := tx.Where("series_id = ?", series.ID).Find(&existing).Error; err != nil {
+		return err
+	}
+	byKey := map[string]*models.Event{}
+	for i, e := range existing {
+		if e.OccurrenceDate == nil || e.OccurrenceDate.Before(from) {
+			continue
+		}
+		byKey[models.OccurrenceKey(e.OccurrenceDate.AddDate(0, 0, shift))] = &existing[i]
+	}
+
+	for _, want := range series.Occurrences() {
+		if want.StartDate.Before(from) {
+			continue
+		}
+		key := models.OccurrenceKey(want.StartDate)
+		current, ok := byKey[key]
+		if !ok {
+			// like single events, new occurrences are drafts until published
+			want.Status = models.EventStatusDraft
+			if err := tx.Create(&want).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&want).Update("ics_file_endpoint", h.icsURL(want.ID)).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		delete(byKey, key)
+
+		current.OccurrenceDate = want.OccurrenceDate
+		if !current.Detached {
+			series.ApplyTo(current)
+			current.StartDate = want.StartDate
+			current.EndDate = want.EndDate
+		}
+		if err := tx.Save(current).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, removed := range byKey {
+		if err := tx.Delete(removed).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// excludeOccurrence removes a single occurrence from the rule of its series
+func excludeOccurrence(tx *gorm.DB, event models.Event) error {
+	var series models.EventSeries
+	if err := tx.First(&series, *event.SeriesID).Error; err != nil {
+		return err
+	}
+	series.Recurrence.Exclusions = append(series.Recurrence.Exclusions, *event.OccurrenceDate)
+	return tx.Omit("Events").Save(&series).Error
+}
+
+// seriesComponents returns the recurring VEVENT of a series followed by the
+// occurrences that override it. Drafts are excluded from the rule until they
+// are published.
+func (h *EventHandler) seriesComponents(series models.EventSeries) []calendar.Event {
+	recurring := calendar.FromSeries(series, h.seriesURL(series))
+	var overrides []calendar.Event
+	for _, e := range series.Events {
+		switch {
+		case !e.IsPublic():
+			if e.OccurrenceDate != nil {
+				recurring.ExDates = append(recurring.ExDates, *e.OccurrenceDate)
+			}
+		case e.Detached || e.Status == models.EventStatusCancelled:
+			overrides = append(overrides, calendar.FromOccurrence(e, h.eventURL(e.ID)))
+		}
+	}
+	slices.SortFunc(recurring.ExDates, time.Time.Compare)
+	return append([]calendar.Event{recurring}, overrides...)
+}
+
+// seriesURL links a series to its first published occurrence
+func (h *EventHandler) seriesURL(series models.EventSeries) string {
+	for _, e := range series.Events {
+		if e.IsPublic() {
+			return h.eventURL(e.ID)
+		}
+	}
+	return ""
+}
+
+func (h *EventHandler) respondWithSeries(c *gin.Context, status int, id any) {
+	var series models.EventSeries
+	if err := h.db.Preload("Events", func(db *gorm.DB) *gorm.DB {
+		return db.Order("start_date ASC")
+	}).First(&series, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+	// drafts are only shown to those who can see them
+	series.Events = slices.DeleteFunc(series.Events, func(e models.Event) bool {
+		return !h.canView(c, e)
+	})
+	c.JSON(status, series)
+}
+
+func validateSeries(series *models.EventSeries) error {
+	if series.Title == "" {
+		return fmt.Errorf("title is required")
+	}
+	if series.StartDate.IsZero() || series.EndDate.Before(series.StartDate) {
+		return fmt.Errorf("start_date is required and end_date can't be before it")
+	}
+	return series.Recurrence.Validate()
+}
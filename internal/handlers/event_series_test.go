@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestSeries(t *testing.T, r http.Handler, auth []*http.Cookie, start time.Time, count int) models.EventSeries {
+	t.Helper()
+	w := doRequest(r, testRequest{method: http.MethodPost, path: "/api/v1/event/series", cookies: auth, body: gin.H{
+		"title":      "Reading group",
+		"location":   "Library",
+		"start_date": start,
+		"end_date":   start.Add(time.Hour),
+		"recurrence": gin.H{"frequency": "weekly", "count": count},
+	}})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	return decodeBody[models.EventSeries](t, w)
+}
+
+// weekly occurrences keep their wall clock time in the series time zone
+func inSeriesZone(t time.Time) time.Time {
+	loc, _ := time.LoadLocation(models.SeriesTimeZone)
+	return t.In(loc)
+}
+
+func TestCreateSeries(t *testing.T) {
+	db := newTestDB(t)
+	r := newTestRouter(NewEventHandler(db, newTestConfig()))
+	admin := newTestAdmin(t, db)
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+
+	series := createTestSeries(t, r, admin, start, 4)
+	require.Len(t, series.Events, 4)
+	for i, e := range series.Events {
+		assert.Equal(t, "Reading group", e.Title)
+		assert.Equal(t, series.ID, *e.SeriesID)
+		assert.True(t, e.StartDate.Equal(inSeriesZone(start).AddDate(0, 0, 7*i)))
+		assert.Equal(t, fmt.Sprintf("http://localhost:8080/api/v1/event/%d/ics", e.ID), e.ICSFileEndpoint)
+	}
+
+	w := doRequest(r, testRequest{method: http.MethodPost, cookies: admin, path: "/api/v1/event/series", body: gin.H{
+		"title":      "No end",
+		"start_date": start,
+		"end_date":   start.Add(time.Hour),
+		"recurrence": gin.H{"frequency": "weekly"},
+	}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestNewSeriesAreDrafts(t *testing.T) {
+	db := newTestDB(t)
+	r := newTestRouter(NewEventHandler(db, newTestConfig()))
+	admin := newTestAdmin(t, db)
+	series := createTestSeries(t, r, admin, time.Now().Add(24*time.Hour).Truncate(time.Hour), 2)
+	for _, e := range series.Events {
+		assert.Equal(t, models.EventStatusDraft, e.Status)
+	}
+
+	w := doRequest(r, testRequest{method: http.MethodGet, path: "/api/v1/event"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, decodeBody[struct{ Events []models.Event }](t, w).Events)
+	w = doRequest(r, testRequest{method: http.MethodGet, path: fmt.Sprintf("/api/v1/event/%d", series.Events[0].ID)})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(r, testRequest{method: http.MethodGet, path: fmt.Sprintf("/api/v1/event/series/%d", series.ID)})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, decodeBody[models.EventSeries](t, w).Events)
+
+	// occurrences are published one by one
+	w = doRequest(r, testRequest{method: http.MethodPut, cookies: admin, path: fmt.Sprintf("/api/v1/event/%d/status", series.Events[0].ID),
+		body: gin.H{"status": models.EventStatusPublished}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doRequest(r, testRequest{method: http.MethodGet, path: "/api/v1/event"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	listed := decodeBody[struct{ Events []models.Event }](t, w).Events
+	require.Len(t, listed, 1)
+	assert.Equal(t, series.Events[0].ID, listed[0].ID)
+}
+
+func TestUpdateSingleOccurrence(t *testing.T) {
+	db := newTestDB(t)
+	r := newTestRouter(NewEventHandler(db, newTestConfig()))
+	admin := newTestAdmin(t, db)
+	series := createTestSeries(t, r, admin, time.Now().Add(24*time.Hour).Truncate(time.Hour), 3)
+	require.NoError(t, db.Model(&models.Event{}).Where("series_id = ?", series.ID).
+		Update("status", models.EventStatusPublished).Error)
+	second := series.Events[1]
+
+	second.Location = "Lecture hall F1"
+	w := doRequest(r, testRequest{method: http.MethodPut, cookies: admin, path: fmt.Sprintf("/api/v1/event/%d", second.ID), body: second})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// editing the whole series afterwards leaves the detached occurrence alone
+	w = doRequest(r, testRequest{method: http.MethodPut, cookies: admin, path: fmt.Sprintf("/api/v1/event/series/%d", series.ID), body: gin.H{"location": "Library, room 2"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	updated := decodeBody[models.EventSeries](t, w)
+	require.Len(t, updated.Events, 3)
+	assert.Equal(t, "Library, room 2", updated.Events[0].Location)
+	assert.Equal(t, "Lecture hall F1", updated.Events[1].Location)
+	assert.True(t, updated.Events[1].Detached)
+	assert.Equal(t, "Library, room 2", updated.Events[2].Location)
+
+	w = doRequest(r, testRequest{method: http.MethodGet, path: fmt.Sprintf("/api/v1/event/series/%d/ics", series.ID)})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, strings.Count(w.Body.String(), "RRULE:FREQ=WEEKLY;COUNT=3"))
+	assert.Contains(t, w.Body.String(), "RECURRENCE-ID;TZID=Europe/Stockholm:")
+	assert.Contains(t, w.Body.String(), "LOCATION:Lecture hall F1")
+}
+
+func TestUpdateFutureOccurrences(t *testing.T) {
+	db := newTestDB(t)
+	r := newTestRouter(NewEventHandler(db, newTestConfig()))
+	admin := newTestAdmin(t, db)
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	series := createTestSeries(t, r, admin, start, 4)
+
+	// register someone for the last occurrence, the registration must survive the split
+	last := series.Events[3]
+	require.NoError(t, db.Create(&models.Registration{EventID: last.ID, UserID: 1, Status: models.RegistrationStatusApproved}).Error)
+
+	third := series.Events[2]
+	third.Title = "Reading group (new room)"
+	third.StartDate = third.StartDate.Add(time.Hour)
+	third.EndDate = third.EndDate.Add(time.Hour)
+	w := doRequest(r, testRequest{method: http.MethodPut, cookies: admin, path: fmt.Sprintf("/api/v1/event/%d?scope=future", third.ID), body: third})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	next := decodeBody[models.EventSeries](t, w)
+
+	assert.NotEqual(t, series.ID, next.ID)
+	assert.Equal(t, 2, next.Recurrence.Count)
+	require.Len(t, next.Events, 2)
+	assert.Equal(t, third.ID, next.Events[0].ID)
+	assert.Equal(t, last.ID, next.Events[1].ID)
+	for i, e := range next.Events {
+		assert.Equal(t, "Reading group (new room)", e.Title)
+		assert.True(t, e.StartDate.Equal(inSeriesZone(start.Add(time.Hour)).AddDate(0, 0, 7*(i+2))), e.StartDate)
+	}
+
+	var old models.EventSeries
+	require.NoError(t, db.Preload("Events").First(&old, series.ID).Error)
+	assert.Len(t, old.Events, 2)
+	assert.Equal(t, 0, old.Recurrence.Count)
+	require.NotNil(t, old.Recurrence.Until)
+	assert.True(t, old.Recurrence.Until.Before(*third.OccurrenceDate))
+	assert.Equal(t, "Reading group", old.Events[0].Title)
+
+	var registrations int64
+	db.Model(&models.Registration{}).Where("event_id = ?", last.ID).Count(&registrations)
+	assert.Equal(t, int64(1), registrations)
+}
+
+func TestDeleteOccurrenceExcludesIt(t *testing.T) {
+	db := newTestDB(t)
+	r := newTestRouter(NewEventHandler(db, newTestConfig()))
+	admin := newTestAdmin(t, db)
+	series := createTestSeries(t, r, admin, time.Now().Add(24*time.Hour).Truncate(time.Hour), 3)
+	require.NoError(t, db.Model(&models.Event{}).Where("series_id = ?", series.ID).
+		Update("status", models.EventStatusPublished).Error)
+
+	w := doRequest(r, testRequest{method: http.MethodDelete, cookies: admin, path: fmt.Sprintf("/api/v1/event/%d", series.Events[1].ID)})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = doRequest(r, testRequest{method: http.MethodGet, path: "/api/v1/event/calendar.ics"})
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Equal(t, 1, strings.Count(body, "BEGIN:VEVENT"))
+	assert.Contains(t, body, "EXDATE;TZID=Europe/Stockholm:")
+
+	// series edits must not bring the deleted occurrence back
+	w = doRequest(r, testRequest{method: http.MethodPut, cookies: admin, path: fmt.Sprintf("/api/v1/event/series/%d", series.ID), body: gin.H{"title": "Renamed"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Len(t, decodeBody[models.EventSeries](t, w).Events, 2)
+}
+
+func TestSeriesImagesAreUploadedPerOccurrence(t *testing.T) {
+	h, _ := newImageTestHandler(t)
+	r := newTestRouter(h)
+	admin := newTestAdmin(t, h.db)
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	body := gin.H{
+		"title":      "Reading group",
+		"image":      "https://elsewhere.com/ignored.png",
+		"start_date": start,
+		"end_date":   start.Add(time.Hour),
+		"recurrence": gin.H{"frequency": "weekly", "count": 2},
+	}
+
+	w := doRequest(r, testRequest{method: http.MethodPost, path: "/api/v1/event/series", cookies: admin, body: body})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	series := decodeBody[models.EventSeries](t, w)
+	require.Len(t, series.Events, 2)
+	for _, e := range series.Events {
+		assert.Empty(t, e.Image)
+	}
+
+	first := series.Events[0]
+	w = doMultipart(t, r, admin, http.MethodPut, fmt.Sprintf("/api/v1/event/%d", first.ID), gin.H{"title": first.Title,
+		"start_date": first.StartDate, "end_date": first.EndDate}, "poster.png", testPNG(t))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	uploaded := decodeBody[models.Event](t, w).Image
+	require.NotEmpty(t, uploaded)
+
+	// updating the series keeps the uploaded image and sets no others
+	body["title"] = "Book club"
+	w = doRequest(r, testRequest{method: http.MethodPut, path: fmt.Sprintf("/api/v1/event/series/%d", series.ID), cookies: admin, body: body})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var events []models.Event
+	require.NoError(t, h.db.Where("series_id = ?", series.ID).Order("start_date").Find(&events).Error)
+	require.Len(t, events, 2)
+	assert.Equal(t, uploaded, events[0].Image)
+	assert.Empty(t, events[1].Image)
+}
+
+func TestSeriesCalendarHidesDrafts(t *testing.T) {
+	db := newTestDB(t)
+	r := newTestRouter(NewEventHandler(db, newTestConfig()))
+	admin := newTestAdmin(t, db)
+	series := createTestSeries(t, r, admin, time.Now().Add(24*time.Hour).Truncate(time.Hour), 3)
+	ics := fmt.Sprintf("/api/v1/event/series/%d/ics", series.ID)
+
+	w := doRequest(r, testRequest{method: http.MethodGet, path: ics})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// the second occurrence is published, the third is a draft with details of its own
+	require.NoError(t, db.Model(&series.Events[1]).Update("status", models.EventStatusPublished).Error)
+	require.NoError(t, db.Model(&series.Events[2]).Updates(map[string]any{"detached": true, "location": "Secret room"}).Error)
+
+	for _, path := range []string{ics, "/api/v1/event/calendar.ics"} {
+		w = doRequest(r, testRequest{method: http.MethodGet, path: path})
+		require.Equal(t, http.StatusOK, w.Code, path)
+		body := w.Body.String()
+		assert.Equal(t, 1, strings.Count(body, "BEGIN:VEVENT"), path)
+		assert.Equal(t, 2, strings.Count(body, "EXDATE;TZID=Europe/Stockholm:"), path)
+		assert.NotContains(t, body, "Secret room", path)
+		assert.Contains(t, body, fmt.Sprintf("/events/%d", series.Events[1].ID), path)
+	}
+}
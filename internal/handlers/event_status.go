@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"backend/internal/email"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// sendEventCancelEmail is swapped out in tests
+var sendEventCancelEmail = email.SendEventCancelEmail
+
+// UpdateStatus moves an event through its lifecycle: drafts are published,
+// and published events are cancelled or completed. Registrants are emailed
+// when an event is cancelled.
+func (h *EventHandler) UpdateStatus(c *gin.Context) {
+	var event models.Event
+	if err := h.db.First(&event, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if _, ok := h.authorizeEvent(c, event); !ok {
+		return
+	}
+
+	var input struct {
+		Status models.EventStatus `json:"status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !input.Status.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be draft, published, cancelled or completed"})
+		return
+	}
+	if err := event.Status.TransitionTo(input.Status); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	// only update if nobody changed the status in the meantime
+	result := h.db.Model(&models.Event{}).
+		Where("id = ? AND status = ?", event.ID, event.Status).
+		Update("status", input.Status)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "The status of the event was changed by someone else"})
+		return
+	}
+	event.Status = input.Status
+
+	if event.Status == models.EventStatusCancelled {
+		if err := notifyCancelled(h.db, h.cfg.FrontendURL, event); err != nil {
+			log.Printf("Failed to notify registrants of cancelled event %d: %v", event.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+// notifyCancelled emails everyone holding a spot at a cancelled event or
+// waiting for one. The emails are sent one after another in the background.
+func notifyCancelled(db *gorm.DB, frontendURL string, event models.Event) error {
+	var userIDs []uint
+	if err := db.Model(&models.Registration{}).
+		Where("event_id = ? AND (status IN ? OR status = ?)", event.ID,
+			models.SpotHoldingStatuses, models.RegistrationStatusWaitlisted).
+		Pluck("user_id", &userIDs).Error; err != nil {
+		return err
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+	profiles, err := models.ProfilesForUsers(db, userIDs)
+	if err != nil {
+		return err
+	}
+
+	send := sendEventCancelEmail
+	go func() {
+		for _, profile := range profiles {
+			if err := send(profile, event, frontendURL); err != nil {
+				log.Printf("Failed to send cancellation email to %s: %v", profile.Email, err)
+			}
+		}
+	}()
+	return nil
+}
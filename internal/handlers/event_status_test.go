@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type statusFixture struct {
+	db     *gorm.DB
+	router http.Handler
+	owner  []*http.Cookie
+	event  models.Event
+}
+
+// newStatusFixture creates a draft event through the API
+func newStatusFixture(t *testing.T) statusFixture {
+	t.Helper()
+	db := newTestDB(t)
+	f := statusFixture{
+		db:     db,
+		router: newTestRouter(NewEventHandler(db, newTestConfig())),
+		owner:  authCookies(newTestUser(t, db, "owner@kthais.com"), models.RoleOrganizer),
+	}
+	w := doRequest(f.router, testRequest{method: http.MethodPost, path: "/api/v1/event", cookies: f.owner,
+		body: gin.H{"title": "Workshop", "status": "published"}})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	f.event = decodeBody[models.Event](t, w)
+	return f
+}
+
+func (f statusFixture) setStatus(status models.EventStatus, cookies []*http.Cookie) int {
+	return doRequest(f.router, testRequest{
+		method:  http.MethodPut,
+		path:    fmt.Sprintf("/api/v1/event/%d/status", f.event.ID),
+		cookies: cookies,
+		body:    gin.H{"status": status},
+	}).Code
+}
+
+func TestNewEventsAreDrafts(t *testing.T) {
+	f := newStatusFixture(t)
+	assert.Equal(t, models.EventStatusDraft, f.event.Status)
+
+	// the status can't be changed through a regular update either
+	w := doRequest(f.router, testRequest{method: http.MethodPut, path: fmt.Sprintf("/api/v1/event/%d", f.event.ID),
+		cookies: f.owner, body: gin.H{"title": "Renamed", "status": "published"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, models.EventStatusDraft, decodeBody[models.Event](t, w).Status)
+}
+
+func TestUpdateEventStatus(t *testing.T) {
+	f := newStatusFixture(t)
+	other := authCookies(newTestUser(t, f.db, "other@kthais.com"), models.RoleOrganizer)
+
+	assert.Equal(t, http.StatusForbidden, f.setStatus(models.EventStatusPublished, other))
+	assert.Equal(t, http.StatusBadRequest, f.setStatus("archived", f.owner))
+	assert.Equal(t, http.StatusConflict, f.setStatus(models.EventStatusCompleted, f.owner))
+	assert.Equal(t, http.StatusOK, f.setStatus(models.EventStatusPublished, f.owner))
+	assert.Equal(t, http.StatusConflict, f.setStatus(models.EventStatusDraft, f.owner))
+	assert.Equal(t, http.StatusOK, f.setStatus(models.EventStatusCompleted, f.owner))
+	assert.Equal(t, http.StatusConflict, f.setStatus(models.EventStatusCancelled, f.owner))
+
+	var event models.Event
+	require.NoError(t, f.db.First(&event, f.event.ID).Error)
+	assert.Equal(t, models.EventStatusCompleted, event.Status)
+}
+
+func TestCancelEventEmailsRegistrants(t *testing.T) {
+	f := newStatusFixture(t)
+	require.Equal(t, http.StatusOK, f.setStatus(models.EventStatusPublished, f.owner))
+
+	sent := make(chan string, 10)
+	original := sendEventCancelEmail
+	sendEventCancelEmail = func(profile models.Profile, event models.Event, frontendURL string) error {
+		assert.Equal(t, f.event.ID, event.ID)
+		assert.Equal(t, models.EventStatusCancelled, event.Status)
+		assert.Equal(t, "http://localhost:3000", frontendURL)
+		sent <- profile.Email
+		return nil
+	}
+	t.Cleanup(func() { sendEventCancelEmail = original })
+
+	for _, status := range []models.RegistrationStatus{
+		models.RegistrationStatusPending,
+		models.RegistrationStatusApproved,
+		models.RegistrationStatusWaitlisted,
+		models.RegistrationStatusRejected,
+	} {
+		user := newTestUser(t, f.db, string(status)+"@kthais.com")
+		require.NoError(t, f.db.Create(&models.Profile{UserID: user.UserId, Email: user.Email}).Error)
+		require.NoError(t, f.db.Create(&models.Registration{EventID: f.event.ID, UserID: user.ID, Status: status}).Error)
+	}
+
+	require.Equal(t, http.StatusOK, f.setStatus(models.EventStatusCancelled, f.owner))
+
+	var got []string
+	for range 3 {
+		select {
+		case email := <-sent:
+			got = append(got, email)
+		case <-time.After(time.Second):
+			t.Fatalf("expected 3 cancellation emails, got %v", got)
+		}
+	}
+	slices.Sort(got)
+	assert.Equal(t, []string{"approved@kthais.com", "pending@kthais.com", "waitlisted@kthais.com"}, got)
+	select {
+	case email := <-sent:
+		t.Fatalf("unexpected cancellation email to %s", email)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDraftsAreHidden(t *testing.T) {
+	f := newStatusFixture(t)
+	path := fmt.Sprintf("/api/v1/event/%d", f.event.ID)
+	published := models.Event{Title: "Lecture", Status: models.EventStatusPublished}
+	require.NoError(t, f.db.Create(&published).Error)
+
+	listed := func(query string, cookies []*http.Cookie) (int, []uint) {
+		w := doRequest(f.router, testRequest{method: http.MethodGet, path: "/api/v1/event" + query, cookies: cookies})
+		if w.Code != http.StatusOK {
+			return w.Code, nil
+		}
+		var ids []uint
+		for _, e := range decodeBody[eventListResponse](t, w).Events {
+			ids = append(ids, e.ID)
+		}
+		return w.Code, ids
+	}
+
+	_, ids := listed("", nil)
+	assert.Equal(t, []uint{published.ID}, ids)
+	_, ids = listed("", f.owner)
+	assert.Equal(t, []uint{published.ID}, ids, "drafts are only listed when asked for")
+
+	code, _ := listed("?status=draft", nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = listed("?status=draft", authCookies(newTestUser(t, f.db, "user@kthais.com"), models.RoleUser))
+	assert.Equal(t, http.StatusForbidden, code)
+	_, ids = listed("?status=draft", f.owner)
+	assert.Equal(t, []uint{f.event.ID}, ids)
+	_, ids = listed("?status=draft", authCookies(newTestUser(t, f.db, "other@kthais.com"), models.RoleOrganizer))
+	assert.Empty(t, ids, "organizers only see their own drafts")
+	_, ids = listed("?status=draft,published&sort=created_at", newTestAdmin(t, f.db))
+	assert.Equal(t, []uint{f.event.ID, published.ID}, ids)
+
+	for _, p := range []string{path, path + "/ics"} {
+		w := doRequest(f.router, testRequest{method: http.MethodGet, path: p})
+		assert.Equal(t, http.StatusNotFound, w.Code, p)
+		w = doRequest(f.router, testRequest{method: http.MethodGet, path: p, cookies: f.owner})
+		assert.Equal(t, http.StatusOK, w.Code, p)
+	}
+
+	require.Equal(t, http.StatusOK, f.setStatus(models.EventStatusPublished, f.owner))
+	w := doRequest(f.router, testRequest{method: http.MethodGet, path: path})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/models"
+	"backend/internal/refresh"
+	"backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// handler tests run against an on-disk sqlite database so they don't need
+// the postgres/redis infra from docker-compose
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	err = db.AutoMigrate(
+		&models.User{},
+		&models.Profile{},
+		&models.Event{},
+		&models.EventSeries{},
+		&models.Registration{},
+		&models.LotteryDraw{},
+		&models.Team{},
+		&models.PolicyOverride{},
+		&models.SurveyResponse{},
+		&models.RegistrationTransfer{},
+		&models.LoginToken{},
+		&models.BlobData{},
+	)
+	if err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	return db
+}
+
+func newTestConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.BackendURL = "http://localhost:8080"
+	cfg.FrontendURL = "http://localhost:3000"
+	cfg.JwtSigningKey = "test-signing-key"
+	return cfg
+}
+
+// newTestUser creates a user. Roles are left out as sqlite has no arrays, the
+// middleware only looks at the roles in the JWT anyway.
+func newTestUser(t *testing.T, db *gorm.DB, email string) models.User {
+	t.Helper()
+	user := models.User{UserId: uuid.New(), Email: email, Provider: "google"}
+	if err := db.Omit("Roles").Create(&user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+// authCookies returns the cookies of a user logged in with the given roles
+func authCookies(user models.User, roles ...string) []*http.Cookie {
+	token := utils.WriteJWT(user.Email, roles, user.UserId, newTestConfig().JwtSigningKey, 15)
+	return []*http.Cookie{{Name: "jwt", Value: token}}
+}
+
+// newTestAdmin creates an admin and returns their cookies
+func newTestAdmin(t *testing.T, db *gorm.DB) []*http.Cookie {
+	t.Helper()
+	return authCookies(newTestUser(t, db, "admin@kthais.com"), models.RoleUser, models.RoleAdmin)
+}
+
+// countingLimiter stands in for Redis, allowing max requests per key
+type countingLimiter struct {
+	max    int
+	counts map[string]int
+}
+
+func (l *countingLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	l.counts[key]++
+	return l.counts[key] <= l.max, nil
+}
+
+// newTestAuthHandler returns an AuthHandler that keeps refresh tokens and
+// rate limits in memory instead of Redis
+func newTestAuthHandler(db *gorm.DB) *AuthHandler {
+	cfg := newTestConfig()
+	return &AuthHandler{
+		db:            db,
+		cfg:           cfg,
+		jwtSigningKey: cfg.JwtSigningKey,
+		refresh:       refresh.NewManager(refresh.NewMemoryStore(), refresh.TTL),
+		ipLimiter:     &countingLimiter{max: magicLinksPerIP, counts: map[string]int{}},
+		emailLimiter:  &countingLimiter{max: magicLinksPerEmail, counts: map[string]int{}},
+	}
+}
+
+// jwtClaims returns the claims of the JWT cookie set by a response
+func jwtClaims(t *testing.T, resp *http.Response) map[string]any {
+	t.Helper()
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "jwt" {
+			valid, token := utils.ParseAndVerify(cookie.Value, newTestConfig().JwtSigningKey)
+			require.True(t, valid)
+			return utils.GetClaims(token)
+		}
+	}
+	t.Fatal("no jwt cookie was set")
+	return nil
+}
+
+func newTestRouter(handlers ...Handler) *gin.Engine {
+	r := gin.New()
+	api := r.Group("/api/v1")
+	for _, h := range handlers {
+		h.Register(api)
+	}
+	return r
+}
+
+type testRequest struct {
+	method  string
+	path    string
+	body    any
+	cookies []*http.Cookie
+	header  http.Header
+}
+
+func doRequest(r http.Handler, req testRequest) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	if req.body != nil {
+		if err := json.NewEncoder(&body).Encode(req.body); err != nil {
+			panic(fmt.Sprintf("failed to encode body: %v", err))
+		}
+	}
+	httpReq := httptest.NewRequest(req.method, req.path, &body)
+	httpReq.Header.Set("Content-Type", "application/json")
+	for name, values := range req.header {
+		httpReq.Header[name] = values
+	}
+	for _, cookie := range req.cookies {
+		httpReq.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httpReq)
+	return w
+}
+
+func decodeBody[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var out T
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("failed to decode response %q: %v", w.Body.String(), err)
+	}
+	return out
+}
+
+// discardEmails swaps out every email sender, for tests that send emails
+// without looking at them
+func discardEmails(t *testing.T) {
+	t.Helper()
+	registration, login, cancel := sendEventRegistrationEmail, sendLoginEmail, sendEventCancelEmail
+	promotion, offer, done := sendWaitlistPromotionEmail, sendTransferOfferEmail, sendTransferDoneEmail
+	sendEventRegistrationEmail = func(models.Profile, models.Event, models.Registration, string, string) error { return nil }
+	sendLoginEmail = func(models.Profile, string) error { return nil }
+	sendEventCancelEmail = func(models.Profile, models.Event, string) error { return nil }
+	sendWaitlistPromotionEmail = func(models.Profile, models.Event, string, *time.Time) error { return nil }
+	sendTransferOfferEmail = func(models.Profile, models.Event, models.Profile, string, *time.Time) error { return nil }
+	sendTransferDoneEmail = func(models.Profile, models.Event, models.Profile, string) error { return nil }
+	t.Cleanup(func() {
+		sendEventRegistrationEmail, sendLoginEmail, sendEventCancelEmail = registration, login, cancel
+		sendWaitlistPromotionEmail, sendTransferOfferEmail, sendTransferDoneEmail = promotion, offer, done
+	})
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errLotteryDrawn = errors.New("the lottery has already been drawn")
+
+// DrawLottery draws the spots of a lottery event once applications have
+// closed. The seed is always random and recorded with the draw, so that it
+// can be verified later but not picked beforehand.
+func (h *EventHandler) DrawLottery(c *gin.Context) {
+	var event models.Event
+	if err := h.db.First(&event, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	r, ok := h.authorizeEvent(c, event)
+	if !ok {
+		return
+	}
+
+	if event.SelectionMode != models.SelectionLottery {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Event doesn't select registrants by lottery"})
+		return
+	}
+	if event.Status != models.EventStatusPublished {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only published events can be drawn"})
+		return
+	}
+	now := time.Now()
+	if event.ApplicationsClose != nil && now.Before(*event.ApplicationsClose) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Applications are still open"})
+		return
+	}
+
+	draw, err := drawLottery(h.db, h.cfg, event.ID, models.NewLotterySeed(), &r.user.ID)
+	if errors.Is(err, errLotteryDrawn) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, draw)
+}
+
+// GetLottery returns the recorded draw of an event
+func (h *EventHandler) GetLottery(c *gin.Context) {
+	var event models.Event
+	if err := h.db.First(&event, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if _, ok := h.authorizeEvent(c, event); !ok {
+		return
+	}
+
+	var draw models.LotteryDraw
+	if err := h.db.Where("event_id = ?", event.ID).First(&draw).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "The lottery hasn't been drawn yet"})
+		return
+	}
+	c.JSON(http.StatusOK, draw)
+}
+
+// VerifyLottery replays the recorded draw of an event from its seed,
+// candidates, rules and spots without changing anything, and reports whether
+// it selects the same registrants in the same order
+func (h *EventHandler) VerifyLottery(c *gin.Context) {
+	var event models.Event
+	if err := h.db.First(&event, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if _, ok := h.authorizeEvent(c, event); !ok {
+		return
+	}
+
+	var draw models.LotteryDraw
+	if err := h.db.Where("event_id = ?", event.ID).First(&draw).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "The lottery hasn't been drawn yet"})
+		return
+	}
+	selected, _ := draw.Rules.Draw(draw.Candidates, draw.Spots, draw.Seed)
+	replayed := make([]uint, len(selected))
+	for i, c := range selected {
+		replayed[i] = c.RegistrationID
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"draw":     draw,
+		"selected": replayed,
+		"matches":  slices.Equal(replayed, draw.Selected),
+	})
+}
+
+// drawLottery draws the pending registrations of an event. Selected
+// registrants are approved and get a ticket, the rest are rejected or put on
+// the waitlist in draw order. Everyone is emailed about the outcome.
+func drawLottery(db *gorm.DB, cfg *config.Config, eventID uint, seed int64, drawnBy *uint) (models.LotteryDraw, error) {
+	var draw models.LotteryDraw
+	var drawn []models.Registration
+	err := db.Transaction(func(tx *gorm.DB) error {
+		event, err := models.LockEvent(tx, eventID)
+		if err != nil {
+			return err
+		}
+		var previous int64
+		if err := tx.Model(&models.LotteryDraw{}).Where("event_id = ?", eventID).Count(&previous).Error; err != nil {
+			return err
+		}
+		if previous > 0 {
+			return errLotteryDrawn
+		}
+
+		candidates, err := models.LotteryCandidates(tx, eventID)
+		if err != nil {
+			return err
+		}
+		// registrants approved by hand before the draw keep their spots
+		spots := len(candidates)
+		if event.RegistrationMax > 0 {
+			held, err := models.CountHeldSpots(tx, event)
+			if err != nil {
+				return err
+			}
+			spots = max(event.RegistrationMax-(held-len(candidates)), 0)
+		}
+
+		selected, rest := event.LotteryRules.Draw(candidates, spots, seed)
+		draw = models.LotteryDraw{
+			EventID:    eventID,
+			Seed:       seed,
+			Rules:      event.LotteryRules,
+			Spots:      spots,
+			Candidates: candidates,
+			DrawnBy:    drawnBy,
+		}
+		for _, c := range selected {
+			draw.Selected = append(draw.Selected, c.RegistrationID)
+		}
+		if err := tx.Create(&draw).Error; err != nil {
+			return err
+		}
+
+		for _, c := range selected {
+			registration := models.Registration{ID: c.RegistrationID}
+			if err := tx.Model(&registration).Update("status", models.RegistrationStatusApproved).Error; err != nil {
+				return err
+			}
+			if err := issueTicket(tx, &registration); err != nil {
+				return err
+			}
+		}
+		unselected := event.LotteryRules.UnselectedStatus()
+		for i, c := range rest {
+			updates := map[string]any{"status": unselected}
+			if unselected == models.RegistrationStatusWaitlisted {
+				updates["lottery_rank"] = i + 1
+			}
+			if err := tx.Model(&models.Registration{}).Where("id = ?", c.RegistrationID).
+				Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(candidates) == 0 {
+			return nil
+		}
+		ids := make([]uint, len(candidates))
+		for i, c := range candidates {
+			ids[i] = c.RegistrationID
+		}
+		return tx.Find(&drawn, ids).Error
+	})
+	if err != nil {
+		return draw, err
+	}
+
+	for _, r := range drawn {
+		// the lottery ranks are the waitlist positions right after the draw
+		r.WaitlistPosition = r.LotteryRank
+		notifyRegistration(db, cfg, r)
+	}
+	return draw, nil
+}
+
+// StartLotterySweeper periodically draws the lotteries of events that should
+// be drawn as soon as their applications close
+func StartLotterySweeper(db *gorm.DB, cfg *config.Config, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			var events []models.Event
+			if err := db.Where("selection_mode = ? AND status = ? AND applications_close <= ?",
+				models.SelectionLottery, models.EventStatusPublished, time.Now()).
+				Where("NOT EXISTS (SELECT 1 FROM lottery_draws WHERE lottery_draws.event_id = events.id)").
+				Find(&events).Error; err != nil {
+				log.Printf("Failed to find lotteries to draw: %v", err)
+				continue
+			}
+			for _, event := range events {
+				if !event.LotteryRules.AutoDraw {
+					continue
+				}
+				if _, err := drawLottery(db, cfg, event.ID, models.NewLotterySeed(), nil); err != nil && !errors.Is(err, errLotteryDrawn) {
+					log.Printf("Failed to draw the lottery of event %d: %v", event.ID, err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newLotteryEvent(t *testing.T, db *gorm.DB, closes time.Time, rules models.LotteryRules) models.Event {
+	t.Helper()
+	event := models.Event{
+		Title:             "Hackathon",
+		RegistrationMax:   3,
+		SelectionMode:     models.SelectionLottery,
+		ApplicationsClose: &closes,
+		LotteryRules:      rules,
+	}
+	require.NoError(t, db.Create(&event).Error)
+	return event
+}
+
+func TestDrawLottery(t *testing.T) {
+	db := newTestDB(t)
+	sent := captureRegistrationEmails(t, nil)
+	r := newTestRouter(NewEventHandler(db, newTestConfig()))
+	admin := newTestAdmin(t, db)
+
+	event := newLotteryEvent(t, db, time.Now().Add(-time.Hour), models.LotteryRules{Unselected: models.RegistrationStatusWaitlisted})
+	past := models.Event{Title: "Last year's hackathon"}
+	require.NoError(t, db.Create(&past).Error)
+	for i := range 8 {
+		user := newTestUser(t, db, fmt.Sprintf("applicant%d@kthais.com", i))
+		require.NoError(t, db.Create(&models.Profile{UserID: user.UserId, Email: user.Email, Programme: models.StudyProgramMathematics}).Error)
+		require.NoError(t, db.Create(&models.Registration{EventID: event.ID, UserID: user.ID, Status: models.RegistrationStatusPending}).Error)
+		if i == 0 {
+			require.NoError(t, db.Create(&models.Registration{EventID: past.ID, UserID: user.ID, Status: models.RegistrationStatusApproved, Attended: true}).Error)
+		}
+	}
+	path := fmt.Sprintf("/api/v1/event/%d/lottery", event.ID)
+
+	w := doRequest(r, testRequest{method: http.MethodGet, path: path, cookies: admin})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequest(r, testRequest{method: http.MethodGet, path: path + "/verify", cookies: admin})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// the seed can't be picked by whoever draws
+	w = doRequest(r, testRequest{method: http.MethodPost, path: path, cookies: admin, body: gin.H{"seed": 7}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	draw := decodeBody[models.LotteryDraw](t, w)
+	assert.NotEqual(t, int64(7), draw.Seed)
+	assert.Equal(t, 3, draw.Spots)
+	require.Len(t, draw.Selected, 3)
+	require.Len(t, draw.Candidates, 8)
+	for i, c := range draw.Candidates {
+		assert.Equal(t, models.StudyProgramMathematics, c.Programme)
+		assert.Equal(t, i != 0, c.FirstTime, "only the first applicant attended an event before")
+	}
+
+	var registrations []models.Registration
+	require.NoError(t, db.Where("event_id = ?", event.ID).Find(&registrations).Error)
+	ranks := map[int]bool{}
+	for _, reg := range registrations {
+		switch reg.Status {
+		case models.RegistrationStatusApproved:
+			assert.Contains(t, draw.Selected, reg.ID)
+			assert.NotEmpty(t, reg.TicketNonce)
+		case models.RegistrationStatusWaitlisted:
+			ranks[reg.LotteryRank] = true
+		default:
+			t.Errorf("unexpected status %s", reg.Status)
+		}
+	}
+	assert.Equal(t, map[int]bool{1: true, 2: true, 3: true, 4: true, 5: true}, ranks)
+	for range 8 {
+		nextEmail(t, sent)
+	}
+
+	// the recorded seed reproduces the draw
+	w = doRequest(r, testRequest{method: http.MethodGet, path: path + "/verify", cookies: admin})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	verified := decodeBody[struct {
+		Selected []uint `json:"selected"`
+		Matches  bool   `json:"matches"`
+	}](t, w)
+	assert.True(t, verified.Matches)
+	assert.Equal(t, draw.Selected, verified.Selected)
+
+	w = doRequest(r, testRequest{method: http.MethodPost, path: path, cookies: admin})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = doRequest(r, testRequest{method: http.MethodGet, path: path, cookies: admin})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, draw.Selected, decodeBody[models.LotteryDraw](t, w).Selected)
+}
+
+func TestDrawLotteryRequiresClosedApplications(t *testing.T) {
+	db := newTestDB(t)
+	discardEmails(t)
+	r := newTestRouter(NewEventHandler(db, newTestConfig()))
+	admin := newTestAdmin(t, db)
+
+	open := newLotteryEvent(t, db, time.Now().Add(time.Hour), models.LotteryRules{})
+	w := doRequest(r, testRequest{method: http.MethodPost, path: fmt.Sprintf("/api/v1/event/%d/lottery", open.ID), cookies: admin})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	firstCome := models.Event{Title: "Lecture"}
+	require.NoError(t, db.Create(&firstCome).Error)
+	w = doRequest(r, testRequest{method: http.MethodPost, path: fmt.Sprintf("/api/v1/event/%d/lottery", firstCome.ID), cookies: admin})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestLotteryApplications(t *testing.T) {
+	db := newTestDB(t)
+	discardEmails(t)
+	h := NewRegistrationHandler(db, newTestConfig())
+	open := newLotteryEvent(t, db, time.Now().Add(time.Hour), models.LotteryRules{})
+	closed := newLotteryEvent(t, db, time.Now().Add(-time.Hour), models.LotteryRules{})
+
+	// everyone applies, even beyond the capacity of the event
+	for i := range 5 {
+		user := newTestUser(t, db, fmt.Sprintf("applicant%d@kthais.com", i))
+		w := doRequest(actingAs(h, user.ID), testRequest{method: http.MethodPost, path: fmt.Sprintf("/register/%d", open.ID)})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, models.RegistrationStatusPending, decodeBody[models.Registration](t, w).Status)
+	}
+
+	user := newTestUser(t, db, "late@kthais.com")
+	w := doRequest(actingAs(h, user.ID), testRequest{method: http.MethodPost, path: fmt.Sprintf("/register/%d", closed.ID)})
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestLotteryEventValidation(t *testing.T) {
+	f := newAuthFixture(t)
+	w := doRequest(f.router, testRequest{
+		method:  http.MethodPost,
+		path:    "/api/v1/event",
+		cookies: f.cookies["organizer"],
+		body:    gin.H{"title": "Hackathon", "selection_mode": "lottery"},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "applications_close is required")
+}
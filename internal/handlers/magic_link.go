@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"backend/internal/email"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	magicLinksPerIP    = 10 // per hour
+	magicLinksPerEmail = 3  // per models.LoginTokenTTL
+)
+
+// swapped out in tests
+var sendLoginEmail = email.SendLoginEmail
+
+// RequestMagicLink emails a link to log in with. The answer is the same
+// whether or not the address belongs to a member, so it can't be used to
+// find out who is one.
+func (h *AuthHandler) RequestMagicLink(c *gin.Context) {
+	var input struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	address := strings.ToLower(input.Email)
+	// the same members can log in by email as with Google
+	if !utils.EmailDomainAllowed(address, h.cfg.OAuth.AllowedDomains) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This email domain is not allowed to log in"})
+		return
+	}
+
+	limits := []struct {
+		limiter middleware.Limiter
+		key     string
+	}{
+		{h.ipLimiter, "magic_link:ip:" + c.ClientIP()},
+		{h.emailLimiter, "magic_link:email:" + address},
+	}
+	for _, l := range limits {
+		allowed, err := l.limiter.Allow(c.Request.Context(), l.key)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Rate limiter error"})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+	}
+
+	token, err := models.CreateLoginToken(h.db, address, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// members are greeted by name, everyone else just gets the link
+	profile := models.Profile{Email: address}
+	h.db.Where("email = ?", address).First(&profile)
+	loginURL := fmt.Sprintf("%s/auth/magic-link?token=%s", h.cfg.FrontendURL, url.QueryEscape(token))
+	send := sendLoginEmail
+	go func() {
+		if err := send(profile, loginURL); err != nil {
+			log.Printf("Failed to send login email to %s: %v", address, err)
+		}
+	}()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Check your inbox, we sent you a link to log in with"})
+}
+
+// VerifyMagicLink logs in with the token of a magic link and signs up new
+// members. It's a POST the frontend sends when the link is opened, so mail
+// scanners following the link don't use it up.
+func (h *AuthHandler) VerifyMagicLink(c *gin.Context) {
+	var input struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	address, err := models.ConsumeLoginToken(h.db, input.Token, time.Now())
+	if errors.Is(err, models.ErrLoginTokenInvalid) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "This login link is invalid or has expired"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// the allowed domains may have changed since the link was sent
+	if !utils.EmailDomainAllowed(address, h.cfg.OAuth.AllowedDomains) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This email domain is not allowed to log in"})
+		return
+	}
+
+	user, err := findOrCreateUser(h.db, address, "magic-link")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create account"})
+		return
+	}
+	profile := ensureProfile(h.db, user, "", "")
+
+	if err := h.logIn(c, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+	// unregistered members are sent on to complete their profile
+	c.JSON(http.StatusOK, gin.H{"registered": profile.Registered})
+}
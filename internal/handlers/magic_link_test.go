@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newMagicLinkRouter(db *gorm.DB) (*gin.Engine, *AuthHandler) {
+	h := newTestAuthHandler(db)
+	r := gin.New()
+	r.POST("/auth/magic-link", h.RequestMagicLink)
+	r.POST("/auth/magic-link/verify", h.VerifyMagicLink)
+	return r, h
+}
+
+type loginEmail struct {
+	profile models.Profile
+	url     string
+}
+
+// captureLoginEmails swaps out the login email sender, the emails show up on
+// the channel
+func captureLoginEmails(t *testing.T) chan loginEmail {
+	t.Helper()
+	sent := make(chan loginEmail, 20)
+	original := sendLoginEmail
+	sendLoginEmail = func(profile models.Profile, loginURL string) error {
+		sent <- loginEmail{profile, loginURL}
+		return nil
+	}
+	t.Cleanup(func() { sendLoginEmail = original })
+	return sent
+}
+
+func nextLoginEmail(t *testing.T, sent chan loginEmail) loginEmail {
+	t.Helper()
+	select {
+	case e := <-sent:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no login email was sent")
+		return loginEmail{}
+	}
+}
+
+func tokenFromLink(t *testing.T, link string) string {
+	t.Helper()
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	assert.Equal(t, "/auth/magic-link", u.Path)
+	return u.Query().Get("token")
+}
+
+func TestMagicLinkSignsUpNewMembers(t *testing.T) {
+	db := newTestDB(t)
+	sent := captureLoginEmails(t)
+	r, _ := newMagicLinkRouter(db)
+
+	w := doRequest(r, testRequest{method: http.MethodPost, path: "/auth/magic-link", body: gin.H{"email": "New@KTHAIS.com"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	e := nextLoginEmail(t, sent)
+	assert.Equal(t, "new@kthais.com", e.profile.Email)
+	token := tokenFromLink(t, e.url)
+
+	w = doRequest(r, testRequest{method: http.MethodPost, path: "/auth/magic-link/verify", body: gin.H{"token": token}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, false, decodeBody[map[string]any](t, w)["registered"])
+	claims := jwtClaims(t, w.Result())
+	assert.Equal(t, "new@kthais.com", claims["email"])
+	assert.Equal(t, models.RoleUser, claims["roles"])
+	assert.NotEmpty(t, responseCookie(t, w.Result(), refreshCookie))
+
+	var user models.User
+	require.NoError(t, db.Omit("Roles").Where("email = ?", "new@kthais.com").First(&user).Error)
+	assert.Equal(t, "magic-link", user.Provider)
+	assert.Equal(t, user.UserId.String(), claims["user_id"])
+	var profile models.Profile
+	require.NoError(t, db.Where("user_id = ?", user.UserId).First(&profile).Error)
+	assert.False(t, profile.Registered)
+
+	// the link only works once
+	w = doRequest(r, testRequest{method: http.MethodPost, path: "/auth/magic-link/verify", body: gin.H{"token": token}})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Result().Cookies())
+}
+
+func TestMagicLinkLogsInMembers(t *testing.T) {
+	db := newTestDB(t)
+	sent := captureLoginEmails(t)
+	r, _ := newMagicLinkRouter(db)
+	user := newTestUser(t, db, "ada@kthais.com")
+	require.NoError(t, db.Create(&models.Profile{UserID: user.UserId, Email: user.Email, FirstName: "Ada", Registered: true}).Error)
+
+	w := doRequest(r, testRequest{method: http.MethodPost, path: "/auth/magic-link", body: gin.H{"email": "ada@kthais.com"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	e := nextLoginEmail(t, sent)
+	assert.Equal(t, "Ada", e.profile.FirstName)
+
+	w = doRequest(r, testRequest{method: http.MethodPost, path: "/auth/magic-link/verify", body: gin.H{"token": tokenFromLink(t, e.url)}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, true, decodeBody[map[string]any](t, w)["registered"])
+	assert.Equal(t, user.UserId.String(), jwtClaims(t, w.Result())["user_id"])
+
+	var users int64
+	require.NoError(t, db.Model(&models.User{}).Count(&users).Error)
+	assert.EqualValues(t, 1, users)
+
+	w = doRequest(r, testRequest{method: http.MethodPost, path: "/auth/magic-link/verify", body: gin.H{"token": "made-up"}})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestMagicLinkRateLimits(t *testing.T) {
+	db := newTestDB(t)
+	captureLoginEmails(t)
+	r, _ := newMagicLinkRouter(db)
+	request := func(email string) int {
+		return doRequest(r, testRequest{method: http.MethodPost, path: "/auth/magic-link", body: gin.H{"email": email}}).Code
+	}
+
+	for i := 0; i < magicLinksPerEmail; i++ {
+		assert.Equal(t, http.StatusOK, request("ada@kthais.com"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, request("ada@kthais.com"))
+	// the address counts however it is written
+	assert.Equal(t, http.StatusTooManyRequests, request("ADA@kthais.com"))
+
+	// one client can't go through many addresses either, the requests so far count too
+	for i := magicLinksPerEmail + 2; i < magicLinksPerIP; i++ {
+		assert.Equal(t, http.StatusOK, request(fmt.Sprintf("member%d@kthais.com", i)))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, request("grace@kthais.com"))
+}
+
+func TestMagicLinkAllowedDomains(t *testing.T) {
+	db := newTestDB(t)
+	sent := captureLoginEmails(t)
+	r, h := newMagicLinkRouter(db)
+	h.cfg.OAuth.AllowedDomains = []string{"kth.se"}
+
+	w := doRequest(r, testRequest{method: http.MethodPost, path: "/auth/magic-link", body: gin.H{"email": "ada@gmail.com"}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(r, testRequest{method: http.MethodPost, path: "/auth/magic-link", body: gin.H{"email": "ada@ug.kth.se"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	token := tokenFromLink(t, nextLoginEmail(t, sent).url)
+
+	// links sent before the domain was taken off the list stop working
+	h.cfg.OAuth.AllowedDomains = []string{"kthais.com"}
+	w = doRequest(r, testRequest{method: http.MethodPost, path: "/auth/magic-link/verify", body: gin.H{"token": token}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Result().Cookies())
+}
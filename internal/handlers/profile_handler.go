@@ -5,12 +5,9 @@ import (
 	"backend/internal/mailchimp"
 	"backend/internal/middleware"
 	"backend/internal/models"
-	"backend/internal/utils"
-	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -44,13 +41,8 @@ func (h *ProfileHandler) Register(r *gin.RouterGroup) {
 
 // GetMyProfile returns the current user's profile
 func (h *ProfileHandler) GetMyProfile(c *gin.Context) {
-	// get userId from jwt now
-	token := utils.GetJWT(c)
-	claims := utils.GetClaims(token)
-	userID, err := uuid.Parse(claims["user_id"].(string))
-	if err != nil {
-		log.Printf("Could not get userid")
-	}
+	principal, _ := middleware.CurrentPrincipal(c)
+	userID := principal.UserID
 
 	var profile models.Profile
 	if err := h.db.Where("user_id = ?", userID).First(&profile).Error; err != nil {
@@ -78,13 +70,8 @@ func (h *ProfileHandler) GetMyProfile(c *gin.Context) {
 
 // UpdateMyProfile allows a user to update their own profile
 func (h *ProfileHandler) UpdateMyProfile(c *gin.Context) {
-	// get userId from jwt now
-	token := utils.GetJWT(c)
-	claims := utils.GetClaims(token)
-	userID, err := uuid.Parse(claims["user_id"].(string))
-	if err != nil {
-		log.Printf("Could not get userid")
-	}
+	principal, _ := middleware.CurrentPrincipal(c)
+	userID := principal.UserID
 
 	// Check if profile exists
 	var existingProfile models.Profile
@@ -172,13 +159,8 @@ func (h *ProfileHandler) UpdateMyProfile(c *gin.Context) {
 
 // CreateMyProfile creates a profile for the authenticated user
 func (h *ProfileHandler) CreateMyProfile(c *gin.Context) {
-	// get userId from jwt now
-	token := utils.GetJWT(c)
-	claims := utils.GetClaims(token)
-	userID, err := uuid.Parse(claims["user_id"].(string))
-	if err != nil {
-		log.Printf("Could not get userid")
-	}
+	principal, _ := middleware.CurrentPrincipal(c)
+	userID := principal.UserID
 
 	// Check if profile already exists
 	var existingProfile models.Profile
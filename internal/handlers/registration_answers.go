@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registrationAnswers is one row of the answers to an event's registration form
+type registrationAnswers struct {
+	RegistrationID      uint                      `json:"registration_id"`
+	UserID              uint                      `json:"user_id"`
+	FirstName           string                    `json:"first_name"`
+	LastName            string                    `json:"last_name"`
+	Email               string                    `json:"email"`
+	Status              models.RegistrationStatus `json:"status"`
+	DietaryRestrictions string                    `json:"dietary_restrictions"`
+	Answers             models.FormAnswers        `json:"answers"`
+}
+
+// GetAnswers returns the registration form of an event together with the
+// answers of everyone who registered
+func (h *EventHandler) GetAnswers(c *gin.Context) {
+	var event models.Event
+	if err := h.db.First(&event, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if _, ok := h.authorizeEvent(c, event); !ok {
+		return
+	}
+
+	var registrations []models.Registration
+	if err := h.db.Where("event_id = ?", event.ID).Order("created_at ASC, id ASC").
+		Find(&registrations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDs := make([]uint, len(registrations))
+	for i, r := range registrations {
+		userIDs[i] = r.UserID
+	}
+	profiles, err := models.ProfilesForUsers(h.db, userIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows := make([]registrationAnswers, 0, len(registrations))
+	for _, r := range registrations {
+		row := registrationAnswers{
+			RegistrationID:      r.ID,
+			UserID:              r.UserID,
+			Status:              r.Status,
+			DietaryRestrictions: r.DietaryRestrictions,
+			Answers:             r.Answers,
+		}
+		if profile, ok := profiles[r.UserID]; ok {
+			row.FirstName = profile.FirstName
+			row.LastName = profile.LastName
+			row.Email = profile.Email
+		}
+		rows = append(rows, row)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"form":    event.RegistrationForm,
+		"answers": rows,
+	})
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventWithInvalidFormIsRejected(t *testing.T) {
+	f := newAuthFixture(t)
+	w := doRequest(f.router, testRequest{
+		method:  http.MethodPost,
+		path:    "/api/v1/event",
+		cookies: f.cookies["organizer"],
+		body: gin.H{"title": "Hackathon", "registration_form": gin.H{"fields": []gin.H{
+			{"name": "tshirt", "label": "T-shirt size", "type": "select"},
+		}}},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "options are required")
+}
+
+func TestGetAnswers(t *testing.T) {
+	db := newTestDB(t)
+	discardEmails(t)
+	r := newTestRouter(NewEventHandler(db, newTestConfig()))
+	organizer := newTestUser(t, db, "organizer@kthais.com")
+	other := newTestUser(t, db, "other@kthais.com")
+	attendee := newTestUser(t, db, "attendee@kthais.com")
+	require.NoError(t, db.Create(&models.Profile{
+		UserID: attendee.UserId, Email: attendee.Email, FirstName: "Ada", LastName: "Lovelace",
+	}).Error)
+
+	w := doRequest(r, testRequest{
+		method:  http.MethodPost,
+		path:    "/api/v1/event",
+		cookies: authCookies(organizer, models.RoleOrganizer),
+		body: gin.H{"title": "Hackathon", "registration_form": gin.H{"fields": []gin.H{
+			{"name": "tshirt", "label": "T-shirt size", "type": "select", "required": true, "options": []string{"S", "M"}},
+		}}},
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	event := decodeBody[models.Event](t, w)
+	require.Len(t, event.RegistrationForm.Fields, 1)
+
+	require.NoError(t, db.Create(&models.Registration{
+		EventID: event.ID,
+		UserID:  attendee.ID,
+		Status:  models.RegistrationStatusPending,
+		Answers: models.FormAnswers{"tshirt": "M"},
+	}).Error)
+
+	path := fmt.Sprintf("/api/v1/event/%d/answers", event.ID)
+	w = doRequest(r, testRequest{method: http.MethodGet, path: path, cookies: authCookies(other, models.RoleOrganizer)})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = doRequest(r, testRequest{method: http.MethodGet, path: path, cookies: authCookies(organizer, models.RoleOrganizer)})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	body := decodeBody[struct {
+		Form    models.FormSchema     `json:"form"`
+		Answers []registrationAnswers `json:"answers"`
+	}](t, w)
+	assert.Equal(t, event.RegistrationForm, body.Form)
+	require.Len(t, body.Answers, 1)
+	assert.Equal(t, "Ada", body.Answers[0].FirstName)
+	assert.Equal(t, attendee.Email, body.Answers[0].Email)
+	assert.Equal(t, models.FormAnswers{"tshirt": "M"}, body.Answers[0].Answers)
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// bulkSelection picks the registrations a bulk change applies to, either by
+// ID or by a filter
+type bulkSelection struct {
+	IDs    []uint      `json:"ids"`
+	Filter *bulkFilter `json:"filter"`
+}
+
+// bulkFilter selects the registrations of an event or a team, optionally
+// only those with a given status
+type bulkFilter struct {
+	EventID uint                      `json:"event_id"`
+	TeamID  uint                      `json:"team_id"`
+	Status  models.RegistrationStatus `json:"status"`
+}
+
+const (
+	bulkUpdated   = "updated"
+	bulkUnchanged = "unchanged"
+	bulkFailed    = "failed"
+)
+
+// bulkResult is the outcome of a bulk change for one registration
+type bulkResult struct {
+	ID     uint   `json:"id"`
+	Result string `json:"result"` // updated, unchanged or failed
+	Error  string `json:"error,omitempty"`
+}
+
+func (s bulkSelection) validate() error {
+	if (len(s.IDs) > 0) == (s.Filter != nil) {
+		return errors.New("select registrations with either ids or a filter")
+	}
+	if s.Filter != nil {
+		if s.Filter.EventID == 0 && s.Filter.TeamID == 0 {
+			return errors.New("filter.event_id or filter.team_id is required")
+		}
+		if s.Filter.Status != "" && !s.Filter.Status.Valid() {
+			return fmt.Errorf("unknown status %q", s.Filter.Status)
+		}
+	}
+	return nil
+}
+
+// load returns the selected registrations in ID order. IDs that don't match a
+// registration get a failed result.
+func (s bulkSelection) load(tx *gorm.DB) ([]models.Registration, []bulkResult, error) {
+	var registrations []models.Registration
+	query := tx.Order("id")
+	if s.Filter != nil {
+		if s.Filter.EventID != 0 {
+			query = query.Where("event_id = ?", s.Filter.EventID)
+		}
+		if s.Filter.TeamID != 0 {
+			// registrants who cancelled aren't part of the team anymore
+			query = query.Where("team_id = ? AND cancelled_at IS NULL", s.Filter.TeamID)
+		}
+		if s.Filter.Status != "" {
+			query = query.Where("status = ?", s.Filter.Status)
+		}
+	} else {
+		query = query.Where("id IN ?", s.IDs)
+	}
+	if err := query.Find(&registrations).Error; err != nil {
+		return nil, nil, err
+	}
+
+	missing := []bulkResult{}
+	found := make(map[uint]bool, len(registrations))
+	for _, r := range registrations {
+		found[r.ID] = true
+	}
+	for _, id := range s.IDs {
+		if !found[id] {
+			missing = append(missing, bulkResult{ID: id, Result: bulkFailed, Error: "Registration not found"})
+			found[id] = true // report duplicates once
+		}
+	}
+	return registrations, missing, nil
+}
+
+// BulkUpdateStatus sets the status of many registrations in one transaction.
+// Registrations of cancelled or completed events are skipped and reported as
+// failed. Registrants are only emailed about the change if notify is set.
+func (h *RegistrationHandler) BulkUpdateStatus(c *gin.Context) {
+	var input struct {
+		bulkSelection
+		Status models.RegistrationStatus `json:"status" binding:"required"`
+		Notify bool                      `json:"notify"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !input.Status.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown status %q", input.Status)})
+		return
+	}
+
+	results, err := h.bulkSetStatus(input.bulkSelection, input.Status, input.Notify)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// bulkSetStatus sets the status of the selected registrations in one
+// transaction, then passes freed spots on to the waitlists and emails the
+// registrants if notify is set
+func (h *RegistrationHandler) bulkSetStatus(selection bulkSelection, status models.RegistrationStatus, notify bool) ([]bulkResult, error) {
+	var results []bulkResult
+	var changed []models.Registration
+	freed := map[uint]bool{}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		registrations, missing, err := selection.load(tx)
+		if err != nil {
+			return err
+		}
+		results = missing
+
+		events := map[uint]models.Event{}
+		for _, r := range registrations {
+			event, ok := events[r.EventID]
+			if !ok {
+				if err := tx.First(&event, r.EventID).Error; err != nil {
+					return err
+				}
+				events[r.EventID] = event
+			}
+			if event.RegistrationsLocked() {
+				results = append(results, bulkResult{ID: r.ID, Result: bulkFailed,
+					Error: fmt.Sprintf("Registrations can't be changed, the event is %s", event.Status)})
+				continue
+			}
+
+			updated, freedSpot, err := setStatus(tx, &r, status)
+			if err != nil {
+				return err
+			}
+			if freedSpot {
+				freed[r.EventID] = true
+			}
+			if !updated {
+				results = append(results, bulkResult{ID: r.ID, Result: bulkUnchanged})
+				continue
+			}
+			results = append(results, bulkResult{ID: r.ID, Result: bulkUpdated})
+			changed = append(changed, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for eventID := range freed {
+		if err := h.promoteWaitlist(eventID); err != nil {
+			log.Printf("Failed to promote from waitlist of event %d: %v", eventID, err)
+		}
+	}
+	if notify && notifiesRegistrant(status) {
+		for _, r := range changed {
+			notifyRegistration(h.db, h.cfg, r)
+		}
+	}
+	return results, nil
+}
+
+// BulkMarkAttendance sets the attendance of many registrations in one
+// transaction, e.g. from a sign-in sheet after an event
+func (h *RegistrationHandler) BulkMarkAttendance(c *gin.Context) {
+	var input struct {
+		bulkSelection
+		Attended *bool `json:"attended" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var results []bulkResult
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		registrations, missing, err := input.load(tx)
+		if err != nil {
+			return err
+		}
+		results = missing
+
+		for _, r := range registrations {
+			if r.Attended == *input.Attended {
+				results = append(results, bulkResult{ID: r.ID, Result: bulkUnchanged})
+				continue
+			}
+			if err := tx.Model(&r).Update("attended", *input.Attended).Error; err != nil {
+				return err
+			}
+			results = append(results, bulkResult{ID: r.ID, Result: bulkUpdated})
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func seedBulkRegistrations(t *testing.T, db *gorm.DB, event models.Event, statuses ...models.RegistrationStatus) []models.Registration {
+	t.Helper()
+	registrations := make([]models.Registration, len(statuses))
+	for i, status := range statuses {
+		user := newTestUser(t, db, fmt.Sprintf("registrant%d-%d@kthais.com", event.ID, i))
+		require.NoError(t, db.Create(&models.Profile{UserID: user.UserId, Email: user.Email}).Error)
+		registrations[i] = models.Registration{EventID: event.ID, UserID: user.ID, Status: status}
+		require.NoError(t, db.Create(&registrations[i]).Error)
+	}
+	return registrations
+}
+
+func bulkResults(t *testing.T, r *gin.Engine, path string, body gin.H) map[uint]bulkResult {
+	t.Helper()
+	w := doRequest(r, testRequest{method: http.MethodPut, path: path, body: body})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	results := map[uint]bulkResult{}
+	for _, result := range decodeBody[struct{ Results []bulkResult }](t, w).Results {
+		results[result.ID] = result
+	}
+	return results
+}
+
+func TestBulkUpdateStatus(t *testing.T) {
+	db := newTestDB(t)
+	sent := captureRegistrationEmails(t, nil)
+	r := actingAs(NewRegistrationHandler(db, newTestConfig()), 0)
+
+	event := models.Event{Title: "Workshop"}
+	require.NoError(t, db.Create(&event).Error)
+	cancelled := models.Event{Title: "Cancelled workshop", Status: models.EventStatusCancelled}
+	require.NoError(t, db.Create(&cancelled).Error)
+	regs := seedBulkRegistrations(t, db, event, models.RegistrationStatusPending, models.RegistrationStatusPending, models.RegistrationStatusApproved)
+	locked := seedBulkRegistrations(t, db, cancelled, models.RegistrationStatusPending)
+
+	results := bulkResults(t, r, "/admin/bulk/status", gin.H{
+		"ids":    []uint{regs[0].ID, regs[1].ID, regs[2].ID, locked[0].ID, 999},
+		"status": models.RegistrationStatusApproved,
+		"notify": true,
+	})
+	assert.Equal(t, bulkUpdated, results[regs[0].ID].Result)
+	assert.Equal(t, bulkUpdated, results[regs[1].ID].Result)
+	assert.Equal(t, bulkUnchanged, results[regs[2].ID].Result)
+	assert.Equal(t, bulkFailed, results[locked[0].ID].Result)
+	assert.Contains(t, results[locked[0].ID].Error, "cancelled")
+	assert.Equal(t, bulkFailed, results[999].Result)
+
+	for _, id := range []uint{regs[0].ID, regs[1].ID} {
+		var reg models.Registration
+		require.NoError(t, db.First(&reg, id).Error)
+		assert.Equal(t, models.RegistrationStatusApproved, reg.Status)
+		assert.NotEmpty(t, reg.TicketNonce)
+	}
+	// only the registrants whose status changed are emailed
+	for range 2 {
+		email := nextEmail(t, sent)
+		assert.Equal(t, models.RegistrationStatusApproved, email.status)
+		assert.NotEmpty(t, email.qrURL)
+	}
+	select {
+	case email := <-sent:
+		t.Fatalf("unexpected email to %s", email.to)
+	default:
+	}
+}
+
+func TestBulkUpdateStatusByFilter(t *testing.T) {
+	db := newTestDB(t)
+	sent := captureRegistrationEmails(t, nil)
+	promotions := make(chan string, 1)
+	original := sendWaitlistPromotionEmail
+	sendWaitlistPromotionEmail = func(profile models.Profile, _ models.Event, _ string, _ *time.Time) error {
+		promotions <- profile.Email
+		return nil
+	}
+	t.Cleanup(func() { sendWaitlistPromotionEmail = original })
+	r := actingAs(NewRegistrationHandler(db, newTestConfig()), 0)
+
+	event := models.Event{Title: "Workshop", RegistrationMax: 2}
+	require.NoError(t, db.Create(&event).Error)
+	regs := seedBulkRegistrations(t, db, event, models.RegistrationStatusPending, models.RegistrationStatusApproved,
+		models.RegistrationStatusWaitlisted, models.RegistrationStatusWaitlisted)
+
+	results := bulkResults(t, r, "/admin/bulk/status", gin.H{
+		"filter": gin.H{"event_id": event.ID, "status": models.RegistrationStatusPending},
+		"status": models.RegistrationStatusRejected,
+	})
+	assert.Len(t, results, 1)
+	assert.Equal(t, bulkUpdated, results[regs[0].ID].Result)
+
+	// the freed spot goes to the waitlist, without notify only the promoted
+	// registrant is told
+	var promoted models.Registration
+	require.NoError(t, db.First(&promoted, regs[2].ID).Error)
+	assert.Equal(t, models.RegistrationStatusPending, promoted.Status)
+	select {
+	case to := <-promotions:
+		assert.Equal(t, "registrant1-2@kthais.com", to)
+	case <-time.After(time.Second):
+		t.Fatal("the promoted registrant wasn't emailed")
+	}
+	select {
+	case email := <-sent:
+		t.Fatalf("unexpected email to %s", email.to)
+	default:
+	}
+}
+
+func TestBulkMarkAttendance(t *testing.T) {
+	db := newTestDB(t)
+	discardEmails(t)
+	r := actingAs(NewRegistrationHandler(db, newTestConfig()), 0)
+
+	event := models.Event{Title: "Workshop", Status: models.EventStatusCompleted}
+	require.NoError(t, db.Create(&event).Error)
+	regs := seedBulkRegistrations(t, db, event, models.RegistrationStatusApproved, models.RegistrationStatusApproved, models.RegistrationStatusRejected)
+
+	results := bulkResults(t, r, "/admin/bulk/attended", gin.H{
+		"filter":   gin.H{"event_id": event.ID, "status": models.RegistrationStatusApproved},
+		"attended": true,
+	})
+	assert.Len(t, results, 2)
+	results = bulkResults(t, r, "/admin/bulk/attended", gin.H{"ids": []uint{regs[0].ID, regs[1].ID}, "attended": false})
+	assert.Equal(t, bulkUpdated, results[regs[0].ID].Result)
+
+	var attended int64
+	require.NoError(t, db.Model(&models.Registration{}).Where("attended").Count(&attended).Error)
+	assert.Zero(t, attended)
+}
+
+func TestBulkSelectionValidation(t *testing.T) {
+	db := newTestDB(t)
+	discardEmails(t)
+	r := actingAs(NewRegistrationHandler(db, newTestConfig()), 0)
+
+	for name, body := range map[string]gin.H{
+		"nothing selected":  {"status": "approved"},
+		"ids and filter":    {"ids": []uint{1}, "filter": gin.H{"event_id": 1}, "status": "approved"},
+		"filter without id": {"filter": gin.H{"status": "pending"}, "status": "approved"},
+		"unknown status":    {"ids": []uint{1}, "status": "maybe"},
+		"unknown filter":    {"filter": gin.H{"event_id": 1, "status": "maybe"}, "status": "approved"},
+	} {
+		w := doRequest(r, testRequest{method: http.MethodPut, path: "/admin/bulk/status", body: body})
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+	w := doRequest(r, testRequest{method: http.MethodPut, path: "/admin/bulk/attended", body: gin.H{"ids": []uint{1}}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
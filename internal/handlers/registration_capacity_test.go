@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sqlite ignores FOR UPDATE, but the test database begins transactions with
+// _txlock=immediate which serializes them just like the row lock in postgres
+func TestConcurrentRegistrationsRespectCapacity(t *testing.T) {
+	// enough to contend without outrunning the busy timeout of sqlite under -race
+	const registrants, capacity = 40, 10
+	db := newTestDB(t)
+	discardEmails(t)
+	h := NewRegistrationHandler(db, newTestConfig())
+	event := models.Event{Title: "Hackathon", RegistrationMax: capacity}
+	require.NoError(t, db.Create(&event).Error)
+
+	users := make([]models.User, registrants)
+	for i := range users {
+		users[i] = newTestUser(t, db, fmt.Sprintf("hacker%d@kthais.com", i))
+	}
+
+	// all registrations are fired at once when start is closed
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	codes := make(chan int, registrants)
+	for _, user := range users {
+		r := actingAs(h, user.ID)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			w := doRequest(r, testRequest{method: http.MethodPost, path: fmt.Sprintf("/register/%d", event.ID)})
+			codes <- w.Code
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(codes)
+	// a lock timeout would show up as a 500
+	counts := map[int]int{}
+	for code := range codes {
+		counts[code]++
+	}
+	assert.Equal(t, map[int]int{http.StatusCreated: registrants}, counts)
+
+	held, err := models.CountHeldSpots(db, event)
+	require.NoError(t, err)
+	assert.Equal(t, capacity, held)
+
+	var waitlisted int64
+	require.NoError(t, db.Model(&models.Registration{}).
+		Where("event_id = ? AND status = ?", event.ID, models.RegistrationStatusWaitlisted).Count(&waitlisted).Error)
+	assert.Equal(t, int64(registrants-capacity), waitlisted)
+}
+
+func TestConcurrentDuplicateRegistrations(t *testing.T) {
+	db := newTestDB(t)
+	discardEmails(t)
+	user := newTestUser(t, db, "eager@kthais.com")
+	event := models.Event{Title: "Hackathon"}
+	require.NoError(t, db.Create(&event).Error)
+	r := actingAs(NewRegistrationHandler(db, newTestConfig()), user.ID)
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	codes := make(chan int, 20)
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			codes <- doRequest(r, testRequest{method: http.MethodPost, path: fmt.Sprintf("/register/%d", event.ID)}).Code
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(codes)
+
+	counts := map[int]int{}
+	for code := range codes {
+		counts[code]++
+	}
+	assert.Equal(t, map[int]int{http.StatusCreated: 1, http.StatusConflict: 19}, counts)
+
+	var rows int64
+	require.NoError(t, db.Model(&models.Registration{}).Where("event_id = ?", event.ID).Count(&rows).Error)
+	assert.Equal(t, int64(1), rows)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EditRegistration lets registrants change their dietary restrictions and
+// answers until the edit deadline of the event. Fields left out of the body
+// are kept, answers replace the old ones and must fit the registration form.
+func (h *RegistrationHandler) EditRegistration(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		DietaryRestrictions *string            `json:"dietary_restrictions"`
+		Answers             models.FormAnswers `json:"answers"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var registration models.Registration
+	if err := h.db.Preload("Event").First(&registration, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Registration not found"})
+		return
+	}
+	if registration.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own registrations"})
+		return
+	}
+	if !h.checkUnlocked(c, registration) {
+		return
+	}
+	if registration.CancelledAt != nil || registration.Status == models.RegistrationStatusRejected {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cancelled and rejected registrations can't be edited"})
+		return
+	}
+	event := registration.Event
+	if deadline := event.EditDeadline(); !time.Now().Before(deadline) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Registrations for %s could only be changed until %s",
+			event.Title, deadline.Format(time.RFC3339))})
+		return
+	}
+
+	if input.DietaryRestrictions != nil {
+		registration.DietaryRestrictions = *input.DietaryRestrictions
+	}
+	if input.Answers != nil {
+		answers, err := event.RegistrationForm.ValidateAnswers(input.Answers)
+		var formErrors models.FormErrors
+		if errors.As(err, &formErrors) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid answers", "fields": formErrors})
+			return
+		}
+		registration.Answers = answers
+	}
+
+	if err := h.db.Model(&registration).Select("DietaryRestrictions", "Answers").Updates(&registration).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, registration)
+}
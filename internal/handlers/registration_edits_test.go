@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestEditRegistration(t *testing.T) {
+	db := newTestDB(t)
+	h := NewRegistrationHandler(db, newTestConfig())
+	event := models.Event{Title: "Workshop", StartDate: time.Now().Add(72 * time.Hour), EditDeadlineHours: 48,
+		RegistrationForm: models.FormSchema{Fields: []models.FormField{
+			{Name: "level", Label: "Level", Type: models.FormFieldSelect, Options: []string{"beginner", "expert"}, Required: true},
+		}}}
+	require.NoError(t, db.Create(&event).Error)
+	regs := seedBulkRegistrations(t, db, event, models.RegistrationStatusApproved, models.RegistrationStatusRejected)
+	path := fmt.Sprintf("/%d", regs[0].ID)
+	r := actingAs(h, regs[0].UserID)
+
+	w := doRequest(r, testRequest{method: http.MethodPut, path: path, body: gin.H{"dietary_restrictions": "Vegan"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "Vegan", decodeBody[models.Registration](t, w).DietaryRestrictions)
+
+	w = doRequest(r, testRequest{method: http.MethodPut, path: path, body: gin.H{"answers": gin.H{"level": "guru"}}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(r, testRequest{method: http.MethodPut, path: path, body: gin.H{"answers": gin.H{"level": "expert"}}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var saved models.Registration
+	require.NoError(t, db.First(&saved, regs[0].ID).Error)
+	assert.Equal(t, "Vegan", saved.DietaryRestrictions, "left out fields are kept")
+	assert.Equal(t, models.FormAnswers{"level": "expert"}, saved.Answers)
+	assert.Equal(t, models.RegistrationStatusApproved, saved.Status)
+
+	w = doRequest(actingAs(h, regs[1].UserID), testRequest{method: http.MethodPut, path: path, body: gin.H{}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(actingAs(h, regs[1].UserID), testRequest{method: http.MethodPut, path: fmt.Sprintf("/%d", regs[1].ID), body: gin.H{}})
+	assert.Equal(t, http.StatusConflict, w.Code, "rejected registrations can't be edited")
+
+	// two days before the start is too late
+	require.NoError(t, db.Model(&event).Update("start_date", time.Now().Add(47*time.Hour)).Error)
+	w = doRequest(r, testRequest{method: http.MethodPut, path: path, body: gin.H{"dietary_restrictions": "None"}})
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCancelRegistration(t *testing.T) {
+	db := newTestDB(t)
+	captureRegistrationEmails(t, nil)
+	h := NewRegistrationHandler(db, newTestConfig())
+	event := models.Event{Title: "Workshop", StartDate: time.Now().Add(36 * time.Hour), CancelDeadlineHours: 24}
+	require.NoError(t, db.Create(&event).Error)
+	regs := seedBulkRegistrations(t, db, event, models.RegistrationStatusApproved, models.RegistrationStatusApproved,
+		models.RegistrationStatusApproved)
+	cancel := func(registration models.Registration) *httptest.ResponseRecorder {
+		t.Helper()
+		return doRequest(actingAs(h, registration.UserID), testRequest{method: http.MethodPut, path: fmt.Sprintf("/%d/cancel", registration.ID)})
+	}
+
+	w := cancel(regs[0])
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.False(t, decodeBody[struct{ Late bool }](t, w).Late)
+	assert.Equal(t, http.StatusConflict, cancel(regs[0]).Code, "already cancelled")
+
+	// another cancellation gets in between reading and writing the registration
+	require.NoError(t, db.Callback().Update().Before("gorm:update").Register("cancel_first", func(tx *gorm.DB) {
+		tx.Session(&gorm.Session{NewDB: true}).
+			Exec("UPDATE registrations SET cancelled_at = ? WHERE id = ? AND cancelled_at IS NULL", time.Now(), regs[1].ID)
+	}))
+	assert.Equal(t, http.StatusConflict, cancel(regs[1]).Code)
+	require.NoError(t, db.Callback().Update().Remove("cancel_first"))
+	require.NoError(t, db.Model(&regs[1]).Update("cancelled_at", nil).Error)
+
+	require.NoError(t, db.Model(&event).Update("start_date", time.Now().Add(12*time.Hour)).Error)
+	w = cancel(regs[1])
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, decodeBody[struct{ Late bool }](t, w).Late)
+	var late models.Registration
+	require.NoError(t, db.First(&late, regs[1].ID).Error)
+	assert.Equal(t, models.RegistrationStatusRejected, late.Status)
+	assert.True(t, late.CancelledLate)
+	assert.NotNil(t, late.CancelledAt)
+
+	require.NoError(t, db.Model(&event).Update("start_date", time.Now().Add(-time.Hour)).Error)
+	assert.Equal(t, http.StatusConflict, cancel(regs[2]).Code, "the event has started")
+
+	// late cancellations show up in the attendance of the registrant
+	stats, err := models.UserAttendance(db, regs[1].UserID, time.Now().Add(-models.DefaultAttendanceWindow), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.LateCancellations)
+	assert.Zero(t, stats.Registered)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+
+	"backend/internal/config"
+	"backend/internal/email"
+	"backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// sendEventRegistrationEmail is swapped out in tests
+var sendEventRegistrationEmail = email.SendEventRegistrationEmail
+
+// notifyRegistration emails a registrant about their registration, with the
+// ticket if it is approved. The email is sent in the background after the
+// registration is saved, so a failing mail server never undoes it.
+func notifyRegistration(db *gorm.DB, cfg *config.Config, registration models.Registration) {
+	var event models.Event
+	if err := db.First(&event, registration.EventID).Error; err != nil {
+		log.Printf("Failed to load event %d for registration email: %v", registration.EventID, err)
+		return
+	}
+	profile, err := models.ProfileForUser(db, registration.UserID)
+	if err != nil {
+		log.Printf("Failed to load profile of user %d for registration email: %v", registration.UserID, err)
+		return
+	}
+	eventURL := fmt.Sprintf("%s/events/%d", cfg.FrontendURL, event.ID)
+	qrURL := ""
+	if registration.Status == models.RegistrationStatusApproved && registration.TicketNonce != "" {
+		qrURL = ticketQRURL(cfg, ticketToken(cfg, registration))
+	}
+	send := sendEventRegistrationEmail
+	go func() {
+		if err := send(profile, event, registration, eventURL, qrURL); err != nil {
+			log.Printf("Failed to send registration email to %s: %v", profile.Email, err)
+		}
+	}()
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"backend/internal/middleware"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type sentRegistrationEmail struct {
+	to       string
+	status   models.RegistrationStatus
+	eventURL string
+	qrURL    string
+}
+
+// captureRegistrationEmails swaps out the registration email sender, the
+// emails it was asked to send show up on the returned channel
+func captureRegistrationEmails(t *testing.T, err error) chan sentRegistrationEmail {
+	t.Helper()
+	sent := make(chan sentRegistrationEmail, 10)
+	original := sendEventRegistrationEmail
+	sendEventRegistrationEmail = func(profile models.Profile, event models.Event, registration models.Registration, eventURL, qrURL string) error {
+		sent <- sentRegistrationEmail{profile.Email, registration.Status, eventURL, qrURL}
+		return err
+	}
+	t.Cleanup(func() { sendEventRegistrationEmail = original })
+	return sent
+}
+
+func nextEmail(t *testing.T, sent chan sentRegistrationEmail) sentRegistrationEmail {
+	t.Helper()
+	select {
+	case email := <-sent:
+		return email
+	case <-time.After(time.Second):
+		t.Fatal("no registration email was sent")
+		return sentRegistrationEmail{}
+	}
+}
+
+// actingAs mounts registration handlers without the auth middleware, with
+// the request made by the given user
+func actingAs(h *RegistrationHandler, userID uint) *gin.Engine {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		middleware.SetPrincipal(c, middleware.Principal{ID: userID})
+		c.Next()
+	})
+	r.POST("/register/:eventId", h.RegisterForEvent)
+	r.PUT("/admin/:id/status", h.UpdateStatus)
+	r.PUT("/admin/bulk/status", h.BulkUpdateStatus)
+	r.PUT("/admin/bulk/attended", h.BulkMarkAttendance)
+	r.PUT("/admin/teams/:id/status", h.UpdateTeamStatus)
+	r.GET("/admin/attendance", h.ListAttendance)
+	r.GET("/admin/attendance/:userId", h.GetAttendance)
+	r.POST("/admin/overrides", h.CreatePolicyOverride)
+	r.DELETE("/admin/overrides/:id", h.DeletePolicyOverride)
+	r.GET("/my", h.GetUserRegistrations)
+	r.PUT("/:id", h.EditRegistration)
+	r.PUT("/:id/cancel", h.CancelRegistration)
+	r.PUT("/:id/confirm", h.ConfirmOffer)
+	r.POST("/:id/transfer", h.TransferRegistration)
+	r.DELETE("/:id/transfer", h.CancelTransfer)
+	r.GET("/transfers/:token", h.GetTransfer)
+	r.POST("/transfers/:token/accept", h.AcceptTransfer)
+	r.POST("/teams", h.CreateTeam)
+	r.POST("/teams/join", h.JoinTeam)
+	r.GET("/teams/:id", h.GetTeam)
+	r.POST("/teams/:id/leave", h.LeaveTeam)
+	return r
+}
+
+func newRegistrant(t *testing.T, db *gorm.DB) models.User {
+	t.Helper()
+	user := newTestUser(t, db, "ada@kthais.com")
+	require.NoError(t, db.Create(&models.Profile{UserID: user.UserId, Email: user.Email, FirstName: "Ada"}).Error)
+	return user
+}
+
+func TestRegisterSendsConfirmation(t *testing.T) {
+	for name, mailErr := range map[string]error{
+		"sent":   nil,
+		"failed": errors.New("mail server is down"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDB(t)
+			sent := captureRegistrationEmails(t, mailErr)
+			user := newRegistrant(t, db)
+			event := models.Event{Title: "Workshop"}
+			require.NoError(t, db.Create(&event).Error)
+
+			r := actingAs(NewRegistrationHandler(db, newTestConfig()), user.ID)
+			w := doRequest(r, testRequest{method: http.MethodPost, path: fmt.Sprintf("/register/%d", event.ID)})
+			require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+			assert.Equal(t, sentRegistrationEmail{
+				to:       "ada@kthais.com",
+				status:   models.RegistrationStatusPending,
+				eventURL: fmt.Sprintf("http://localhost:3000/events/%d", event.ID),
+			}, nextEmail(t, sent))
+
+			// the registration stays even if the email couldn't be sent
+			var count int64
+			require.NoError(t, db.Model(&models.Registration{}).Where("user_id = ?", user.ID).Count(&count).Error)
+			assert.Equal(t, int64(1), count)
+		})
+	}
+}
+
+func TestStatusChangeSendsEmail(t *testing.T) {
+	db := newTestDB(t)
+	sent := captureRegistrationEmails(t, nil)
+	user := newRegistrant(t, db)
+	event := models.Event{Title: "Workshop"}
+	require.NoError(t, db.Create(&event).Error)
+	registration := models.Registration{EventID: event.ID, UserID: user.ID, Status: models.RegistrationStatusPending}
+	require.NoError(t, db.Create(&registration).Error)
+
+	r := actingAs(NewRegistrationHandler(db, newTestConfig()), 0)
+	setStatus := func(status models.RegistrationStatus) {
+		t.Helper()
+		w := doRequest(r, testRequest{method: http.MethodPut, path: fmt.Sprintf("/admin/%d/status", registration.ID),
+			body: gin.H{"status": status}})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	setStatus(models.RegistrationStatusApproved)
+	email := nextEmail(t, sent)
+	assert.Equal(t, models.RegistrationStatusApproved, email.status)
+	assert.Contains(t, email.qrURL, "http://localhost:8080/api/v1/tickets/")
+
+	// nothing changed, nothing to tell
+	setStatus(models.RegistrationStatusApproved)
+	setStatus(models.RegistrationStatusRejected)
+	email = nextEmail(t, sent)
+	assert.Equal(t, models.RegistrationStatusRejected, email.status)
+	assert.Empty(t, email.qrURL)
+
+	setStatus(models.RegistrationStatusPending)
+	select {
+	case email := <-sent:
+		t.Fatalf("unexpected email for status %s", email.status)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestUserRoutesNeedRegisteredUser(t *testing.T) {
+	db := newTestDB(t)
+	h := NewRegistrationHandler(db, newTestConfig())
+	event := models.Event{Title: "Workshop"}
+	require.NoError(t, db.Create(&event).Error)
+	// as if mounted without RegisteredUserRequired
+	r := actingAs(h, 0)
+
+	for _, req := range []testRequest{
+		{method: http.MethodPost, path: fmt.Sprintf("/register/%d", event.ID)},
+		{method: http.MethodGet, path: "/my"},
+		{method: http.MethodPost, path: "/teams", body: gin.H{"event_id": event.ID, "name": "Nobody"}},
+		{method: http.MethodGet, path: "/transfers/token"},
+	} {
+		w := doRequest(r, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, req.path)
+	}
+	var count int64
+	require.NoError(t, db.Model(&models.Registration{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
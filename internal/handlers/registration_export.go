@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"backend/internal/models"
+	"backend/internal/spreadsheet"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportColumns are the columns an export can have, in the order they are
+// written. The answers column expands into one column per form field, single
+// fields can be picked with answers.<name>.
+var exportColumns = []string{
+	"name", "email", "programme", "graduation_year", "status", "attended", "team", "dietary_restrictions", "answers",
+}
+
+var exportFormats = map[string]struct {
+	contentType string
+	open        func(w http.ResponseWriter, event models.Event) (spreadsheet.Writer, error)
+}{
+	"csv": {"text/csv; charset=utf-8", func(w http.ResponseWriter, _ models.Event) (spreadsheet.Writer, error) {
+		return spreadsheet.NewCSV(w)
+	}},
+	"xlsx": {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", func(w http.ResponseWriter, event models.Event) (spreadsheet.Writer, error) {
+		return spreadsheet.NewXLSX(w, event.Title)
+	}},
+}
+
+// exportRow is a registration joined with the profile of the registrant
+type exportRow struct {
+	models.Registration
+	FirstName      string
+	LastName       string
+	Email          string
+	Programme      models.StudyProgram
+	GraduationYear int
+	TeamName       string
+}
+
+// exportColumn is a header and how to get its cell out of a row
+type exportColumn struct {
+	header string
+	value  func(exportRow) string
+}
+
+// ExportRegistrations streams the registrations of an event as a CSV or XLSX
+// file, e.g. for name badges and catering orders.
+//
+// Query parameters:
+//   - format: csv (default) or xlsx
+//   - columns: comma separated, see exportColumns, all of them by default
+//   - status: comma separated registration statuses to include, all by default
+func (h *EventHandler) ExportRegistrations(c *gin.Context) {
+	var event models.Event
+	if err := h.db.First(&event, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if _, ok := h.authorizeEvent(c, event); !ok {
+		return
+	}
+
+	ext := c.DefaultQuery("format", "csv")
+	format, ok := exportFormats[ext]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or xlsx"})
+		return
+	}
+	columns, err := parseExportColumns(c.Query("columns"), event.RegistrationForm)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	statuses, err := parseStatuses(c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.db.Model(&models.Registration{}).
+		Select(`registrations.*, COALESCE(profiles.first_name, '') AS first_name,
+			COALESCE(profiles.last_name, '') AS last_name, COALESCE(profiles.email, '') AS email,
+			COALESCE(profiles.programme, '') AS programme, COALESCE(profiles.graduation_year, 0) AS graduation_year,
+			COALESCE(teams.name, '') AS team_name`).
+		Joins("JOIN users ON users.id = registrations.user_id").
+		Joins("LEFT JOIN profiles ON profiles.user_id = users.user_id AND profiles.deleted_at IS NULL").
+		Joins("LEFT JOIN teams ON teams.id = registrations.team_id AND teams.deleted_at IS NULL").
+		Where("registrations.event_id = ?", event.ID).
+		Order("registrations.created_at ASC, registrations.id ASC")
+	if len(statuses) > 0 {
+		query = query.Where("registrations.status IN ?", statuses)
+	}
+	rows, err := query.Rows()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	c.Header("Content-Type", format.contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="event-%d-registrations.%s"`, event.ID, ext))
+	c.Status(http.StatusOK)
+
+	// the status is sent, from here on errors can only cut the file short
+	w, err := format.open(c.Writer, event)
+	if err != nil {
+		log.Printf("Failed to export registrations of event %d: %v", event.ID, err)
+		return
+	}
+	headers := make([]string, len(columns))
+	for i, col := range columns {
+		headers[i] = col.header
+	}
+	if err := w.Write(headers); err != nil {
+		log.Printf("Failed to export registrations of event %d: %v", event.ID, err)
+		return
+	}
+	for rows.Next() {
+		var row exportRow
+		if err := h.db.ScanRows(rows, &row); err != nil {
+			log.Printf("Failed to export registrations of event %d: %v", event.ID, err)
+			return
+		}
+		cells := make([]string, len(columns))
+		for i, col := range columns {
+			cells[i] = col.value(row)
+		}
+		if err := w.Write(cells); err != nil {
+			log.Printf("Failed to export registrations of event %d: %v", event.ID, err)
+			return
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Failed to export registrations of event %d: %v", event.ID, err)
+		return
+	}
+	if err := w.Close(); err != nil {
+		log.Printf("Failed to export registrations of event %d: %v", event.ID, err)
+	}
+}
+
+// parseExportColumns turns the columns query parameter into the columns to
+// export, all of them if it is empty
+func parseExportColumns(param string, form models.FormSchema) ([]exportColumn, error) {
+	names := exportColumns
+	if param != "" {
+		names = strings.Split(param, ",")
+	}
+
+	var columns []exportColumn
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "name":
+			columns = append(columns, exportColumn{"Name", func(r exportRow) string {
+				return strings.TrimSpace(r.FirstName + " " + r.LastName)
+			}})
+		case name == "email":
+			columns = append(columns, exportColumn{"Email", func(r exportRow) string { return r.Email }})
+		case name == "programme":
+			columns = append(columns, exportColumn{"Programme", func(r exportRow) string { return string(r.Programme) }})
+		case name == "graduation_year":
+			columns = append(columns, exportColumn{"Graduation year", func(r exportRow) string {
+				if r.GraduationYear == 0 {
+					return ""
+				}
+				return strconv.Itoa(r.GraduationYear)
+			}})
+		case name == "status":
+			columns = append(columns, exportColumn{"Status", func(r exportRow) string { return string(r.Status) }})
+		case name == "attended":
+			columns = append(columns, exportColumn{"Attended", func(r exportRow) string { return yesNo(r.Attended) }})
+		case name == "team":
+			columns = append(columns, exportColumn{"Team", func(r exportRow) string { return r.TeamName }})
+		case name == "dietary_restrictions":
+			columns = append(columns, exportColumn{"Dietary restrictions", func(r exportRow) string { return r.DietaryRestrictions }})
+		case name == "answers":
+			for _, f := range form.Fields {
+				columns = append(columns, answerColumn(f))
+			}
+		case strings.HasPrefix(name, "answers."):
+			field := strings.TrimPrefix(name, "answers.")
+			i := slices.IndexFunc(form.Fields, func(f models.FormField) bool { return f.Name == field })
+			if i < 0 {
+				return nil, fmt.Errorf("the registration form has no field %q", field)
+			}
+			columns = append(columns, answerColumn(form.Fields[i]))
+		default:
+			return nil, fmt.Errorf("unknown column %q, columns are %s and answers.<field>", name, strings.Join(exportColumns, ", "))
+		}
+	}
+	return columns, nil
+}
+
+func answerColumn(f models.FormField) exportColumn {
+	header := f.Label
+	if header == "" {
+		header = f.Name
+	}
+	return exportColumn{header, func(r exportRow) string { return formatAnswer(r.Answers[f.Name]) }}
+}
+
+// formatAnswer writes an answer the way a person would type it into a cell
+func formatAnswer(answer any) string {
+	switch a := answer.(type) {
+	case nil:
+		return ""
+	case string:
+		return a
+	case bool:
+		return yesNo(a)
+	case float64:
+		return strconv.FormatFloat(a, 'f', -1, 64)
+	case []any:
+		parts := make([]string, len(a))
+		for i, v := range a {
+			parts[i] = formatAnswer(v)
+		}
+		return strings.Join(parts, ", ")
+	default:
+		return fmt.Sprint(a)
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// parseStatuses turns a comma separated list of registration statuses into a
+// filter, nil if the list is empty
+func parseStatuses(param string) ([]models.RegistrationStatus, error) {
+	if param == "" {
+		return nil, nil
+	}
+	var statuses []models.RegistrationStatus
+	for _, s := range strings.Split(param, ",") {
+		status := models.RegistrationStatus(strings.TrimSpace(s))
+		if !status.Valid() {
+			return nil, fmt.Errorf("unknown status %q", s)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
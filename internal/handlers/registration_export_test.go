@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportRegistrations(t *testing.T) {
+	db := newTestDB(t)
+	discardEmails(t)
+	r := newTestRouter(NewEventHandler(db, newTestConfig()))
+	admin := newTestAdmin(t, db)
+
+	event := models.Event{Title: "Hackathon", RegistrationForm: models.FormSchema{Fields: []models.FormField{
+		{Name: "tshirt", Label: "T-shirt size", Type: models.FormFieldSelect, Options: []string{"S", "M"}},
+		{Name: "tracks", Label: "Tracks", Type: models.FormFieldMultiSelect, Options: []string{"NLP", "Vision"}},
+	}}}
+	require.NoError(t, db.Create(&event).Error)
+
+	ada := newTestUser(t, db, "ada@kthais.com")
+	require.NoError(t, db.Create(&models.Profile{UserID: ada.UserId, Email: ada.Email, FirstName: "Ada", LastName: "Lovelace",
+		Programme: models.StudyProgramMathematics, GraduationYear: 2026}).Error)
+	team := models.Team{EventID: event.ID, Name: "Transformers", InviteCode: models.NewInviteCode()}
+	require.NoError(t, db.Create(&team).Error)
+	require.NoError(t, db.Create(&models.Registration{EventID: event.ID, UserID: ada.ID, Status: models.RegistrationStatusApproved,
+		TeamID: &team.ID, Attended: true, DietaryRestrictions: "vegan", Answers: models.FormAnswers{"tshirt": "M", "tracks": []any{"NLP", "Vision"}}}).Error)
+	// registrants without a profile are still exported
+	anon := newTestUser(t, db, "anon@kthais.com")
+	require.NoError(t, db.Create(&models.Registration{EventID: event.ID, UserID: anon.ID, Status: models.RegistrationStatusWaitlisted}).Error)
+
+	export := func(query string) [][]string {
+		t.Helper()
+		w := doRequest(r, testRequest{method: http.MethodGet, cookies: admin,
+			path: fmt.Sprintf("/api/v1/event/%d/registrations/export%s", event.ID, query)})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+		rows, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(w.Body.String(), "\ufeff"))).ReadAll()
+		require.NoError(t, err)
+		return rows
+	}
+
+	assert.Equal(t, [][]string{
+		{"Name", "Email", "Programme", "Graduation year", "Status", "Attended", "Team", "Dietary restrictions", "T-shirt size", "Tracks"},
+		{"Ada Lovelace", "ada@kthais.com", "Mathematics", "2026", "approved", "yes", "Transformers", "vegan", "M", "NLP, Vision"},
+		{"", "", "", "", "waitlisted", "no", "", "", "", ""},
+	}, export(""))
+
+	assert.Equal(t, [][]string{
+		{"Name", "T-shirt size"},
+		{"Ada Lovelace", "M"},
+	}, export("?columns=name,answers.tshirt&status=approved,pending"))
+
+	w := doRequest(r, testRequest{method: http.MethodGet, cookies: admin,
+		path: fmt.Sprintf("/api/v1/event/%d/registrations/export?format=xlsx", event.ID)})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "PK", w.Body.String()[:2])
+	assert.Contains(t, w.Header().Get("Content-Disposition"), ".xlsx")
+
+	for _, query := range []string{"?format=pdf", "?columns=name,shoe_size", "?columns=answers.shoe_size", "?status=maybe"} {
+		w := doRequest(r, testRequest{method: http.MethodGet, cookies: admin,
+			path: fmt.Sprintf("/api/v1/event/%d/registrations/export%s", event.ID, query)})
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+
+	w = doRequest(r, testRequest{method: http.MethodGet,
+		path: fmt.Sprintf("/api/v1/event/%d/registrations/export", event.ID)})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -4,12 +4,21 @@ import (
 	"backend/internal/config"
 	"backend/internal/middleware"
 	"backend/internal/models"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// errRegistrationChanged is returned when a registration changed between
+// being read and written
+var errRegistrationChanged = errors.New("registration was changed")
+
 type RegistrationHandler struct {
 	db  *gorm.DB
 	cfg *config.Config
@@ -20,6 +29,9 @@ func NewRegistrationHandler(db *gorm.DB, cfg *config.Config) *RegistrationHandle
 }
 
 func (h *RegistrationHandler) Register(r *gin.RouterGroup) {
+	// Public, the token in the path is the secret
+	r.GET("/tickets/:token/qr.png", h.TicketQR)
+
 	registrations := r.Group("/registrations")
 	{
 		// Public endpoints (require auth)
@@ -31,11 +43,29 @@ func (h *RegistrationHandler) Register(r *gin.RouterGroup) {
 		registrations.GET("/my", h.GetUserRegistrations)
 		registrations.GET("/event/:eventId", h.GetEventRegistrations)
 		registrations.POST("/register/:eventId", h.RegisterForEvent)
+		registrations.PUT("/:id", h.EditRegistration)
 		registrations.PUT("/:id/cancel", h.CancelRegistration)
+		registrations.PUT("/:id/confirm", h.ConfirmOffer)
+		registrations.GET("/:id/ticket", h.GetTicket)
+		registrations.POST("/:id/transfer", h.TransferRegistration)
+		registrations.DELETE("/:id/transfer", h.CancelTransfer)
+		registrations.GET("/transfers/:token", h.GetTransfer)
+		registrations.POST("/transfers/:token/accept", h.AcceptTransfer)
+		registrations.POST("/teams", h.CreateTeam)
+		registrations.POST("/teams/join", h.JoinTeam)
+		registrations.GET("/teams/:id", h.GetTeam)
+		registrations.POST("/teams/:id/leave", h.LeaveTeam)
 
 		// Admin-only endpoints
 		admin := registrations.Group("/admin")
 		admin.Use(middleware.RoleRequired(h.cfg, "admin"))
+		admin.PUT("/bulk/status", h.BulkUpdateStatus)
+		admin.PUT("/bulk/attended", h.BulkMarkAttendance)
+		admin.PUT("/teams/:id/status", h.UpdateTeamStatus)
+		admin.GET("/attendance", h.ListAttendance)
+		admin.GET("/attendance/:userId", h.GetAttendance)
+		admin.POST("/overrides", h.CreatePolicyOverride)
+		admin.DELETE("/overrides/:id", h.DeletePolicyOverride)
 		admin.PUT("/:id", h.Update)
 		admin.DELETE("/:id", h.Delete)
 		admin.PUT("/:id/status", h.UpdateStatus)
@@ -98,79 +128,164 @@ func (h *RegistrationHandler) Update(c *gin.Context) {
 
 func (h *RegistrationHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.db.Delete(&models.Registration{}, id).Error; err != nil {
+	var registration models.Registration
+	if err := h.db.First(&registration, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Registration not found"})
+		return
+	}
+	if err := h.db.Delete(&registration).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+
+	if registration.Status.HoldsSpot() {
+		if err := h.promoteWaitlist(registration.EventID); err != nil {
+			log.Printf("Failed to promote from waitlist of event %d: %v", registration.EventID, err)
+		}
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Registration deleted"})
 }
 
-// RegisterForEvent allows a user to register for an event
+// RegisterForEvent allows a user to register for an event. The body is
+// optional unless the event has a registration form with required fields.
 func (h *RegistrationHandler) RegisterForEvent(c *gin.Context) {
 	eventID := c.Param("eventId")
 
-	userID, _, err := h.getUserData(c)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user data"})
+	var input struct {
+		DietaryRestrictions string             `json:"dietary_restrictions"`
+		Answers             models.FormAnswers `json:"answers"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
 		return
 	}
 
 	// Check if event exists
 	var event models.Event
-	if err := h.db.First(&event, eventID).Error; err != nil {
+	if err := h.db.First(&event, eventID).Error; err != nil || !event.IsPublic() {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
-
-	// Check if registration exists already
-	var existingReg models.Registration
-	result := h.db.Where("event_id = ? AND user_id = ?", eventID, userID).First(&existingReg)
-	if result.Error == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "You are already registered for this event"})
+	if event.RegistrationsLocked() {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Registration is closed, the event is %s", event.Status)})
+		return
+	}
+	if event.SelectionMode == models.SelectionLottery && event.ApplicationsClose != nil && !time.Now().Before(*event.ApplicationsClose) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Applications for this event are closed"})
 		return
 	}
 
-	// Check if event has reached max capacity
-	var count int64
-	h.db.Model(&models.Registration{}).Where("event_id = ? AND status != ?",
-		eventID, models.RegistrationStatusRejected).Count(&count)
-
-	if event.RegistrationMax > 0 && int(count) >= event.RegistrationMax {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Event has reached maximum capacity"})
+	deprioritized, ok := h.checkNoShowPolicy(c, event, userID)
+	if !ok {
 		return
 	}
 
-	// Create new registration
-	registration := models.Registration{
-		EventID:  uint(event.ID),
-		UserID:   userID,
-		Status:   models.RegistrationStatusPending,
-		Attended: false,
+	answers, err := event.RegistrationForm.ValidateAnswers(input.Answers)
+	var formErrors models.FormErrors
+	if errors.As(err, &formErrors) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid answers", "fields": formErrors})
+		return
 	}
 
-	if err := h.db.Create(&registration).Error; err != nil {
+	// Count the spots and register in one transaction with the event locked,
+	// otherwise a burst of registrations can overshoot RegistrationMax
+	var registration models.Registration
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		event, err := models.LockEvent(tx, event.ID)
+		if err != nil {
+			return err
+		}
+
+		// Join the waitlist if the event has reached max capacity. In lottery
+		// mode everyone applies and the spots are drawn later.
+		status := models.RegistrationStatusPending
+		if event.SelectionMode != models.SelectionLottery {
+			full, err := models.IsFull(tx, event)
+			if err != nil {
+				return err
+			}
+			if full {
+				status = models.RegistrationStatusWaitlisted
+			}
+		}
+
+		registration = models.Registration{
+			EventID:             event.ID,
+			UserID:              userID,
+			Status:              status,
+			Attended:            false,
+			DietaryRestrictions: input.DietaryRestrictions,
+			Answers:             answers,
+			Deprioritized:       deprioritized,
+		}
+		return tx.Create(&registration).Error
+	})
+	// the unique index on event and user catches registering twice
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		c.JSON(http.StatusConflict, gin.H{"error": "You are already registered for this event"})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if registration.Status == models.RegistrationStatusWaitlisted {
+		registration.WaitlistPosition, err = models.WaitlistPosition(h.db, registration)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	notifyRegistration(h.db, h.cfg, registration)
+
 	c.JSON(http.StatusCreated, registration)
 }
 
 // GetUserRegistrations returns all registrations for the current user
 func (h *RegistrationHandler) GetUserRegistrations(c *gin.Context) {
-	userID := c.GetUint("user_id")
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
 
 	// Optionally get user profile data
-	var profile models.Profile
-	h.db.Where("user_id = ?", userID).First(&profile)
+	profile, _ := models.ProfileForUser(h.db, userID)
 
 	var registrations []models.Registration
 	if err := h.db.Where("user_id = ?", userID).
-		Preload("Event").Find(&registrations).Error; err != nil {
+		Preload("Event").Preload("Team").Find(&registrations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var teams []*models.Team
+	for _, r := range registrations {
+		if r.Team != nil {
+			teams = append(teams, r.Team)
+		}
+	}
+	if err := models.LoadTeamMates(h.db, teams...); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	for i, r := range registrations {
+		if r.Status != models.RegistrationStatusWaitlisted {
+			continue
+		}
+		position, err := models.WaitlistPosition(h.db, r)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		registrations[i].WaitlistPosition = position
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"registrations": registrations,
 		"user": gin.H{
@@ -198,10 +313,13 @@ func (h *RegistrationHandler) GetEventRegistrations(c *gin.Context) {
 // CancelRegistration allows a user to cancel their own registration
 func (h *RegistrationHandler) CancelRegistration(c *gin.Context) {
 	id := c.Param("id")
-	userID := c.GetUint("user_id")
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
 
 	var registration models.Registration
-	if err := h.db.First(&registration, id).Error; err != nil {
+	if err := h.db.Preload("Event").First(&registration, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Registration not found"})
 		return
 	}
@@ -211,16 +329,66 @@ func (h *RegistrationHandler) CancelRegistration(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You can only cancel your own registrations"})
 		return
 	}
+	if !h.checkUnlocked(c, registration) {
+		return
+	}
+	if registration.CancelledAt != nil || registration.Status == models.RegistrationStatusRejected {
+		c.JSON(http.StatusConflict, gin.H{"error": "Registration is already cancelled or rejected"})
+		return
+	}
+	now := time.Now()
+	if !now.Before(registration.Event.StartDate) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The event has already started"})
+		return
+	}
 
-	// Cancel by setting status to rejected
-	registration.Status = models.RegistrationStatusRejected
-
-	if err := h.db.Save(&registration).Error; err != nil {
+	// Cancel by setting status to rejected, giving up a spot after the
+	// deadline is remembered as a late cancellation. Cancelled registrants
+	// leave their team, so that it isn't approved with them in it.
+	heldSpot := registration.Status.HoldsSpot()
+	teamID := registration.TeamID
+	late := heldSpot && !now.Before(registration.Event.CancelDeadline())
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		// only the cancellation is written, and only if nothing changed the
+		// registration in the meantime, so a spot is never given back twice
+		result := tx.Model(&models.Registration{}).
+			Where("id = ? AND status = ? AND cancelled_at IS NULL", registration.ID, registration.Status).
+			Updates(map[string]any{
+				"status":           models.RegistrationStatusRejected,
+				"offer_expires_at": nil,
+				"cancelled_at":     now,
+				"cancelled_late":   late,
+				"team_id":          nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRegistrationChanged
+		}
+		if teamID == nil {
+			return nil
+		}
+		return models.DeleteTeamIfEmpty(tx, *teamID)
+	})
+	if errors.Is(err, errRegistrationChanged) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Registration is already cancelled or was changed, try again"})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Registration cancelled successfully"})
+	// The spot goes to the next person on the waitlist
+	if heldSpot {
+		if err := h.promoteWaitlist(registration.EventID); err != nil {
+			log.Printf("Failed to promote from waitlist of event %d: %v", registration.EventID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Registration cancelled successfully", "late": late})
 }
 
 // UpdateStatus allows admins to update the status of a registration
@@ -235,23 +403,73 @@ func (h *RegistrationHandler) UpdateStatus(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !input.Status.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown status %q", input.Status)})
+		return
+	}
 
 	var registration models.Registration
 	if err := h.db.First(&registration, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Registration not found"})
 		return
 	}
+	if !h.checkUnlocked(c, registration) {
+		return
+	}
 
-	registration.Status = input.Status
-
-	if err := h.db.Save(&registration).Error; err != nil {
+	// the status and the ticket are saved together or not at all
+	var changed, freedSpot bool
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		changed, freedSpot, err = setStatus(tx, &registration, input.Status)
+		return err
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if freedSpot {
+		if err := h.promoteWaitlist(registration.EventID); err != nil {
+			log.Printf("Failed to promote from waitlist of event %d: %v", registration.EventID, err)
+		}
+	}
+	if changed && notifiesRegistrant(registration.Status) {
+		notifyRegistration(h.db, h.cfg, registration)
+	}
+
 	c.JSON(http.StatusOK, registration)
 }
 
+// setStatus changes the status of a registration. Approved registrations get
+// a ticket to check in with, run it in a transaction so they can't end up
+// without one. Returns whether the status changed and whether a spot opened
+// up for the waitlist.
+func setStatus(db *gorm.DB, registration *models.Registration, status models.RegistrationStatus) (changed, freedSpot bool, err error) {
+	freedSpot = registration.Status.HoldsSpot() && !status.HoldsSpot()
+	changed = registration.Status != status
+	registration.Status = status
+	if status != models.RegistrationStatusOffered {
+		registration.OfferExpiresAt = nil
+	}
+
+	if err := db.Save(registration).Error; err != nil {
+		return false, false, err
+	}
+	if changed && status == models.RegistrationStatusApproved {
+		if err := issueTicket(db, registration); err != nil {
+			return false, false, err
+		}
+	}
+	return changed, freedSpot, nil
+}
+
+// notifiesRegistrant reports whether registrants are emailed when their
+// registration changes to status
+func notifiesRegistrant(status models.RegistrationStatus) bool {
+	return status == models.RegistrationStatusApproved || status == models.RegistrationStatusRejected
+}
+
 // MarkAttendance allows admins to mark attendance for a registration
 func (h *RegistrationHandler) MarkAttendance(c *gin.Context) {
 	id := c.Param("id")
@@ -281,14 +499,17 @@ func (h *RegistrationHandler) MarkAttendance(c *gin.Context) {
 	c.JSON(http.StatusOK, registration)
 }
 
-// getUserData retrieves user data from the session and database
-func (h *RegistrationHandler) getUserData(c *gin.Context) (uint, *models.User, error) {
-	userID := c.GetUint("user_id")
-
-	var user models.User
-	if err := h.db.First(&user, userID).Error; err != nil {
-		return 0, nil, err
+// checkUnlocked responds with an error and returns false if the event of a
+// registration is cancelled or completed
+func (h *RegistrationHandler) checkUnlocked(c *gin.Context, registration models.Registration) bool {
+	var event models.Event
+	if err := h.db.First(&event, registration.EventID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
 	}
-
-	return userID, &user, nil
+	if event.RegistrationsLocked() {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Registrations can't be changed, the event is %s", event.Status)})
+		return false
+	}
+	return true
 }
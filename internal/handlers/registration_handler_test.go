@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMyRegistrationsAreTheRequesters(t *testing.T) {
+	db := newTestDB(t)
+	discardEmails(t)
+	r := newTestRouter(NewRegistrationHandler(db, newTestConfig()))
+	event := models.Event{Title: "Hackathon", StartDate: time.Now().Add(72 * time.Hour)}
+	require.NoError(t, db.Create(&event).Error)
+	regs := seedBulkRegistrations(t, db, event, models.RegistrationStatusApproved, models.RegistrationStatusPending)
+
+	users := make([]models.User, len(regs))
+	for i, reg := range regs {
+		require.NoError(t, db.First(&users[i], reg.UserID).Error)
+	}
+	type myRegistrations struct {
+		Registrations []models.Registration
+		User          struct{ ID uint }
+	}
+
+	// scripts send the JWT in the header, the frontend as a cookie
+	bearer := authCookies(users[0], models.RoleUser)[0].Value
+	w := doRequest(r, testRequest{method: http.MethodGet, path: "/api/v1/registrations/my",
+		header: http.Header{"Authorization": {"Bearer " + bearer}}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	mine := decodeBody[myRegistrations](t, w)
+	assert.Equal(t, users[0].ID, mine.User.ID)
+	require.Len(t, mine.Registrations, 1)
+	assert.Equal(t, regs[0].ID, mine.Registrations[0].ID)
+
+	w = doRequest(r, testRequest{method: http.MethodGet, path: "/api/v1/registrations/my", cookies: authCookies(users[1], models.RoleUser)})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	mine = decodeBody[myRegistrations](t, w)
+	require.Len(t, mine.Registrations, 1)
+	assert.Equal(t, regs[1].ID, mine.Registrations[0].ID)
+
+	// users without a profile have to complete it first
+	w = doRequest(r, testRequest{method: http.MethodGet, path: "/api/v1/registrations/my",
+		cookies: authCookies(newTestUser(t, db, "new@kthais.com"), models.RoleUser)})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequest(r, testRequest{method: http.MethodGet, path: "/api/v1/registrations/my"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
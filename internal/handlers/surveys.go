@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// findSurvey loads the survey response and event a link's token belongs to
+func (h *EventHandler) findSurvey(c *gin.Context) (models.SurveyResponse, models.Event, bool) {
+	var event models.Event
+	response, err := models.FindSurvey(h.db, c.Param("token"))
+	if err == nil {
+		err = h.db.First(&event, response.EventID).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Survey not found"})
+		return response, event, false
+	}
+	return response, event, true
+}
+
+// GetSurvey returns the feedback survey of an event. The token in the path
+// is the secret, so this is public and can be linked from emails.
+func (h *EventHandler) GetSurvey(c *gin.Context) {
+	response, event, ok := h.findSurvey(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"event": gin.H{
+			"id":         event.ID,
+			"title":      event.Title,
+			"start_date": event.StartDate,
+			"end_date":   event.EndDate,
+		},
+		"form":      event.SurveyForm,
+		"submitted": response.SubmittedAt != nil,
+	})
+}
+
+// SubmitSurvey stores the feedback of an attendee. Every link can only be
+// used once.
+func (h *EventHandler) SubmitSurvey(c *gin.Context) {
+	var input struct {
+		Rating   int                `json:"rating"`
+		Comments string             `json:"comments"`
+		Answers  models.FormAnswers `json:"answers"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, event, ok := h.findSurvey(c)
+	if !ok {
+		return
+	}
+
+	err := response.Submit(h.db, event.SurveyForm, input.Rating, input.Comments, input.Answers, time.Now())
+	var formErrors models.FormErrors
+	switch {
+	case errors.As(err, &formErrors):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid answers", "fields": formErrors})
+	case errors.Is(err, models.ErrSurveySubmitted):
+		c.JSON(http.StatusConflict, gin.H{"error": "You have already given feedback, thank you!"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Thank you for your feedback!"})
+	}
+}
+
+// GetSurveyResults returns the survey responses of an event aggregated:
+// the response rate, the average rating and the answers to each question
+func (h *EventHandler) GetSurveyResults(c *gin.Context) {
+	var event models.Event
+	if err := h.db.First(&event, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if _, ok := h.authorizeEvent(c, event); !ok {
+		return
+	}
+
+	summary, err := models.SummarizeSurvey(h.db, event)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
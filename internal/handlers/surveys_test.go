@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSurveys(t *testing.T) {
+	db := newTestDB(t)
+	admin := newTestAdmin(t, db)
+	r := newTestRouter(NewEventHandler(db, newTestConfig()))
+	end := time.Now().Add(-2 * time.Hour)
+	event := models.Event{Title: "Workshop", StartDate: end.Add(-2 * time.Hour), EndDate: end, SurveyForm: models.FormSchema{
+		Fields: []models.FormField{
+			{Name: "pace", Label: "How was the pace?", Type: models.FormFieldSelect, Options: []string{"slow", "right", "fast"}},
+			{Name: "again", Label: "Would you come again?", Type: models.FormFieldCheckbox},
+		},
+	}}
+	require.NoError(t, db.Create(&event).Error)
+
+	var tokens []string
+	for _, registration := range seedBulkRegistrations(t, db, event, models.RegistrationStatusApproved, models.RegistrationStatusApproved,
+		models.RegistrationStatusApproved) {
+		require.NoError(t, db.Model(&registration).Update("attended", true).Error)
+		token, claimed, err := models.ClaimSurvey(db, registration, time.Now())
+		require.NoError(t, err)
+		require.True(t, claimed)
+		tokens = append(tokens, token)
+	}
+
+	w := doRequest(r, testRequest{method: http.MethodGet, path: "/api/v1/surveys/" + tokens[0]})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	survey := decodeBody[struct {
+		Form      models.FormSchema
+		Submitted bool
+	}](t, w)
+	assert.Len(t, survey.Form.Fields, 2)
+	assert.False(t, survey.Submitted)
+
+	submit := func(token string, body gin.H) int {
+		t.Helper()
+		return doRequest(r, testRequest{method: http.MethodPost, path: "/api/v1/surveys/" + token, body: body}).Code
+	}
+	assert.Equal(t, http.StatusBadRequest, submit(tokens[0], gin.H{"rating": 6}))
+	assert.Equal(t, http.StatusBadRequest, submit(tokens[0], gin.H{"rating": 4, "answers": gin.H{"pace": "glacial"}}))
+	assert.Equal(t, http.StatusOK, submit(tokens[0], gin.H{"rating": 4, "comments": " Great! ",
+		"answers": gin.H{"pace": "fast", "again": true}}))
+	assert.Equal(t, http.StatusConflict, submit(tokens[0], gin.H{"rating": 1}), "links can only be used once")
+	assert.Equal(t, http.StatusOK, submit(tokens[1], gin.H{"rating": 5, "answers": gin.H{"pace": "fast"}}))
+	assert.Equal(t, http.StatusNotFound, submit("not-a-token", gin.H{"rating": 5}))
+
+	w = doRequest(r, testRequest{method: http.MethodGet, path: "/api/v1/surveys/" + tokens[0]})
+	assert.True(t, decodeBody[struct{ Submitted bool }](t, w).Submitted)
+
+	w = doRequest(r, testRequest{method: http.MethodGet, path: fmt.Sprintf("/api/v1/event/%d/survey", event.ID), cookies: admin})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	summary := decodeBody[models.SurveySummary](t, w)
+	assert.Equal(t, 3, summary.Invited)
+	assert.Equal(t, 2, summary.Responses)
+	assert.InDelta(t, 2.0/3, summary.ResponseRate, 0.001)
+	assert.InDelta(t, 4.5, summary.AverageRating, 0.001)
+	assert.Equal(t, map[int]int{1: 0, 2: 0, 3: 0, 4: 1, 5: 1}, summary.Ratings)
+	assert.Equal(t, []string{"Great!"}, summary.Comments)
+	require.Len(t, summary.Questions, 2)
+	assert.Equal(t, map[string]int{"slow": 0, "right": 0, "fast": 2}, summary.Questions[0].Counts)
+	assert.Equal(t, 1, summary.Questions[1].Responses)
+	assert.Equal(t, map[string]int{"true": 1, "false": 0}, summary.Questions[1].Counts)
+}
@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// teamError is an error to show the user, with the status to respond with
+type teamError struct {
+	status  int
+	message string
+}
+
+func (e teamError) Error() string {
+	return e.message
+}
+
+// ownRegistration loads the registration of a user for an event
+func ownRegistration(tx *gorm.DB, eventID, userID uint) (models.Registration, error) {
+	var registration models.Registration
+	err := tx.Where("event_id = ? AND user_id = ?", eventID, userID).First(&registration).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return registration, teamError{http.StatusNotFound, "Register for the event first"}
+	}
+	return registration, err
+}
+
+// respondTeamError responds with the status of a teamError, or 500 for
+// anything else
+func respondTeamError(c *gin.Context, err error) {
+	var te teamError
+	if errors.As(err, &te) {
+		c.JSON(te.status, gin.H{"error": te.message})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// CreateTeam creates a team for an event the user is registered for and puts
+// them in it. Teammates join with the invite code of the team.
+func (h *RegistrationHandler) CreateTeam(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		EventID uint   `json:"event_id" binding:"required"`
+		Name    string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	var team models.Team
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		event, err := models.LockEvent(tx, input.EventID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return teamError{http.StatusNotFound, "Event not found"}
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkTeamsOpen(event); err != nil {
+			return err
+		}
+		registration, err := ownRegistration(tx, event.ID, userID)
+		if err != nil {
+			return err
+		}
+		if registration.TeamID != nil {
+			return teamError{http.StatusConflict, "You are already in a team, leave it first"}
+		}
+		if registration.Status == models.RegistrationStatusRejected {
+			return teamError{http.StatusConflict, "Your registration was rejected"}
+		}
+
+		team = models.Team{EventID: event.ID, Name: input.Name, InviteCode: models.NewInviteCode(), CreatedBy: userID}
+		if err := tx.Create(&team).Error; err != nil {
+			return err
+		}
+		return tx.Model(&registration).Update("team_id", team.ID).Error
+	})
+	if err != nil {
+		respondTeamError(c, err)
+		return
+	}
+
+	if err := models.LoadTeamMates(h.db, &team); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, team)
+}
+
+// JoinTeam puts the registration of the user into the team with the invite
+// code. If the event counts capacity in teams, the registration takes on the
+// status of the team, the team holds the spot for all of its members.
+func (h *RegistrationHandler) JoinTeam(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		InviteCode string `json:"invite_code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var team models.Team
+	var registration models.Registration
+	var changed, freedSpot, promote bool
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		code := strings.ToUpper(strings.TrimSpace(input.InviteCode))
+		if err := tx.Where("invite_code = ?", code).First(&team).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return teamError{http.StatusNotFound, "No team has this invite code"}
+			}
+			return err
+		}
+		// the lock also keeps two people from taking the last place in a team
+		event, err := models.LockEvent(tx, team.EventID)
+		if err != nil {
+			return err
+		}
+		if err := checkTeamsOpen(event); err != nil {
+			return err
+		}
+		registration, err = ownRegistration(tx, event.ID, userID)
+		if err != nil {
+			return err
+		}
+		if registration.TeamID != nil {
+			if *registration.TeamID == team.ID {
+				return teamError{http.StatusConflict, "You are already in this team"}
+			}
+			return teamError{http.StatusConflict, "You are already in a team, leave it first"}
+		}
+		if registration.Status == models.RegistrationStatusRejected {
+			return teamError{http.StatusConflict, "Your registration was rejected"}
+		}
+		size, err := models.TeamSize(tx, team.ID)
+		if err != nil {
+			return err
+		}
+		if size >= event.TeamMaxSize {
+			return teamError{http.StatusConflict, fmt.Sprintf("The team is full, teams have at most %d members", event.TeamMaxSize)}
+		}
+
+		registration.TeamID = &team.ID
+		if !event.CapacityCountsTeams {
+			return tx.Save(&registration).Error
+		}
+		// members share a status, the one of whoever registered first.
+		// Members the organizers rejected don't speak for the team.
+		var first models.Registration
+		err = tx.Where("team_id = ? AND status <> ?", team.ID, models.RegistrationStatusRejected).
+			Order("created_at ASC, id ASC").First(&first).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// the team holds no spot, they keep their own
+			return tx.Save(&registration).Error
+		}
+		if err != nil {
+			return err
+		}
+		// a spot of their own goes back to the waitlist, the team holds one
+		promote = registration.Status.HoldsSpot()
+		changed, freedSpot, err = setStatus(tx, &registration, first.Status)
+		return err
+	})
+	if err != nil {
+		respondTeamError(c, err)
+		return
+	}
+
+	if promote || freedSpot {
+		if err := h.promoteWaitlist(team.EventID); err != nil {
+			log.Printf("Failed to promote from waitlist of event %d: %v", team.EventID, err)
+		}
+	}
+	if changed && notifiesRegistrant(registration.Status) {
+		notifyRegistration(h.db, h.cfg, registration)
+	}
+
+	if err := models.LoadTeamMates(h.db, &team); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, team)
+}
+
+// LeaveTeam takes the registration of the user out of a team and returns it.
+// The last member to leave deletes the team. If the event counts capacity in
+// teams, the member now needs a spot of their own and is waitlisted if the
+// event is full.
+func (h *RegistrationHandler) LeaveTeam(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var team models.Team
+	if err := h.db.First(&team, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	var registration models.Registration
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		event, err := models.LockEvent(tx, team.EventID)
+		if err != nil {
+			return err
+		}
+		if event.RegistrationsLocked() {
+			return teamError{http.StatusConflict, fmt.Sprintf("Teams can't be changed, the event is %s", event.Status)}
+		}
+		registration, err = ownRegistration(tx, event.ID, userID)
+		if err != nil {
+			return err
+		}
+		if registration.TeamID == nil || *registration.TeamID != team.ID {
+			return teamError{http.StatusNotFound, "You are not in this team"}
+		}
+
+		registration.TeamID = nil
+		if err := tx.Model(&registration).Update("team_id", nil).Error; err != nil {
+			return err
+		}
+		if event.CapacityCountsTeams && registration.Status.HoldsSpot() && event.RegistrationMax > 0 {
+			held, err := models.CountHeldSpots(tx, event)
+			if err != nil {
+				return err
+			}
+			if held > event.RegistrationMax {
+				if _, _, err := setStatus(tx, &registration, models.RegistrationStatusWaitlisted); err != nil {
+					return err
+				}
+			}
+		}
+
+		return models.DeleteTeamIfEmpty(tx, team.ID)
+	})
+	if err != nil {
+		respondTeamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, registration)
+}
+
+// GetTeam returns a team with its members, only to its members
+func (h *RegistrationHandler) GetTeam(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var team models.Team
+	if err := h.db.First(&team, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+	var count int64
+	if err := h.db.Model(&models.Registration{}).Where("team_id = ? AND user_id = ?", team.ID, userID).
+		Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	if err := models.LoadTeamMates(h.db, &team); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, team)
+}
+
+// UpdateTeamStatus lets admins approve or reject all members of a team at
+// once. Teams smaller than the minimum size of the event can't be approved.
+func (h *RegistrationHandler) UpdateTeamStatus(c *gin.Context) {
+	var input struct {
+		Status models.RegistrationStatus `json:"status" binding:"required"`
+		Notify bool                      `json:"notify"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !input.Status.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown status %q", input.Status)})
+		return
+	}
+
+	var team models.Team
+	if err := h.db.First(&team, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+	if input.Status == models.RegistrationStatusApproved {
+		var event models.Event
+		if err := h.db.First(&event, team.EventID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		size, err := models.TeamSize(h.db, team.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if size < event.TeamMinSize {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("The team has %d members, teams need at least %d", size, event.TeamMinSize)})
+			return
+		}
+	}
+
+	selection := bulkSelection{Filter: &bulkFilter{TeamID: team.ID}}
+	results, err := h.bulkSetStatus(selection, input.Status, input.Notify)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// checkTeamsOpen returns a teamError unless teams of an event can be formed
+func checkTeamsOpen(event models.Event) error {
+	if !event.HasTeams() {
+		return teamError{http.StatusBadRequest, "This event doesn't have teams"}
+	}
+	if event.RegistrationsLocked() {
+		return teamError{http.StatusConflict, fmt.Sprintf("Teams can't be changed, the event is %s", event.Status)}
+	}
+	return nil
+}
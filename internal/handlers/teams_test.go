@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestTeams(t *testing.T) {
+	db := newTestDB(t)
+	discardEmails(t)
+	h := NewRegistrationHandler(db, newTestConfig())
+	event := models.Event{Title: "Hackathon", TeamMaxSize: 2}
+	require.NoError(t, db.Create(&event).Error)
+	regs := seedBulkRegistrations(t, db, event, models.RegistrationStatusPending, models.RegistrationStatusPending,
+		models.RegistrationStatusPending)
+	ada, bob, carol := regs[0].UserID, regs[1].UserID, regs[2].UserID
+
+	w := doRequest(actingAs(h, ada), testRequest{method: http.MethodPost, path: "/teams",
+		body: gin.H{"event_id": event.ID, "name": "Transformers"}})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	team := decodeBody[models.Team](t, w)
+	require.Len(t, team.InviteCode, 8)
+	assert.Len(t, team.Members, 1)
+
+	// codes are case insensitive
+	w = doRequest(actingAs(h, bob), testRequest{method: http.MethodPost, path: "/teams/join",
+		body: gin.H{"invite_code": strings.ToLower(team.InviteCode)}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Len(t, decodeBody[models.Team](t, w).Members, 2)
+
+	w = doRequest(actingAs(h, carol), testRequest{method: http.MethodPost, path: "/teams/join",
+		body: gin.H{"invite_code": team.InviteCode}})
+	assert.Equal(t, http.StatusConflict, w.Code, "the team is full")
+	w = doRequest(actingAs(h, carol), testRequest{method: http.MethodGet, path: fmt.Sprintf("/teams/%d", team.ID)})
+	assert.Equal(t, http.StatusNotFound, w.Code, "only members see the team")
+
+	w = doRequest(actingAs(h, bob), testRequest{method: http.MethodGet, path: "/my"})
+	require.Equal(t, http.StatusOK, w.Code)
+	my := decodeBody[struct{ Registrations []models.Registration }](t, w)
+	require.Len(t, my.Registrations, 1)
+	require.NotNil(t, my.Registrations[0].Team)
+	assert.Equal(t, "Transformers", my.Registrations[0].Team.Name)
+	assert.Len(t, my.Registrations[0].Team.Members, 2)
+
+	for _, user := range []uint{bob, ada} {
+		w = doRequest(actingAs(h, user), testRequest{method: http.MethodPost, path: fmt.Sprintf("/teams/%d/leave", team.ID)})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Nil(t, decodeBody[models.Registration](t, w).TeamID)
+	}
+	// the last one out deletes the team
+	assert.ErrorIs(t, db.First(&models.Team{}, team.ID).Error, gorm.ErrRecordNotFound)
+}
+
+func TestTeamsNeedRegistrationAndTeamEvent(t *testing.T) {
+	db := newTestDB(t)
+	discardEmails(t)
+	h := NewRegistrationHandler(db, newTestConfig())
+	solo := models.Event{Title: "Lecture"}
+	require.NoError(t, db.Create(&solo).Error)
+	hackathon := models.Event{Title: "Hackathon", TeamMaxSize: 4}
+	require.NoError(t, db.Create(&hackathon).Error)
+	regs := seedBulkRegistrations(t, db, solo, models.RegistrationStatusPending)
+
+	w := doRequest(actingAs(h, regs[0].UserID), testRequest{method: http.MethodPost, path: "/teams",
+		body: gin.H{"event_id": solo.ID, "name": "Solo"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(actingAs(h, regs[0].UserID), testRequest{method: http.MethodPost, path: "/teams",
+		body: gin.H{"event_id": hackathon.ID, "name": "Unregistered"}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(actingAs(h, regs[0].UserID), testRequest{method: http.MethodPost, path: "/teams/join",
+		body: gin.H{"invite_code": "NOSUCHCO"}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestTeamsCountAsOneSpot(t *testing.T) {
+	db := newTestDB(t)
+	captureRegistrationEmails(t, nil)
+	h := NewRegistrationHandler(db, newTestConfig())
+	event := models.Event{Title: "Hackathon", RegistrationMax: 2, TeamMaxSize: 4, CapacityCountsTeams: true}
+	require.NoError(t, db.Create(&event).Error)
+
+	var users []uint
+	register := func(i int) models.Registration {
+		t.Helper()
+		user := newTestUser(t, db, fmt.Sprintf("hacker%d@kthais.com", i))
+		users = append(users, user.ID)
+		w := doRequest(actingAs(h, user.ID), testRequest{method: http.MethodPost, path: fmt.Sprintf("/register/%d", event.ID)})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		return decodeBody[models.Registration](t, w)
+	}
+	register(0)
+	register(1)
+	assert.Equal(t, models.RegistrationStatusWaitlisted, register(2).Status)
+
+	w := doRequest(actingAs(h, users[0]), testRequest{method: http.MethodPost, path: "/teams",
+		body: gin.H{"event_id": event.ID, "name": "Transformers"}})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	team := decodeBody[models.Team](t, w)
+
+	// joining a team gives up the spot of their own to the waitlist
+	w = doRequest(actingAs(h, users[1]), testRequest{method: http.MethodPost, path: "/teams/join",
+		body: gin.H{"invite_code": team.InviteCode}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var third models.Registration
+	require.NoError(t, db.Where("user_id = ?", users[2]).First(&third).Error)
+	assert.Equal(t, models.RegistrationStatusPending, third.Status)
+
+	// the team can grow without taking more spots, others are waitlisted
+	assert.Equal(t, models.RegistrationStatusWaitlisted, register(3).Status)
+	w = doRequest(actingAs(h, users[3]), testRequest{method: http.MethodPost, path: "/teams/join",
+		body: gin.H{"invite_code": team.InviteCode}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var fourth models.Registration
+	require.NoError(t, db.Where("user_id = ?", users[3]).First(&fourth).Error)
+	assert.Equal(t, models.RegistrationStatusPending, fourth.Status, "members take on the status of the team")
+
+	// leaving a team of a full event means waiting for a spot
+	w = doRequest(actingAs(h, users[3]), testRequest{method: http.MethodPost, path: fmt.Sprintf("/teams/%d/leave", team.ID)})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, models.RegistrationStatusWaitlisted, decodeBody[models.Registration](t, w).Status)
+}
+
+func TestUpdateTeamStatus(t *testing.T) {
+	db := newTestDB(t)
+	sent := captureRegistrationEmails(t, nil)
+	h := NewRegistrationHandler(db, newTestConfig())
+	event := models.Event{Title: "Hackathon", TeamMinSize: 2, TeamMaxSize: 4}
+	require.NoError(t, db.Create(&event).Error)
+	regs := seedBulkRegistrations(t, db, event, models.RegistrationStatusPending, models.RegistrationStatusPending,
+		models.RegistrationStatusPending)
+	team := models.Team{EventID: event.ID, Name: "Transformers", InviteCode: models.NewInviteCode()}
+	require.NoError(t, db.Create(&team).Error)
+	require.NoError(t, db.Model(&regs[0]).Update("team_id", team.ID).Error)
+
+	path := fmt.Sprintf("/admin/teams/%d/status", team.ID)
+	w := doRequest(actingAs(h, 0), testRequest{method: http.MethodPut, path: path,
+		body: gin.H{"status": models.RegistrationStatusApproved}})
+	assert.Equal(t, http.StatusConflict, w.Code, "the team is too small")
+
+	require.NoError(t, db.Model(&regs[1]).Update("team_id", team.ID).Error)
+	results := bulkResults(t, actingAs(h, 0), path, gin.H{"status": models.RegistrationStatusApproved, "notify": true})
+	assert.Len(t, results, 2)
+	for _, r := range regs[:2] {
+		assert.Equal(t, bulkUpdated, results[r.ID].Result)
+		assert.Equal(t, models.RegistrationStatusApproved, nextEmail(t, sent).status)
+	}
+	var outsider models.Registration
+	require.NoError(t, db.First(&outsider, regs[2].ID).Error)
+	assert.Equal(t, models.RegistrationStatusPending, outsider.Status)
+}
+
+func TestCancelledMembersLeaveTheirTeam(t *testing.T) {
+	db := newTestDB(t)
+	sent := captureRegistrationEmails(t, nil)
+	h := NewRegistrationHandler(db, newTestConfig())
+	event := models.Event{Title: "Hackathon", StartDate: time.Now().Add(72 * time.Hour), TeamMinSize: 2, TeamMaxSize: 3}
+	require.NoError(t, db.Create(&event).Error)
+	regs := seedBulkRegistrations(t, db, event, models.RegistrationStatusPending, models.RegistrationStatusPending,
+		models.RegistrationStatusPending)
+	team := models.Team{EventID: event.ID, Name: "Transformers", InviteCode: models.NewInviteCode()}
+	require.NoError(t, db.Create(&team).Error)
+	for _, r := range regs {
+		require.NoError(t, db.Model(&r).Update("team_id", team.ID).Error)
+	}
+
+	w := doRequest(actingAs(h, regs[0].UserID), testRequest{method: http.MethodPut, path: fmt.Sprintf("/%d/cancel", regs[0].ID)})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	size, err := models.TeamSize(db, team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, size)
+
+	results := bulkResults(t, actingAs(h, 0), fmt.Sprintf("/admin/teams/%d/status", team.ID),
+		gin.H{"status": models.RegistrationStatusApproved, "notify": true})
+	assert.Len(t, results, 2)
+	assert.NotContains(t, results, regs[0].ID)
+	for range regs[1:] {
+		assert.Equal(t, models.RegistrationStatusApproved, nextEmail(t, sent).status)
+	}
+	var cancelled models.Registration
+	require.NoError(t, db.First(&cancelled, regs[0].ID).Error)
+	assert.Equal(t, models.RegistrationStatusRejected, cancelled.Status)
+	assert.Nil(t, cancelled.TeamID)
+	assert.Empty(t, cancelled.TicketNonce)
+	select {
+	case e := <-sent:
+		t.Fatalf("unexpected email to %s", e.to)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/models"
+	"backend/internal/tickets"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ticketQRSize is the width and height of ticket QR codes in pixels
+const ticketQRSize = 256
+
+// GetTicket returns the ticket of one of the user's approved registrations
+func (h *RegistrationHandler) GetTicket(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var registration models.Registration
+	if err := h.db.First(&registration, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Registration not found"})
+		return
+	}
+	if registration.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only view your own tickets"})
+		return
+	}
+	if registration.Status != models.RegistrationStatusApproved || registration.TicketNonce == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Registration has no ticket"})
+		return
+	}
+
+	token := ticketToken(h.cfg, registration)
+	c.JSON(http.StatusOK, gin.H{
+		"token":  token,
+		"qr_url": ticketQRURL(h.cfg, token),
+	})
+}
+
+// TicketQR renders a ticket as a QR code. The token itself is the secret, so
+// this is public and can be linked from emails.
+func (h *RegistrationHandler) TicketQR(c *gin.Context) {
+	token := c.Param("token")
+	if _, err := verifyTicket(h.db, h.cfg, token); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+		return
+	}
+
+	png, err := tickets.QRCode(token, ticketQRSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// CheckIn marks the holder of a ticket as attending the event. Scanning the
+// same ticket again succeeds but reports that it was already checked in.
+func (h *EventHandler) CheckIn(c *gin.Context) {
+	var input struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var event models.Event
+	if err := h.db.First(&event, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if _, ok := h.authorizeEvent(c, event); !ok {
+		return
+	}
+
+	registration, err := verifyTicket(h.db, h.cfg, input.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if registration.EventID != event.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": "Ticket is for another event"})
+		return
+	}
+	if registration.Status != models.RegistrationStatusApproved {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Registration is %s", registration.Status)})
+		return
+	}
+
+	// only the first scan sets the check-in time, even if two scanners race
+	now := time.Now()
+	result := h.db.Model(&registration).Where("checked_in_at IS NULL").
+		Updates(map[string]any{"attended": true, "checked_in_at": now})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	alreadyCheckedIn := result.RowsAffected == 0
+	if err := h.db.First(&registration, registration.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	attendee := ""
+	if profile, err := models.ProfileForUser(h.db, registration.UserID); err == nil {
+		attendee = profile.FirstName + " " + profile.LastName
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"registration":       registration,
+		"attendee":           attendee,
+		"already_checked_in": alreadyCheckedIn,
+	})
+}
+
+var (
+	errTicketNotFound = errors.New("ticket not found")
+	errTicketRevoked  = errors.New("ticket is no longer valid")
+)
+
+// verifyTicket checks the signature of a ticket token and that it is still the
+// current ticket of its registration
+func verifyTicket(db *gorm.DB, cfg *config.Config, token string) (models.Registration, error) {
+	var registration models.Registration
+	ticket, err := tickets.Parse(cfg.TicketSigningKey, token)
+	if err != nil {
+		return registration, err
+	}
+	if err := db.First(&registration, ticket.RegistrationID).Error; err != nil {
+		return registration, errTicketNotFound
+	}
+	if registration.EventID != ticket.EventID || registration.TicketNonce != ticket.Nonce {
+		return registration, errTicketRevoked
+	}
+	return registration, nil
+}
+
+// issueTicket gives a registration a ticket if it doesn't have one yet
+func issueTicket(db *gorm.DB, registration *models.Registration) error {
+	if registration.TicketNonce != "" {
+		return nil
+	}
+	registration.TicketNonce = tickets.NewNonce()
+	return db.Model(registration).Update("ticket_nonce", registration.TicketNonce).Error
+}
+
+func ticketToken(cfg *config.Config, registration models.Registration) string {
+	return tickets.Sign(cfg.TicketSigningKey, tickets.Ticket{
+		RegistrationID: registration.ID,
+		EventID:        registration.EventID,
+		Nonce:          registration.TicketNonce,
+	})
+}
+
+func ticketQRURL(cfg *config.Config, token string) string {
+	return fmt.Sprintf("%s/api/v1/tickets/%s/qr.png", cfg.BackendURL, token)
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type checkInResponse struct {
+	Registration     models.Registration `json:"registration"`
+	AlreadyCheckedIn bool                `json:"already_checked_in"`
+}
+
+func newTicketRegistration(t *testing.T, db *gorm.DB, eventID, userID uint, status models.RegistrationStatus) models.Registration {
+	t.Helper()
+	registration := models.Registration{EventID: eventID, UserID: userID, Status: status}
+	require.NoError(t, db.Create(&registration).Error)
+	require.NoError(t, issueTicket(db, &registration))
+	return registration
+}
+
+func TestCheckIn(t *testing.T) {
+	db := newTestDB(t)
+	discardEmails(t)
+	cfg := newTestConfig()
+	events := NewEventHandler(db, cfg)
+	r := newTestRouter(events, NewRegistrationHandler(db, cfg))
+	admin := newTestAdmin(t, db)
+	attendee := newTestUser(t, db, "attendee@kthais.com")
+
+	event := models.Event{Title: "Lecture"}
+	other := models.Event{Title: "Other lecture"}
+	require.NoError(t, db.Create(&event).Error)
+	require.NoError(t, db.Create(&other).Error)
+
+	approved := newTicketRegistration(t, db, event.ID, attendee.ID, models.RegistrationStatusApproved)
+	cancelled := newTicketRegistration(t, db, event.ID, attendee.ID+1, models.RegistrationStatusRejected)
+	forOther := newTicketRegistration(t, db, other.ID, attendee.ID, models.RegistrationStatusApproved)
+	revoked := newTicketRegistration(t, db, event.ID, attendee.ID+2, models.RegistrationStatusApproved)
+	revokedToken := ticketToken(cfg, revoked)
+	require.NoError(t, db.Model(&revoked).Update("ticket_nonce", "new nonce").Error)
+
+	checkIn := func(token string) *httptest.ResponseRecorder {
+		return doRequest(r, testRequest{
+			method:  http.MethodPost,
+			path:    fmt.Sprintf("/api/v1/event/%d/checkin", event.ID),
+			cookies: admin,
+			body:    gin.H{"token": token},
+		})
+	}
+
+	w := checkIn(ticketToken(cfg, approved))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	first := decodeBody[checkInResponse](t, w)
+	assert.True(t, first.Registration.Attended)
+	require.NotNil(t, first.Registration.CheckedInAt)
+	assert.False(t, first.AlreadyCheckedIn)
+
+	// scanning twice is fine and keeps the first check-in time
+	w = checkIn(ticketToken(cfg, approved))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	second := decodeBody[checkInResponse](t, w)
+	assert.True(t, second.AlreadyCheckedIn)
+	assert.True(t, first.Registration.CheckedInAt.Equal(*second.Registration.CheckedInAt))
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"other event", ticketToken(cfg, forOther), http.StatusConflict},
+		{"cancelled registration", ticketToken(cfg, cancelled), http.StatusConflict},
+		{"revoked ticket", revokedToken, http.StatusBadRequest},
+		{"tampered token", ticketToken(cfg, approved) + "x", http.StatusBadRequest},
+		{"garbage", "not a ticket", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, checkIn(tt.token).Code)
+		})
+	}
+
+	var unchanged models.Registration
+	require.NoError(t, db.First(&unchanged, forOther.ID).Error)
+	assert.False(t, unchanged.Attended)
+}
+
+func TestCheckInRequiresOrganizer(t *testing.T) {
+	f := newAuthFixture(t)
+
+	for actor, want := range map[string]int{
+		"anonymous":   http.StatusUnauthorized,
+		"user":        http.StatusForbidden,
+		"organizer":   http.StatusForbidden,
+		"coorganizer": http.StatusBadRequest, // allowed, but the token is invalid
+		"admin":       http.StatusBadRequest,
+	} {
+		w := doRequest(f.router, testRequest{
+			method:  http.MethodPost,
+			path:    fmt.Sprintf("/api/v1/event/%d/checkin", f.event.ID),
+			cookies: f.cookies[actor],
+			body:    gin.H{"token": "invalid"},
+		})
+		assert.Equal(t, want, w.Code, actor)
+	}
+}
+
+func TestTicketQR(t *testing.T) {
+	db := newTestDB(t)
+	discardEmails(t)
+	cfg := newTestConfig()
+	r := newTestRouter(NewRegistrationHandler(db, cfg))
+	registration := newTicketRegistration(t, db, 1, 1, models.RegistrationStatusApproved)
+	token := ticketToken(cfg, registration)
+
+	w := doRequest(r, testRequest{method: http.MethodGet, path: "/api/v1/tickets/" + token + "/qr.png"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	img, err := png.Decode(bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, ticketQRSize, img.Bounds().Dx())
+
+	w = doRequest(r, testRequest{method: http.MethodGet, path: "/api/v1/tickets/" + token + "x/qr.png"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestApprovalWithoutTicketIsRolledBack(t *testing.T) {
+	db := newTestDB(t)
+	discardEmails(t)
+	r := actingAs(NewRegistrationHandler(db, newTestConfig()), 0)
+	event := models.Event{Title: "Workshop"}
+	require.NoError(t, db.Create(&event).Error)
+	registration := seedBulkRegistrations(t, db, event, models.RegistrationStatusPending)[0]
+	path := fmt.Sprintf("/admin/%d/status", registration.ID)
+
+	w := doRequest(r, testRequest{method: http.MethodPut, path: path, body: gin.H{"status": "confirmed"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// issuing the ticket fails after the status was saved
+	require.NoError(t, db.Callback().Update().Before("gorm:update").Register("fail_tickets", func(tx *gorm.DB) {
+		if dest, ok := tx.Statement.Dest.(map[string]any); ok && dest["ticket_nonce"] != nil {
+			tx.AddError(errors.New("disk full"))
+		}
+	}))
+	w = doRequest(r, testRequest{method: http.MethodPut, path: path, body: gin.H{"status": models.RegistrationStatusApproved}})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	var stored models.Registration
+	require.NoError(t, db.First(&stored, registration.ID).Error)
+	assert.Equal(t, models.RegistrationStatusPending, stored.Status)
+	assert.Empty(t, stored.TicketNonce)
+}
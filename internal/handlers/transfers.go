@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"backend/internal/email"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// swapped out in tests
+var (
+	sendTransferOfferEmail = email.SendTransferOfferEmail
+	sendTransferDoneEmail  = email.SendTransferDoneEmail
+)
+
+// checkTransferable reports whether spots at an event can change hands now
+func checkTransferable(c *gin.Context, event models.Event) bool {
+	if event.TransfersDisabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This event doesn't allow transfers"})
+		return false
+	}
+	if event.RegistrationsLocked() {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Registrations can't be changed, the event is %s", event.Status)})
+		return false
+	}
+	if deadline := event.EditDeadline(); !time.Now().Before(deadline) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Spots at %s could only be transferred until %s",
+			event.Title, deadline.Format(time.RFC3339))})
+		return false
+	}
+	return true
+}
+
+// TransferRegistration offers the user's approved registration to another
+// member by email. They get a link to accept it with, until then the spot
+// stays with the user. A new transfer replaces an open one.
+func (h *RegistrationHandler) TransferRegistration(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var registration models.Registration
+	if err := h.db.Preload("Event").First(&registration, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Registration not found"})
+		return
+	}
+	if registration.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only transfer your own registrations"})
+		return
+	}
+	if registration.Status != models.RegistrationStatusApproved {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only approved registrations can be transferred"})
+		return
+	}
+	event := registration.Event
+	if !checkTransferable(c, event) {
+		return
+	}
+
+	// only members who completed their profile can take over a spot
+	var recipient models.User
+	if err := h.db.Where("email = ?", input.Email).First(&recipient).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No member with that email"})
+		return
+	}
+	recipientProfile, err := models.ProfileForUser(h.db, recipient.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No member with that email"})
+		return
+	}
+	if recipient.ID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You can't transfer a registration to yourself"})
+		return
+	}
+	var held int64
+	if err := h.db.Model(&models.Registration{}).
+		Where("event_id = ? AND user_id = ? AND status IN ?", event.ID, recipient.ID, models.SpotHoldingStatuses).
+		Count(&held).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if held > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "They are already registered for this event"})
+		return
+	}
+	// a transfer is no way around the no-show policy
+	_, applies, err := models.CheckNoShowPolicy(h.db, event, recipient.ID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if applies && event.NoShowPolicy.Action == models.NoShowBlock {
+		c.JSON(http.StatusForbidden, gin.H{"error": "They can't take over your spot, this event turns away registrants who missed too many events"})
+		return
+	}
+
+	transfer, token, err := models.CreateTransfer(h.db, registration, recipient.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := models.ProfileForUser(h.db, userID)
+	if err != nil {
+		log.Printf("Failed to load profile of user %d for transfer email: %v", userID, err)
+	}
+	acceptURL := fmt.Sprintf("%s/transfers/%s", h.cfg.FrontendURL, token)
+	deadline := event.EditDeadline()
+	send := sendTransferOfferEmail
+	go func() {
+		if err := send(recipientProfile, event, profile, acceptURL, &deadline); err != nil {
+			log.Printf("Failed to send transfer email to %s: %v", recipientProfile.Email, err)
+		}
+	}()
+
+	c.JSON(http.StatusCreated, transfer)
+}
+
+// CancelTransfer withdraws the open transfer of one of the user's registrations
+func (h *RegistrationHandler) CancelTransfer(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var registration models.Registration
+	if err := h.db.First(&registration, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Registration not found"})
+		return
+	}
+	if registration.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only cancel transfers of your own registrations"})
+		return
+	}
+	if err := models.CancelTransfers(h.db, registration.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Transfer cancelled"})
+}
+
+// findTransfer loads the transfer a token belongs to. Only its recipient can
+// see it, to anyone else it doesn't exist.
+func (h *RegistrationHandler) findTransfer(c *gin.Context) (models.RegistrationTransfer, models.Event, bool) {
+	var event models.Event
+	userID, ok := currentUserID(c)
+	if !ok {
+		return models.RegistrationTransfer{}, event, false
+	}
+	transfer, err := models.FindTransfer(h.db, c.Param("token"))
+	if err == nil && transfer.ToUserID != userID {
+		err = gorm.ErrRecordNotFound
+	}
+	if err == nil {
+		err = h.db.First(&event, transfer.EventID).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transfer not found"})
+		return transfer, event, false
+	}
+	return transfer, event, true
+}
+
+// GetTransfer returns a transfer offered to the user, with the event and the
+// registration form they fill in when accepting
+func (h *RegistrationHandler) GetTransfer(c *gin.Context) {
+	transfer, event, ok := h.findTransfer(c)
+	if !ok {
+		return
+	}
+	from, err := models.ProfileForUser(h.db, transfer.FromUserID)
+	if err != nil {
+		log.Printf("Failed to load profile of user %d for transfer: %v", transfer.FromUserID, err)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"transfer": transfer,
+		"event": gin.H{
+			"id":         event.ID,
+			"title":      event.Title,
+			"start_date": event.StartDate,
+			"end_date":   event.EndDate,
+			"location":   event.Location,
+		},
+		"from":     gin.H{"first_name": from.FirstName, "last_name": from.LastName},
+		"form":     event.RegistrationForm,
+		"deadline": event.EditDeadline(),
+	})
+}
+
+// AcceptTransfer takes over the registration offered to the user. The body
+// is like the one of RegisterForEvent. The old holder's ticket stops working
+// and both get an email.
+func (h *RegistrationHandler) AcceptTransfer(c *gin.Context) {
+	var input struct {
+		DietaryRestrictions string             `json:"dietary_restrictions"`
+		Answers             models.FormAnswers `json:"answers"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	transfer, event, ok := h.findTransfer(c)
+	if !ok {
+		return
+	}
+	if !checkTransferable(c, event) {
+		return
+	}
+	// their attendance may have changed since the transfer was offered
+	deprioritized, ok := h.checkNoShowPolicy(c, event, transfer.ToUserID)
+	if !ok {
+		return
+	}
+	answers, err := event.RegistrationForm.ValidateAnswers(input.Answers)
+	var formErrors models.FormErrors
+	if errors.As(err, &formErrors) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid answers", "fields": formErrors})
+		return
+	}
+
+	var registration models.Registration
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		registration, err = transfer.Accept(tx, input.DietaryRestrictions, answers, deprioritized, time.Now())
+		if err != nil {
+			return err
+		}
+		return issueTicket(tx, &registration)
+	})
+	switch {
+	case errors.Is(err, models.ErrTransferClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "This transfer is no longer open"})
+		return
+	case errors.Is(err, models.ErrRecipientRegistered):
+		c.JSON(http.StatusConflict, gin.H{"error": "You are already registered for this event"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// the new holder gets their ticket, the old one hears that it's gone
+	notifyRegistration(h.db, h.cfg, registration)
+	from, err := models.ProfileForUser(h.db, transfer.FromUserID)
+	to, toErr := models.ProfileForUser(h.db, transfer.ToUserID)
+	if err != nil || toErr != nil {
+		log.Printf("Failed to load profiles for transfer %d email: %v", transfer.ID, errors.Join(err, toErr))
+	} else {
+		send := sendTransferDoneEmail
+		go func() {
+			if err := send(from, event, to, h.cfg.FrontendURL); err != nil {
+				log.Printf("Failed to send transfer email to %s: %v", from.Email, err)
+			}
+		}()
+	}
+
+	c.JSON(http.StatusOK, registration)
+}
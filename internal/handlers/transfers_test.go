@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// captureTransferEmails swaps out the transfer email senders, the offers
+// show up on the first channel as accept URLs, the notices that a spot was
+// taken over on the second one as the email of the old holder
+func captureTransferEmails(t *testing.T) (chan string, chan string) {
+	t.Helper()
+	offers, done := make(chan string, 10), make(chan string, 10)
+	originalOffer, originalDone := sendTransferOfferEmail, sendTransferDoneEmail
+	sendTransferOfferEmail = func(profile models.Profile, event models.Event, from models.Profile, acceptURL string, deadline *time.Time) error {
+		offers <- acceptURL
+		return nil
+	}
+	sendTransferDoneEmail = func(profile models.Profile, event models.Event, to models.Profile, frontendURL string) error {
+		done <- profile.Email
+		return nil
+	}
+	t.Cleanup(func() { sendTransferOfferEmail, sendTransferDoneEmail = originalOffer, originalDone })
+	return offers, done
+}
+
+func receive(t *testing.T, ch chan string) string {
+	t.Helper()
+	select {
+	case s := <-ch:
+		return s
+	case <-time.After(time.Second):
+		t.Fatal("no email was sent")
+		return ""
+	}
+}
+
+func TestTransferRegistration(t *testing.T) {
+	db := newTestDB(t)
+	cfg := newTestConfig()
+	sent := captureRegistrationEmails(t, nil)
+	offers, done := captureTransferEmails(t)
+	h := NewRegistrationHandler(db, cfg)
+	event := models.Event{Title: "Hackathon", StartDate: time.Now().Add(72 * time.Hour), EditDeadlineHours: 24,
+		RegistrationForm: models.FormSchema{Fields: []models.FormField{
+			{Name: "level", Label: "Level", Type: models.FormFieldSelect, Options: []string{"beginner", "expert"}, Required: true},
+		}}}
+	require.NoError(t, db.Create(&event).Error)
+	regs := seedBulkRegistrations(t, db, event, models.RegistrationStatusApproved, models.RegistrationStatusWaitlisted)
+	holder, friend := regs[0], regs[1]
+	require.NoError(t, issueTicket(db, &holder))
+	oldTicket := ticketToken(cfg, holder)
+	transfer := fmt.Sprintf("/%d/transfer", holder.ID)
+
+	for email, want := range map[string]int{
+		fmt.Sprintf("registrant%d-0@kthais.com", event.ID): http.StatusBadRequest,
+		"stranger@kthais.com":                              http.StatusNotFound,
+	} {
+		w := doRequest(actingAs(h, holder.UserID), testRequest{method: http.MethodPost, path: transfer, body: gin.H{"email": email}})
+		assert.Equal(t, want, w.Code, email)
+	}
+	w := doRequest(actingAs(h, friend.UserID), testRequest{method: http.MethodPost, path: transfer,
+		body: gin.H{"email": "stranger@kthais.com"}})
+	assert.Equal(t, http.StatusForbidden, w.Code, "only the holder can transfer")
+
+	w = doRequest(actingAs(h, holder.UserID), testRequest{method: http.MethodPost, path: transfer,
+		body: gin.H{"email": fmt.Sprintf("registrant%d-1@kthais.com", event.ID)}})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	acceptURL := receive(t, offers)
+	token := acceptURL[strings.LastIndex(acceptURL, "/")+1:]
+	assert.Equal(t, cfg.FrontendURL+"/transfers/"+token, acceptURL)
+
+	w = doRequest(actingAs(h, holder.UserID), testRequest{method: http.MethodGet, path: "/transfers/" + token})
+	assert.Equal(t, http.StatusNotFound, w.Code, "only the recipient sees the transfer")
+	r := actingAs(h, friend.UserID)
+	w = doRequest(r, testRequest{method: http.MethodGet, path: "/transfers/" + token})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	accept := testRequest{method: http.MethodPost, path: "/transfers/" + token + "/accept"}
+	accept.body = gin.H{"answers": gin.H{}}
+	assert.Equal(t, http.StatusBadRequest, doRequest(r, accept).Code, "the recipient fills in the form")
+	accept.body = gin.H{"dietary_restrictions": "Vegan", "answers": gin.H{"level": "expert"}}
+	w = doRequest(r, accept)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	moved := decodeBody[models.Registration](t, w)
+	assert.Equal(t, holder.ID, moved.ID)
+	assert.Equal(t, friend.UserID, moved.UserID)
+	assert.Equal(t, models.RegistrationStatusApproved, moved.Status)
+	assert.Equal(t, "Vegan", moved.DietaryRestrictions)
+
+	// the waitlisted registration of the friend made way for the transferred one
+	assert.ErrorIs(t, db.First(&models.Registration{}, friend.ID).Error, gorm.ErrRecordNotFound)
+	_, err := verifyTicket(db, cfg, oldTicket)
+	assert.ErrorIs(t, err, errTicketRevoked)
+	assert.Equal(t, models.RegistrationStatusApproved, nextEmail(t, sent).status)
+	assert.Equal(t, fmt.Sprintf("registrant%d-0@kthais.com", event.ID), receive(t, done))
+
+	assert.Equal(t, http.StatusConflict, doRequest(r, accept).Code, "transfers are accepted once")
+}
+
+func TestTransferRestrictions(t *testing.T) {
+	db := newTestDB(t)
+	captureTransferEmails(t)
+	h := NewRegistrationHandler(db, newTestConfig())
+	event := models.Event{Title: "Hackathon", StartDate: time.Now().Add(72 * time.Hour), EditDeadlineHours: 24}
+	require.NoError(t, db.Create(&event).Error)
+	regs := seedBulkRegistrations(t, db, event, models.RegistrationStatusApproved, models.RegistrationStatusPending,
+		models.RegistrationStatusRejected)
+	transfer := func(registration models.Registration) int {
+		t.Helper()
+		return doRequest(actingAs(h, registration.UserID), testRequest{method: http.MethodPost,
+			path: fmt.Sprintf("/%d/transfer", registration.ID),
+			body: gin.H{"email": fmt.Sprintf("registrant%d-2@kthais.com", event.ID)}}).Code
+	}
+
+	assert.Equal(t, http.StatusConflict, transfer(regs[1]), "pending registrations can't be transferred")
+	require.NoError(t, db.Model(&event).Update("transfers_disabled", true).Error)
+	assert.Equal(t, http.StatusForbidden, transfer(regs[0]))
+	require.NoError(t, db.Model(&event).Updates(map[string]any{
+		"transfers_disabled": false, "start_date": time.Now().Add(12 * time.Hour)}).Error)
+	assert.Equal(t, http.StatusConflict, transfer(regs[0]), "past the deadline")
+}
+
+func TestTransfersFollowNoShowPolicy(t *testing.T) {
+	db := newTestDB(t)
+	captureRegistrationEmails(t, nil)
+	offers, _ := captureTransferEmails(t)
+	h := NewRegistrationHandler(db, newTestConfig())
+	flaky := newNoShow(t, db)
+	require.NoError(t, db.Create(&models.Profile{UserID: flaky.UserId, Email: flaky.Email}).Error)
+	event := models.Event{Title: "Hackathon", StartDate: time.Now().Add(72 * time.Hour),
+		NoShowPolicy: models.NoShowPolicy{Action: models.NoShowBlock, MaxRate: 0.5}}
+	require.NoError(t, db.Create(&event).Error)
+	holder := seedBulkRegistrations(t, db, event, models.RegistrationStatusApproved)[0]
+	transfer := testRequest{method: http.MethodPost, path: fmt.Sprintf("/%d/transfer", holder.ID), body: gin.H{"email": flaky.Email}}
+	setPolicy := func(policy models.NoShowPolicy) {
+		t.Helper()
+		require.NoError(t, db.Model(&event).Select("NoShowPolicy").Updates(&models.Event{NoShowPolicy: policy}).Error)
+	}
+
+	w := doRequest(actingAs(h, holder.UserID), transfer)
+	assert.Equal(t, http.StatusForbidden, w.Code, "blocked registrants can't be handed a spot")
+
+	// the policy is checked again when accepting
+	setPolicy(models.NoShowPolicy{})
+	w = doRequest(actingAs(h, holder.UserID), transfer)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	acceptURL := receive(t, offers)
+	accept := testRequest{method: http.MethodPost, path: "/transfers/" + acceptURL[strings.LastIndex(acceptURL, "/")+1:] + "/accept",
+		body: gin.H{}}
+	setPolicy(models.NoShowPolicy{Action: models.NoShowBlock, MaxRate: 0.5})
+	assert.Equal(t, http.StatusForbidden, doRequest(actingAs(h, flaky.ID), accept).Code)
+
+	// deprioritized registrants keep that mark on the spot they take over
+	setPolicy(models.NoShowPolicy{Action: models.NoShowDeprioritize, MaxRate: 0.5})
+	w = doRequest(actingAs(h, flaky.ID), accept)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	moved := decodeBody[models.Registration](t, w)
+	assert.Equal(t, flaky.ID, moved.UserID)
+	assert.True(t, moved.Deprioritized)
+}
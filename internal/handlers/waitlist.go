@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/email"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// sendWaitlistPromotionEmail is swapped out in tests
+var sendWaitlistPromotionEmail = email.SendWaitlistPromotionEmail
+
+// ConfirmOffer lets a user accept a spot they were offered from the waitlist
+func (h *RegistrationHandler) ConfirmOffer(c *gin.Context) {
+	id := c.Param("id")
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var registration models.Registration
+	if err := h.db.First(&registration, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Registration not found"})
+		return
+	}
+
+	if registration.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only confirm your own registrations"})
+		return
+	}
+	if !h.checkUnlocked(c, registration) {
+		return
+	}
+	if registration.Status != models.RegistrationStatusOffered {
+		c.JSON(http.StatusConflict, gin.H{"error": "Registration has no open offer"})
+		return
+	}
+	if registration.OfferExpiresAt != nil && registration.OfferExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusGone, gin.H{"error": "The offer has expired"})
+		return
+	}
+
+	// only confirm if the sweeper didn't pass the offer on in the meantime
+	result := h.db.Model(&models.Registration{}).
+		Where("id = ? AND status = ? AND (offer_expires_at IS NULL OR offer_expires_at >= ?)",
+			registration.ID, models.RegistrationStatusOffered, time.Now()).
+		Updates(map[string]any{"status": models.RegistrationStatusPending, "offer_expires_at": nil})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Registration has no open offer"})
+		return
+	}
+	registration.Status = models.RegistrationStatusPending
+	registration.OfferExpiresAt = nil
+
+	c.JSON(http.StatusOK, registration)
+}
+
+// promoteWaitlist fills the spots freed up at an event and notifies the
+// promoted users
+func (h *RegistrationHandler) promoteWaitlist(eventID uint) error {
+	var promoted []models.Registration
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		event, err := models.LockEvent(tx, eventID)
+		if err != nil {
+			return err
+		}
+		promoted, err = models.PromoteFromWaitlist(tx, event, time.Now())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	notifyPromoted(h.db, h.cfg, promoted)
+	return nil
+}
+
+// StartWaitlistSweeper periodically passes on waitlist offers that were not
+// confirmed in time
+func StartWaitlistSweeper(db *gorm.DB, cfg *config.Config, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			var promoted []models.Registration
+			err := db.Transaction(func(tx *gorm.DB) error {
+				var err error
+				promoted, err = models.ExpireOffers(tx, time.Now())
+				return err
+			})
+			if err != nil {
+				log.Printf("Failed to expire waitlist offers: %v", err)
+				continue
+			}
+			notifyPromoted(db, cfg, promoted)
+		}
+	}()
+}
+
+// notifyPromoted emails the users that got a spot from the waitlist. Emails
+// are sent in the background so a slow mail server doesn't hold up requests.
+func notifyPromoted(db *gorm.DB, cfg *config.Config, promoted []models.Registration) {
+	for _, r := range promoted {
+		var event models.Event
+		if err := db.First(&event, r.EventID).Error; err != nil {
+			log.Printf("Failed to load event %d for waitlist email: %v", r.EventID, err)
+			continue
+		}
+		profile, err := models.ProfileForUser(db, r.UserID)
+		if err != nil {
+			log.Printf("Failed to load profile of user %d for waitlist email: %v", r.UserID, err)
+			continue
+		}
+		eventURL := fmt.Sprintf("%s/events/%d", cfg.FrontendURL, event.ID)
+		send := sendWaitlistPromotionEmail
+		go func(deadline *time.Time) {
+			if err := send(profile, event, eventURL, deadline); err != nil {
+				log.Printf("Failed to send waitlist email to %s: %v", profile.Email, err)
+			}
+		}(r.OfferExpiresAt)
+	}
+}
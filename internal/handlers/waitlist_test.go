@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestConfirmOffer(t *testing.T) {
+	db := newTestDB(t)
+	discardEmails(t)
+	h := NewRegistrationHandler(db, newTestConfig())
+	event := models.Event{Title: "Workshop", StartDate: time.Now().Add(72 * time.Hour), RegistrationMax: 2}
+	require.NoError(t, db.Create(&event).Error)
+	regs := seedBulkRegistrations(t, db, event, models.RegistrationStatusOffered, models.RegistrationStatusOffered)
+	expires := time.Now().Add(time.Hour)
+	require.NoError(t, db.Model(&models.Registration{}).Where("event_id = ?", event.ID).Update("offer_expires_at", expires).Error)
+
+	w := doRequest(actingAs(h, regs[0].UserID), testRequest{method: http.MethodPut, path: fmt.Sprintf("/%d/confirm", regs[0].ID)})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var stored models.Registration
+	require.NoError(t, db.First(&stored, regs[0].ID).Error)
+	assert.Equal(t, models.RegistrationStatusPending, stored.Status)
+	assert.Nil(t, stored.OfferExpiresAt)
+
+	// the sweeper passes the offer on while it is being confirmed
+	require.NoError(t, db.Callback().Update().Before("gorm:update").Register("expire_offer", func(tx *gorm.DB) {
+		tx.Session(&gorm.Session{NewDB: true}).
+			Exec("UPDATE registrations SET status = ? WHERE id = ?", models.RegistrationStatusRejected, regs[1].ID)
+	}))
+	w = doRequest(actingAs(h, regs[1].UserID), testRequest{method: http.MethodPut, path: fmt.Sprintf("/%d/confirm", regs[1].ID)})
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	var passedOn models.Registration
+	require.NoError(t, db.First(&passedOn, regs[1].ID).Error)
+	assert.Equal(t, models.RegistrationStatusRejected, passedOn.Status)
+}
@@ -4,73 +4,155 @@ import (
 	"backend/internal/config"
 	"backend/internal/models"
 	"backend/internal/utils"
-	"log"
 	"net/http"
 	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+const principalKey = "principal"
+
+// Principal is the user behind an authenticated request, as their JWT says
+type Principal struct {
+	ID     uint // of the user's row, only set after RegisteredUserRequired
+	UserID uuid.UUID
+	Email  string
+	Roles  []string
+}
+
+// HasRole reports whether the principal has at least one of the roles
+func (p Principal) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if slices.Contains(p.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrentPrincipal returns the principal stored by the auth middleware
+func CurrentPrincipal(c *gin.Context) (Principal, bool) {
+	p, ok := c.Get(principalKey)
+	if !ok {
+		return Principal{}, false
+	}
+	principal, ok := p.(Principal)
+	return principal, ok
+}
+
+// CurrentUserID returns the ID of the user's row. It is only known for
+// requests that passed RegisteredUserRequired, ok is false otherwise.
+func CurrentUserID(c *gin.Context) (uint, bool) {
+	p, ok := CurrentPrincipal(c)
+	return p.ID, ok && p.ID != 0
+}
+
+// SetPrincipal stores the principal of a request
+func SetPrincipal(c *gin.Context, p Principal) {
+	c.Set(principalKey, p)
+}
+
+// Authenticate verifies the JWT of a request and stores its principal. The
+// token is taken from the Authorization header or else the jwt cookie. It is
+// only verified once per request, later calls return the stored principal.
+func Authenticate(c *gin.Context, cfg *config.Config) (Principal, bool) {
+	if p, ok := CurrentPrincipal(c); ok {
+		return p, true
+	}
+	token := bearerToken(c)
+	if token == "" {
+		token, _ = c.Cookie("jwt")
+	}
+	if token == "" {
+		return Principal{}, false
+	}
+	valid, parsed := utils.ParseAndVerify(token, cfg.JwtSigningKey)
+	if !valid {
+		return Principal{}, false
+	}
+
+	claims := utils.GetClaims(parsed)
+	id, _ := claims["user_id"].(string)
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return Principal{}, false
+	}
+	p := Principal{UserID: userID}
+	p.Email, _ = claims["email"].(string)
+	roles, _ := claims["roles"].(string)
+	for _, role := range strings.Split(roles, ",") {
+		if role != "" {
+			p.Roles = append(p.Roles, role)
+		}
+	}
+	SetPrincipal(c, p)
+	return p, true
+}
+
+func bearerToken(c *gin.Context) string {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// AuthRequiredJWT only lets through requests with a valid JWT
 func AuthRequiredJWT(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		for _, cookie := range c.Request.Cookies() {
-			if cookie.Name == "jwt" {
-				valid, _ := utils.ParseAndVerify(cookie.Value, cfg.JwtSigningKey)
-				if valid {
-					c.Next()
-					return
-				} else {
-					c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-					c.Abort()
-				}
-			}
+		if _, ok := Authenticate(c, cfg); !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			c.Abort()
+			return
 		}
-		// no jwt, not authorized
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		c.Abort()
+		c.Next()
 	}
 }
 
-func RoleRequired(cfg *config.Config, role string) gin.HandlerFunc {
+// RoleRequired only lets through requests with a valid JWT that has at least
+// one of the roles
+func RoleRequired(cfg *config.Config, roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		abort := func() {
+		p, ok := Authenticate(c, cfg)
+		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 			c.Abort()
+			return
 		}
-		for _, cookie := range c.Request.Cookies() {
-			if cookie.Name == "jwt" {
-				valid, token := utils.ParseAndVerify(cookie.Value, cfg.JwtSigningKey)
-				if !valid {
-					log.Printf("JWT Token not Valid!\n")
-					abort()
-				}
-				roles := strings.Split(utils.GetClaims(token)["roles"].(string), ",")
-				if slices.Contains(roles, role) {
-					c.Next()
-					return
-				} else {
-					abort()
-				}
-			}
+		if !p.HasRole(roles...) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			c.Abort()
+			return
 		}
-		abort()
+		c.Next()
 	}
 }
 
+// RegisteredUserRequired only lets through users with a profile. It comes
+// after AuthRequiredJWT and adds the ID of the user's row to the principal.
 func RegisteredUserRequired(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userID, _ := c.Get("user_id")
+		p, ok := CurrentPrincipal(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			c.Abort()
+			return
+		}
 
-		// Check if profile exists
-		var existingProfile models.Profile
-		result := db.Where("user_id = ?", userID).First(&existingProfile)
+		var user models.User
+		result := db.Select("users.id").
+			Joins("JOIN profiles ON profiles.user_id = users.user_id AND profiles.deleted_at IS NULL").
+			Where("users.user_id = ?", p.UserID).First(&user)
 		if result.Error != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "profile not found"})
 			c.Abort()
 			return
 		}
+		p.ID = user.ID
+		SetPrincipal(c, p)
 		c.Next()
 	}
 }
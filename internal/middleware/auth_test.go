@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"backend/internal/config"
+	"backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAuthTestRouter(cfg *config.Config, handlers ...gin.HandlerFunc) *gin.Engine {
+	r := gin.New()
+	handlers = append(handlers, func(c *gin.Context) {
+		p, _ := CurrentPrincipal(c)
+		c.JSON(http.StatusOK, p)
+	})
+	r.GET("/", handlers...)
+	return r
+}
+
+func TestAuthRequiredJWT(t *testing.T) {
+	cfg := &config.Config{JwtSigningKey: "test-signing-key"}
+	r := newAuthTestRouter(cfg, AuthRequiredJWT(cfg))
+	userID := uuid.New()
+	token := utils.WriteJWT("ada@kthais.com", []string{"user", "admin"}, userID, cfg.JwtSigningKey, 15)
+	forged := utils.WriteJWT("ada@kthais.com", []string{"admin"}, userID, "not-our-key", 15)
+
+	tests := []struct {
+		name   string
+		header string
+		cookie string
+		status int
+	}{
+		{"bearer header", "Bearer " + token, "", http.StatusOK},
+		{"lowercase scheme", "bearer " + token, "", http.StatusOK},
+		{"cookie", "", token, http.StatusOK},
+		{"header wins over cookie", "Bearer " + token, forged, http.StatusOK},
+		{"nothing", "", "", http.StatusUnauthorized},
+		{"forged header", "Bearer " + forged, "", http.StatusUnauthorized},
+		{"forged cookie", "", forged, http.StatusUnauthorized},
+		{"other scheme", "Basic " + token, "", http.StatusUnauthorized},
+		{"garbage", "Bearer garbage", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "jwt", Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusOK {
+				assert.JSONEq(t, `{"ID":0,"UserID":"`+userID.String()+`","Email":"ada@kthais.com","Roles":["user","admin"]}`, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRoleRequired(t *testing.T) {
+	cfg := &config.Config{JwtSigningKey: "test-signing-key"}
+	r := newAuthTestRouter(cfg, AuthRequiredJWT(cfg), RoleRequired(cfg, "admin", "organizer"))
+	request := func(roles ...string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+utils.WriteJWT("ada@kthais.com", roles, uuid.New(), cfg.JwtSigningKey, 15))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("user", "organizer"))
+	assert.Equal(t, http.StatusOK, request("admin"))
+	assert.Equal(t, http.StatusForbidden, request("user"))
+	assert.Equal(t, http.StatusForbidden, request())
+}
+
+func TestAuthenticateVerifiesOnce(t *testing.T) {
+	cfg := &config.Config{JwtSigningKey: "test-signing-key"}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+
+	_, ok := Authenticate(c, cfg)
+	assert.False(t, ok)
+
+	// a principal stored earlier in the request is used as is
+	SetPrincipal(c, Principal{ID: 7, Email: "ada@kthais.com"})
+	p, ok := Authenticate(c, cfg)
+	assert.True(t, ok)
+	assert.Equal(t, uint(7), p.ID)
+	id, ok := CurrentUserID(c)
+	assert.True(t, ok)
+	assert.Equal(t, uint(7), id)
+}
+
+func TestCurrentUserIDNeedsRegisteredUser(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	_, ok := CurrentUserID(c)
+	assert.False(t, ok)
+
+	// authenticated, but RegisteredUserRequired didn't look up their row
+	SetPrincipal(c, Principal{UserID: uuid.New(), Email: "ada@kthais.com"})
+	_, ok = CurrentUserID(c)
+	assert.False(t, ok)
+}
@@ -12,6 +12,11 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// Limiter counts requests under a key and tells whether one more is allowed
+type Limiter interface {
+	Allow(ctx context.Context, key string) (bool, error)
+}
+
 type RedisRateLimiter struct {
 	client      *redis.Client
 	maxRequests int
@@ -30,11 +35,16 @@ func NewRedisRateLimiter(cfg *config.Config, maxRequests int, window time.Durati
 		return nil, fmt.Errorf("failed to connect to Redis: %v", err)
 	}
 
+	return NewRateLimiter(client, maxRequests, window), nil
+}
+
+// NewRateLimiter limits requests with a Redis client that is already connected
+func NewRateLimiter(client *redis.Client, maxRequests int, window time.Duration) *RedisRateLimiter {
 	return &RedisRateLimiter{
 		client:      client,
 		maxRequests: maxRequests,
 		window:      window,
-	}, nil
+	}
 }
 
 func (rl *RedisRateLimiter) Allow(ctx context.Context, key string) (bool, error) {
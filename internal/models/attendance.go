@@ -0,0 +1,182 @@
+package models
+
+import (
+	"cmp"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultAttendanceWindow is how far back attendance is looked at unless
+// configured otherwise
+const DefaultAttendanceWindow = 365 * 24 * time.Hour
+
+// AttendanceStats sums up how often a user showed up to events they had a
+// spot at. Only approved registrations to events that are over, weren't
+// cancelled and where attendance was taken count, late cancellations are
+// counted on their own.
+type AttendanceStats struct {
+	UserID            uint    `json:"user_id"`
+	Registered        int     `json:"registered"`
+	Attended          int     `json:"attended"`
+	NoShows           int     `json:"no_shows"`
+	NoShowRate        float64 `json:"no_show_rate"` // 0 if the user hasn't been to any events
+	LateCancellations int     `json:"late_cancellations"`
+}
+
+// attendanceQuery selects the registrations that count towards attendance
+// for events that started at or after since and ended before until. Events
+// where nobody attended or was checked in are taken to not have recorded
+// attendance, their registrants aren't no-shows.
+func attendanceQuery(db *gorm.DB, since, until time.Time) *gorm.DB {
+	return db.Model(&Registration{}).
+		Select(`registrations.user_id,
+			SUM(CASE WHEN registrations.status = @approved AND taken.event_id IS NOT NULL THEN 1 ELSE 0 END) AS registered,
+			SUM(CASE WHEN registrations.status = @approved AND taken.event_id IS NOT NULL AND registrations.attended THEN 1 ELSE 0 END) AS attended,
+			SUM(CASE WHEN registrations.status <> @approved AND registrations.cancelled_late THEN 1 ELSE 0 END) AS late_cancellations`,
+			sql.Named("approved", RegistrationStatusApproved)).
+		Joins("JOIN events ON events.id = registrations.event_id AND events.deleted_at IS NULL").
+		Joins(`LEFT JOIN (SELECT DISTINCT event_id FROM registrations
+			WHERE deleted_at IS NULL AND (attended OR checked_in_at IS NOT NULL)) AS taken ON taken.event_id = events.id`).
+		Where("((registrations.status = ? AND taken.event_id IS NOT NULL) OR registrations.cancelled_late) AND events.status <> ?",
+			RegistrationStatusApproved, EventStatusCancelled).
+		Where("events.start_date >= ? AND events.end_date < ?", since, until).
+		Group("registrations.user_id")
+}
+
+func (s *AttendanceStats) computeRate() {
+	s.NoShows = s.Registered - s.Attended
+	if s.Registered > 0 {
+		s.NoShowRate = float64(s.NoShows) / float64(s.Registered)
+	}
+}
+
+// UserAttendance returns the attendance of a user at events that took place
+// in [since, until)
+func UserAttendance(db *gorm.DB, userID uint, since, until time.Time) (AttendanceStats, error) {
+	stats := AttendanceStats{UserID: userID}
+	var rows []AttendanceStats
+	if err := attendanceQuery(db, since, until).Where("registrations.user_id = ?", userID).
+		Scan(&rows).Error; err != nil {
+		return stats, err
+	}
+	if len(rows) > 0 {
+		stats = rows[0]
+	}
+	stats.computeRate()
+	return stats, nil
+}
+
+// AttendanceOfUsers returns the attendance of everyone who had a spot at an
+// event that took place in [since, until), worst no-show rate first
+func AttendanceOfUsers(db *gorm.DB, since, until time.Time) ([]AttendanceStats, error) {
+	var stats []AttendanceStats
+	if err := attendanceQuery(db, since, until).Scan(&stats).Error; err != nil {
+		return nil, err
+	}
+	for i := range stats {
+		stats[i].computeRate()
+	}
+	slices.SortStableFunc(stats, func(a, b AttendanceStats) int {
+		return cmp.Or(cmp.Compare(b.NoShowRate, a.NoShowRate), cmp.Compare(b.NoShows, a.NoShows), cmp.Compare(a.UserID, b.UserID))
+	})
+	return stats, nil
+}
+
+type NoShowAction string
+
+const (
+	NoShowDeprioritize NoShowAction = "deprioritize" // registrants go behind everyone else on the waitlist and in the lottery
+	NoShowBlock        NoShowAction = "block"        // registrants are turned away
+)
+
+// NoShowPolicy applies to registrants who missed more than MaxRate of the
+// events they had a spot at during the window. The policy is off without an
+// action.
+type NoShowPolicy struct {
+	Action                 NoShowAction `json:"action,omitempty"`
+	MaxRate                float64      `json:"max_rate,omitempty"`                 // highest no-show rate that is let through, between 0 and 1
+	MinEvents              int          `json:"min_events,omitempty"`               // registrants with fewer past events are let through
+	WindowDays             int          `json:"window_days,omitempty"`              // 0 = DefaultAttendanceWindow
+	CountLateCancellations bool         `json:"count_late_cancellations,omitempty"` // late cancellations count as missed events
+}
+
+func (p NoShowPolicy) Validate() error {
+	switch p.Action {
+	case "":
+		return nil
+	case NoShowDeprioritize, NoShowBlock:
+	default:
+		return fmt.Errorf("no_show_policy.action must be deprioritize or block")
+	}
+	if p.MaxRate < 0 || p.MaxRate >= 1 {
+		return fmt.Errorf("no_show_policy.max_rate must be at least 0 and below 1")
+	}
+	if p.MinEvents < 0 || p.WindowDays < 0 {
+		return fmt.Errorf("no_show_policy.min_events and window_days can't be negative")
+	}
+	return nil
+}
+
+// Window returns how far back the policy looks
+func (p NoShowPolicy) Window() time.Duration {
+	if p.WindowDays == 0 {
+		return DefaultAttendanceWindow
+	}
+	return time.Duration(p.WindowDays) * 24 * time.Hour
+}
+
+// Missed returns how many of the events in the stats the policy counts as
+// missed, out of how many
+func (p NoShowPolicy) Missed(stats AttendanceStats) (int, int) {
+	if p.CountLateCancellations {
+		return stats.NoShows + stats.LateCancellations, stats.Registered + stats.LateCancellations
+	}
+	return stats.NoShows, stats.Registered
+}
+
+// Applies reports whether the policy applies to a registrant with the stats
+func (p NoShowPolicy) Applies(stats AttendanceStats) bool {
+	missed, total := p.Missed(stats)
+	return p.Action != "" && total > 0 && total >= p.MinEvents && float64(missed)/float64(total) > p.MaxRate
+}
+
+// PolicyOverride exempts a user from the no-show policy of an event, or of
+// all events if EventID is nil
+type PolicyOverride struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	UserID    uint           `gorm:"not null;index" json:"user_id"`
+	EventID   *uint          `gorm:"index" json:"event_id,omitempty"`
+	Reason    string         `json:"reason"`
+	CreatedBy uint           `json:"created_by"`
+	CreatedAt time.Time      `json:"created_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// HasPolicyOverride reports whether a user is exempt from the no-show policy
+// of an event
+func HasPolicyOverride(db *gorm.DB, userID, eventID uint) (bool, error) {
+	var count int64
+	err := db.Model(&PolicyOverride{}).
+		Where("user_id = ? AND (event_id IS NULL OR event_id = ?)", userID, eventID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CheckNoShowPolicy returns the attendance of a registrant and whether the
+// no-show policy of the event applies to them
+func CheckNoShowPolicy(db *gorm.DB, event Event, userID uint, now time.Time) (AttendanceStats, bool, error) {
+	policy := event.NoShowPolicy
+	if policy.Action == "" {
+		return AttendanceStats{UserID: userID}, false, nil
+	}
+	stats, err := UserAttendance(db, userID, now.Add(-policy.Window()), now)
+	if err != nil || !policy.Applies(stats) {
+		return stats, false, err
+	}
+	exempt, err := HasPolicyOverride(db, userID, event.ID)
+	return stats, !exempt, err
+}
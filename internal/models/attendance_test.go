@@ -0,0 +1,163 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// punctualUser attends every event seeded by seedAttendance, so that they
+// all have attendance taken
+const punctualUser = 100
+
+// seedAttendance gives user 1 a spot at an event for every entry, attended or
+// not, one week apart going back from now
+func seedAttendance(t *testing.T, db *gorm.DB, now time.Time, attended ...bool) {
+	t.Helper()
+	for i, a := range attended {
+		start := now.Add(-time.Duration(i+1) * 7 * 24 * time.Hour)
+		event := Event{Title: "Lecture", StartDate: start, EndDate: start.Add(2 * time.Hour)}
+		require.NoError(t, db.Create(&event).Error)
+		require.NoError(t, db.Create(&Registration{EventID: event.ID, UserID: 1, Status: RegistrationStatusApproved, Attended: a}).Error)
+		require.NoError(t, db.Create(&Registration{EventID: event.ID, UserID: punctualUser, Status: RegistrationStatusApproved, Attended: true}).Error)
+	}
+}
+
+func TestUserAttendance(t *testing.T) {
+	db := newWaitlistDB(t)
+	now := time.Now()
+	seedAttendance(t, db, now, true, false, false, true)
+
+	// none of these count: a waitlisted spot, a cancelled event, an event
+	// that hasn't happened yet, one that hasn't ended and one where nobody
+	// took attendance
+	yesterday := now.Add(-24 * time.Hour)
+	past := Event{Title: "Full", StartDate: yesterday, EndDate: yesterday.Add(time.Hour)}
+	cancelled := Event{Title: "Cancelled", StartDate: yesterday, EndDate: yesterday.Add(time.Hour), Status: EventStatusCancelled}
+	future := Event{Title: "Upcoming", StartDate: now.Add(24 * time.Hour), EndDate: now.Add(25 * time.Hour)}
+	ongoing := Event{Title: "Hackathon", StartDate: yesterday, EndDate: now.Add(24 * time.Hour)}
+	untracked := Event{Title: "Meetup", StartDate: yesterday, EndDate: yesterday.Add(time.Hour)}
+	for _, e := range []*Event{&past, &cancelled, &future, &ongoing, &untracked} {
+		require.NoError(t, db.Create(e).Error)
+	}
+	require.NoError(t, db.Create(&Registration{EventID: past.ID, UserID: 1, Status: RegistrationStatusWaitlisted}).Error)
+	for _, e := range []Event{cancelled, future, ongoing, untracked} {
+		require.NoError(t, db.Create(&Registration{EventID: e.ID, UserID: 1, Status: RegistrationStatusApproved}).Error)
+	}
+	for _, e := range []Event{cancelled, future, ongoing} {
+		require.NoError(t, db.Create(&Registration{EventID: e.ID, UserID: punctualUser, Status: RegistrationStatusApproved, Attended: true}).Error)
+	}
+
+	stats, err := UserAttendance(db, 1, now.Add(-DefaultAttendanceWindow), now)
+	require.NoError(t, err)
+	assert.Equal(t, AttendanceStats{UserID: 1, Registered: 4, Attended: 2, NoShows: 2, NoShowRate: 0.5}, stats)
+
+	// the window only reaches back over the last two events
+	stats, err = UserAttendance(db, 1, now.Add(-15*24*time.Hour), now)
+	require.NoError(t, err)
+	assert.Equal(t, AttendanceStats{UserID: 1, Registered: 2, Attended: 1, NoShows: 1, NoShowRate: 0.5}, stats)
+
+	stats, err = UserAttendance(db, 2, now.Add(-DefaultAttendanceWindow), now)
+	require.NoError(t, err)
+	assert.Equal(t, AttendanceStats{UserID: 2}, stats)
+
+	all, err := AttendanceOfUsers(db, now.Add(-DefaultAttendanceWindow), now)
+	require.NoError(t, err)
+	assert.Equal(t, []AttendanceStats{
+		{UserID: 1, Registered: 4, Attended: 2, NoShows: 2, NoShowRate: 0.5},
+		{UserID: punctualUser, Registered: 4, Attended: 4},
+	}, all)
+}
+
+func TestNoShowPolicy(t *testing.T) {
+	db := newWaitlistDB(t)
+	require.NoError(t, db.AutoMigrate(&PolicyOverride{}))
+	now := time.Now()
+	seedAttendance(t, db, now, false, false, true)
+
+	event := Event{Title: "Hackathon", NoShowPolicy: NoShowPolicy{Action: NoShowBlock, MaxRate: 0.5, MinEvents: 3}}
+	require.NoError(t, db.Create(&event).Error)
+	stats, applies, err := CheckNoShowPolicy(db, event, 1, now)
+	require.NoError(t, err)
+	assert.True(t, applies)
+	assert.Equal(t, 2, stats.NoShows)
+
+	// too few events to judge
+	lenient := event
+	lenient.NoShowPolicy.MinEvents = 4
+	_, applies, err = CheckNoShowPolicy(db, lenient, 1, now)
+	require.NoError(t, err)
+	assert.False(t, applies)
+
+	// an override for another event doesn't help, one for all events does
+	other := uint(999)
+	require.NoError(t, db.Create(&PolicyOverride{UserID: 1, EventID: &other}).Error)
+	_, applies, err = CheckNoShowPolicy(db, event, 1, now)
+	require.NoError(t, err)
+	assert.True(t, applies)
+	require.NoError(t, db.Create(&PolicyOverride{UserID: 1, Reason: "was ill"}).Error)
+	_, applies, err = CheckNoShowPolicy(db, event, 1, now)
+	require.NoError(t, err)
+	assert.False(t, applies)
+}
+
+func TestNoShowPolicyValidate(t *testing.T) {
+	assert.NoError(t, NoShowPolicy{}.Validate())
+	assert.NoError(t, NoShowPolicy{Action: NoShowDeprioritize, MaxRate: 0.3, MinEvents: 3, WindowDays: 180}.Validate())
+	for name, policy := range map[string]NoShowPolicy{
+		"unknown action":  {Action: "shame"},
+		"rate too high":   {Action: NoShowBlock, MaxRate: 1},
+		"negative rate":   {Action: NoShowBlock, MaxRate: -0.1},
+		"negative window": {Action: NoShowBlock, WindowDays: -1},
+	} {
+		assert.Error(t, policy.Validate(), name)
+	}
+}
+
+func TestDeprioritizedGoLast(t *testing.T) {
+	db := newWaitlistDB(t)
+	event := Event{Title: "Workshop", RegistrationMax: 1}
+	require.NoError(t, db.Create(&event).Error)
+	regs := seedRegistrations(t, db, event, RegistrationStatusApproved, RegistrationStatusWaitlisted, RegistrationStatusWaitlisted)
+	require.NoError(t, db.Model(&regs[1]).Update("deprioritized", true).Error)
+
+	position, err := WaitlistPosition(db, statusOf(t, db, regs[1].ID))
+	require.NoError(t, err)
+	assert.Equal(t, 2, position)
+	position, err = WaitlistPosition(db, statusOf(t, db, regs[2].ID))
+	require.NoError(t, err)
+	assert.Equal(t, 1, position)
+
+	require.NoError(t, db.Model(&regs[0]).Update("status", RegistrationStatusRejected).Error)
+	promoted, err := PromoteFromWaitlist(db, event, time.Now())
+	require.NoError(t, err)
+	require.Len(t, promoted, 1)
+	assert.Equal(t, regs[2].ID, promoted[0].ID)
+
+	// in a lottery they are only drawn once everyone else is
+	candidates := lotteryCandidates(20, func(i int, c *LotteryCandidate) { c.Deprioritized = i < 10 })
+	for seed := range int64(10) {
+		selected, rest := LotteryRules{}.Draw(candidates, 10, seed)
+		for _, c := range selected {
+			assert.False(t, c.Deprioritized)
+		}
+		for _, c := range rest {
+			assert.True(t, c.Deprioritized)
+		}
+	}
+}
+
+func TestLateCancellationsCountAsMissed(t *testing.T) {
+	stats := AttendanceStats{Registered: 2, Attended: 2, LateCancellations: 2}
+	policy := NoShowPolicy{Action: NoShowBlock, MaxRate: 0.3}
+	assert.False(t, policy.Applies(stats))
+
+	policy.CountLateCancellations = true
+	missed, total := policy.Missed(stats)
+	assert.Equal(t, 2, missed)
+	assert.Equal(t, 4, total)
+	assert.True(t, policy.Applies(stats))
+}
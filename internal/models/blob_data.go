@@ -20,13 +20,27 @@ type BlobData struct {
 }
 
 // Get the bd from the database, then call this to get a bytearray that represents the data.
-func (bd BlobData) GetData(r2 utils.R2Client) ([]byte, error) {
+func (bd BlobData) GetData(r2 utils.BlobStore) ([]byte, error) {
 	///fetch bd.uri from bucket
 	return r2.GetObject(bd.BlobId.String())
 }
 
 // input name, type, and association id (who it belongs to), actual data as a byte array, the db client and the r2 client
-func NewBlobData(name string, ftype string, association_id uuid.UUID, blob []byte, db *gorm.DB, r2 utils.R2Client) (BlobData, error) {
+func NewBlobData(name string, ftype string, association_id uuid.UUID, blob []byte, db *gorm.DB, r2 utils.BlobStore) (BlobData, error) {
+	bd, err := UploadBlob(name, ftype, association_id, blob, r2)
+	if err != nil {
+		return bd, err
+	}
+	// push to postgresql
+	if err := db.Create(&bd).Error; err != nil {
+		return bd, err
+	}
+	return bd, nil
+}
+
+// UploadBlob pushes the data to R2 without saving the bd, for callers that
+// save it within a transaction of their own
+func UploadBlob(name string, ftype string, association_id uuid.UUID, blob []byte, r2 utils.BlobStore) (BlobData, error) {
 	// create struct
 	id, _ := uuid.NewUUID()
 	bd := BlobData{
@@ -36,13 +50,13 @@ func NewBlobData(name string, ftype string, association_id uuid.UUID, blob []byt
 		FType:         ftype,
 	}
 	// push data to R2
-	err := r2.PutObject(id.String(), blob)
-	if err != nil {
-		return bd, err
-	}
-	// push to postgresql
-	if err := db.Create(&bd).Error; err != nil {
-		return bd, err
+	return bd, r2.PutObject(id.String(), blob)
+}
+
+// removes the data from R2 and the bd from the database
+func (bd BlobData) Delete(db *gorm.DB, r2 utils.BlobStore) error {
+	if err := r2.DeleteObject(bd.BlobId.String()); err != nil {
+		return err
 	}
-	return bd, nil
+	return db.Unscoped().Delete(&bd).Error
 }
@@ -1,8 +1,10 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -29,20 +31,121 @@ const (
 )
 
 type Event struct {
-	ID                 uint               `gorm:"primarykey" json:"id"`
-	Title              string             `gorm:"not null" json:"title"`
-	Description        string             `json:"description"`
-	RegistrationMethod RegistrationMethod `json:"registration_method"`
-	ICSFileEndpoint    string             `json:"ics_file_endpoint"`
-	Location           string             `json:"location"`
-	Image              string             `json:"image"`
-	RegistrationMax    int                `json:"registration_max"`
-	TypeOfEvent        EventType          `json:"type_of_event"`
-	StartDate          time.Time          `json:"start_date"`
-	EndDate            time.Time          `json:"end_date"`
-	CreatedBy          uint               `json:"created_by"`
-	User               User               `gorm:"foreignKey:CreatedBy" json:"user"`
-	CreatedAt          time.Time          `json:"created_at"`
-	UpdatedAt          time.Time          `json:"updated_at"`
-	DeletedAt          gorm.DeletedAt     `gorm:"index" json:"-"`
+	ID                  uint               `gorm:"primarykey" json:"id"`
+	UUID                uuid.UUID          `gorm:"type:uuid;uniqueIndex" json:"uuid"` // blobs of the event are associated with this
+	Title               string             `gorm:"not null" json:"title"`
+	Description         string             `json:"description"`
+	RegistrationMethod  RegistrationMethod `json:"registration_method"`
+	ICSFileEndpoint     string             `json:"ics_file_endpoint"`
+	Location            string             `json:"location"`
+	Image               string             `json:"image"` // public URL of the uploaded image
+	ImageBlobID         *uuid.UUID         `json:"image_blob_id,omitempty"`
+	RegistrationMax     int                `json:"registration_max"`
+	OfferWindowHours    int                `json:"offer_window_hours"` // time to confirm a spot offered from the waitlist, 0 = no confirmation
+	RegistrationForm    FormSchema         `gorm:"serializer:json" json:"registration_form"`
+	EditDeadlineHours   int                `json:"edit_deadline_hours,omitempty"`   // registrants can change their registration until this many hours before the start
+	CancelDeadlineHours int                `json:"cancel_deadline_hours,omitempty"` // cancelling later than this many hours before the start is a late cancellation
+	TransfersDisabled   bool               `json:"transfers_disabled,omitempty"`    // registrants can't hand their spot over to another member
+	SelectionMode       SelectionMode      `gorm:"not null;default:'first_come'" json:"selection_mode"`
+	ApplicationsClose   *time.Time         `json:"applications_close,omitempty"` // lottery mode: registrations stay pending until then, then spots are drawn
+	LotteryRules        LotteryRules       `gorm:"serializer:json" json:"lottery_rules"`
+	TeamMinSize         int                `json:"team_min_size,omitempty"`         // smallest team that can be approved
+	TeamMaxSize         int                `json:"team_max_size,omitempty"`         // 0 = registrants can't form teams
+	CapacityCountsTeams bool               `json:"capacity_counts_teams,omitempty"` // a team takes up one spot of RegistrationMax, however many members it has
+	NoShowPolicy        NoShowPolicy       `gorm:"serializer:json" json:"no_show_policy"`
+	SurveyForm          FormSchema         `gorm:"serializer:json" json:"survey_form"` // questions asked in the feedback survey besides rating and comments
+	TypeOfEvent         EventType          `json:"type_of_event"`
+	Status              EventStatus        `gorm:"not null;default:'published';index" json:"status"` // events from before statuses existed are published
+	StartDate           time.Time          `json:"start_date"`
+	EndDate             time.Time          `json:"end_date"`
+	CreatedBy           uint               `json:"created_by"`
+	User                User               `gorm:"foreignKey:CreatedBy" json:"user"`
+	Organizers          []User             `gorm:"many2many:event_organizers" json:"organizers,omitempty"` // co-organizers, can manage the event like its creator
+	SeriesID            *uint              `gorm:"index" json:"series_id,omitempty"`                       // set for occurrences of a recurring series
+	OccurrenceDate      *time.Time         `json:"occurrence_date,omitempty"`                              // original start in the series, the RECURRENCE-ID
+	Detached            bool               `json:"detached,omitempty"`                                     // edited on its own, series edits skip it
+	CreatedAt           time.Time          `json:"created_at"`
+	UpdatedAt           time.Time          `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt     `gorm:"index" json:"-"`
+}
+
+// BeforeSave also covers events created before the UUID column existed
+func (e *Event) BeforeSave(tx *gorm.DB) error {
+	if e.UUID == uuid.Nil {
+		e.UUID = uuid.New()
+	}
+	return nil
+}
+
+// ValidateRegistration checks the registration and survey settings of an event
+func (e *Event) ValidateRegistration() error {
+	if err := e.RegistrationForm.Validate(); err != nil {
+		return err
+	}
+	if err := e.SurveyForm.Validate(); err != nil {
+		return fmt.Errorf("survey_form: %w", err)
+	}
+	if err := e.validateTeams(); err != nil {
+		return err
+	}
+	if e.EditDeadlineHours < 0 || e.CancelDeadlineHours < 0 {
+		return fmt.Errorf("edit_deadline_hours and cancel_deadline_hours can't be negative")
+	}
+	if err := e.NoShowPolicy.Validate(); err != nil {
+		return err
+	}
+	switch e.SelectionMode {
+	case "", SelectionFirstCome:
+		return nil
+	case SelectionLottery:
+		if e.ApplicationsClose == nil {
+			return fmt.Errorf("applications_close is required for lottery events")
+		}
+		return e.LotteryRules.Validate(e.RegistrationMax)
+	default:
+		return fmt.Errorf("selection_mode must be first_come or lottery")
+	}
+}
+
+func (e *Event) validateTeams() error {
+	if e.TeamMinSize < 0 || e.TeamMaxSize < 0 {
+		return fmt.Errorf("team sizes can't be negative")
+	}
+	if e.TeamMaxSize == 0 && (e.TeamMinSize > 0 || e.CapacityCountsTeams) {
+		return fmt.Errorf("team_max_size is required for events with teams")
+	}
+	if e.TeamMinSize > e.TeamMaxSize {
+		return fmt.Errorf("team_min_size is larger than team_max_size")
+	}
+	if e.CapacityCountsTeams && e.SelectionMode == SelectionLottery {
+		return fmt.Errorf("lottery events can't count capacity in teams")
+	}
+	return nil
+}
+
+// EditDeadline returns when registrants can no longer change their registration
+func (e *Event) EditDeadline() time.Time {
+	return e.StartDate.Add(-time.Duration(e.EditDeadlineHours) * time.Hour)
+}
+
+// CancelDeadline returns when cancellations start to count as late
+func (e *Event) CancelDeadline() time.Time {
+	return e.StartDate.Add(-time.Duration(e.CancelDeadlineHours) * time.Hour)
+}
+
+// HasTeams reports whether registrants can form teams
+func (e *Event) HasTeams() bool {
+	return e.TeamMaxSize > 0
+}
+
+// IsOrganizer reports whether a user created the event or was added as a co-organizer
+func (e *Event) IsOrganizer(db *gorm.DB, userID uint) (bool, error) {
+	if e.CreatedBy == userID {
+		return true, nil
+	}
+	var count int64
+	err := db.Table("event_organizers").
+		Where("event_id = ? AND user_id = ?", e.ID, userID).
+		Count(&count).Error
+	return count > 0, err
 }
@@ -0,0 +1,216 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // the production image has no zoneinfo
+
+	"gorm.io/gorm"
+)
+
+// SeriesTimeZone is the time zone recurring events are expanded in, so that a
+// weekly event at 17:00 stays at 17:00 across daylight saving changes.
+const SeriesTimeZone = "Europe/Stockholm"
+
+// MaxSeriesOccurrences limits how many events a single series can expand into
+const MaxSeriesOccurrences = 104
+
+type RecurrenceFrequency string
+
+const (
+	RecurrenceWeekly   RecurrenceFrequency = "weekly"
+	RecurrenceBiweekly RecurrenceFrequency = "biweekly"
+)
+
+// RecurrenceRule is the subset of RFC 5545 RRULE we support: a weekly or
+// biweekly rule ending either at a date or after a number of occurrences.
+// Exclusions remove single occurrences (matched by date) and map to EXDATE.
+type RecurrenceRule struct {
+	Frequency  RecurrenceFrequency `json:"frequency"`
+	Until      *time.Time          `json:"until,omitempty"`
+	Count      int                 `json:"count,omitempty"`
+	Exclusions []time.Time         `json:"exclusions,omitempty"`
+}
+
+type EventSeries struct {
+	ID                 uint               `gorm:"primarykey" json:"id"`
+	Title              string             `gorm:"not null" json:"title"`
+	Description        string             `json:"description"`
+	RegistrationMethod RegistrationMethod `json:"registration_method"`
+	Location           string             `json:"location"`
+	RegistrationMax    int                `json:"registration_max"`
+	TypeOfEvent        EventType          `json:"type_of_event"`
+	StartDate          time.Time          `json:"start_date"` // start and end of the first occurrence
+	EndDate            time.Time          `json:"end_date"`
+	Recurrence         RecurrenceRule     `gorm:"serializer:json" json:"recurrence"`
+	CreatedBy          uint               `json:"created_by"`
+	Events             []Event            `gorm:"foreignKey:SeriesID" json:"events,omitempty"`
+	CreatedAt          time.Time          `json:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt     `gorm:"index" json:"-"`
+}
+
+func seriesLocation() *time.Location {
+	loc, err := time.LoadLocation(SeriesTimeZone)
+	if err != nil {
+		// tzdata is embedded, so this can't really happen
+		panic(fmt.Sprintf("failed to load %s: %v", SeriesTimeZone, err))
+	}
+	return loc
+}
+
+// OccurrenceKey identifies an occurrence by its date in the series time zone.
+// Occurrences keep their key when the time of day of the series changes.
+func OccurrenceKey(t time.Time) string {
+	return t.In(seriesLocation()).Format(time.DateOnly)
+}
+
+// OccurrenceDayShift returns the number of days between the dates of two
+// occurrences in the series time zone
+func OccurrenceDayShift(from, to time.Time) int {
+	f, _ := time.Parse(time.DateOnly, OccurrenceKey(from))
+	t, _ := time.Parse(time.DateOnly, OccurrenceKey(to))
+	return int(t.Sub(f).Hours() / 24)
+}
+
+func (r RecurrenceRule) interval() int {
+	if r.Frequency == RecurrenceBiweekly {
+		return 14
+	}
+	return 7
+}
+
+func (r RecurrenceRule) Validate() error {
+	if r.Frequency != RecurrenceWeekly && r.Frequency != RecurrenceBiweekly {
+		return fmt.Errorf("frequency must be weekly or biweekly")
+	}
+	if (r.Until == nil) == (r.Count == 0) {
+		return fmt.Errorf("exactly one of until and count must be set")
+	}
+	if r.Count < 0 || r.Count > MaxSeriesOccurrences {
+		return fmt.Errorf("count must be between 1 and %d", MaxSeriesOccurrences)
+	}
+	return nil
+}
+
+// Dates returns the start times generated by the rule, before exclusions are
+// applied. This is the set RFC 5545 COUNT refers to.
+func (r RecurrenceRule) Dates(start time.Time) []time.Time {
+	local := start.In(seriesLocation())
+	var dates []time.Time
+	for i := 0; i < MaxSeriesOccurrences; i++ {
+		// AddDate keeps the wall clock time in the series time zone
+		t := local.AddDate(0, 0, i*r.interval())
+		if r.Count > 0 && i >= r.Count {
+			break
+		}
+		if r.Until != nil && t.After(*r.Until) {
+			break
+		}
+		dates = append(dates, t)
+	}
+	return dates
+}
+
+// Excluded returns the generated dates that are removed by an exclusion
+func (r RecurrenceRule) Excluded(start time.Time) []time.Time {
+	var excluded []time.Time
+	for _, t := range r.Dates(start) {
+		if r.IsExcluded(t) {
+			excluded = append(excluded, t)
+		}
+	}
+	return excluded
+}
+
+func (r RecurrenceRule) IsExcluded(t time.Time) bool {
+	key := OccurrenceKey(t)
+	for _, ex := range r.Exclusions {
+		if OccurrenceKey(ex) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Shift moves the exclusions of the rule by a number of days, for when the
+// whole series is moved to another weekday
+func (r *RecurrenceRule) Shift(days int) {
+	for i, ex := range r.Exclusions {
+		r.Exclusions[i] = ex.AddDate(0, 0, days)
+	}
+}
+
+// Occurrences returns the start times of all occurrences
+func (r RecurrenceRule) Occurrences(start time.Time) []time.Time {
+	var occurrences []time.Time
+	for _, t := range r.Dates(start) {
+		if !r.IsExcluded(t) {
+			occurrences = append(occurrences, t)
+		}
+	}
+	return occurrences
+}
+
+// RRule formats the rule as an RFC 5545 RRULE value
+func (r RecurrenceRule) RRule() string {
+	parts := []string{"FREQ=WEEKLY"}
+	if r.Frequency == RecurrenceBiweekly {
+		parts = append(parts, "INTERVAL=2")
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	} else {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences expands the series into events. The events are not saved.
+func (s *EventSeries) Occurrences() []Event {
+	duration := s.EndDate.Sub(s.StartDate)
+	var events []Event
+	for _, start := range s.Recurrence.Occurrences(s.StartDate) {
+		event := Event{
+			OccurrenceDate: &start,
+			StartDate:      start,
+			EndDate:        start.Add(duration),
+		}
+		s.ApplyTo(&event)
+		events = append(events, event)
+	}
+	return events
+}
+
+// ApplyTo copies the shared fields of the series to one of its occurrences.
+// Images aren't shared, they are uploaded for each occurrence.
+func (s *EventSeries) ApplyTo(e *Event) {
+	seriesID := s.ID
+	e.SeriesID = &seriesID
+	e.Title = s.Title
+	e.Description = s.Description
+	e.RegistrationMethod = s.RegistrationMethod
+	e.Location = s.Location
+	e.RegistrationMax = s.RegistrationMax
+	e.TypeOfEvent = s.TypeOfEvent
+	e.CreatedBy = s.CreatedBy
+}
+
+// IsOrganizer reports whether a user created the series or organizes every
+// one of its occurrences. Changes to the series reach all of them, so
+// organizing some isn't enough.
+func (s *EventSeries) IsOrganizer(db *gorm.DB, userID uint) (bool, error) {
+	if s.CreatedBy == userID {
+		return true, nil
+	}
+	var total, organized int64
+	if err := db.Model(&Event{}).Where("series_id = ?", s.ID).Count(&total).Error; err != nil {
+		return false, err
+	}
+	err := db.Model(&Event{}).
+		Where("series_id = ? AND (created_by = ? OR id IN (?))", s.ID, userID,
+			db.Table("event_organizers").Select("event_id").Where("user_id = ?", userID)).
+		Count(&organized).Error
+	return total > 0 && organized == total, err
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stockholm(t *testing.T) *time.Location {
+	loc, err := time.LoadLocation(SeriesTimeZone)
+	if err != nil {
+		t.Fatalf("failed to load time zone: %v", err)
+	}
+	return loc
+}
+
+func TestRecurrenceKeepsLocalTimeAcrossDST(t *testing.T) {
+	loc := stockholm(t)
+	// summer time ends on the last Sunday of October
+	start := time.Date(2025, 10, 14, 17, 0, 0, 0, loc)
+	rule := RecurrenceRule{Frequency: RecurrenceWeekly, Count: 3}
+
+	dates := rule.Occurrences(start)
+	assert.Len(t, dates, 3)
+	for _, d := range dates {
+		assert.Equal(t, 17, d.In(loc).Hour())
+	}
+	assert.Equal(t, 15*time.Hour, dates[0].UTC().Sub(dates[0].UTC().Truncate(24*time.Hour)))
+	assert.Equal(t, 16*time.Hour, dates[2].UTC().Sub(dates[2].UTC().Truncate(24*time.Hour)))
+}
+
+func TestRecurrenceRule(t *testing.T) {
+	loc := stockholm(t)
+	start := time.Date(2025, 9, 2, 18, 0, 0, 0, loc)
+	until := time.Date(2025, 10, 1, 0, 0, 0, 0, loc)
+
+	tests := []struct {
+		name  string
+		rule  RecurrenceRule
+		want  []string
+		rrule string
+	}{
+		{
+			name:  "weekly with count",
+			rule:  RecurrenceRule{Frequency: RecurrenceWeekly, Count: 3},
+			want:  []string{"2025-09-02", "2025-09-09", "2025-09-16"},
+			rrule: "FREQ=WEEKLY;COUNT=3",
+		},
+		{
+			name:  "biweekly until",
+			rule:  RecurrenceRule{Frequency: RecurrenceBiweekly, Until: &until},
+			want:  []string{"2025-09-02", "2025-09-16", "2025-09-30"},
+			rrule: "FREQ=WEEKLY;INTERVAL=2;UNTIL=20250930T220000Z",
+		},
+		{
+			name: "exclusions are part of the count",
+			rule: RecurrenceRule{Frequency: RecurrenceWeekly, Count: 4, Exclusions: []time.Time{
+				time.Date(2025, 9, 9, 0, 0, 0, 0, loc),
+			}},
+			want:  []string{"2025-09-02", "2025-09-16", "2025-09-23"},
+			rrule: "FREQ=WEEKLY;COUNT=4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, tt.rule.Validate())
+			var got []string
+			for _, d := range tt.rule.Occurrences(start) {
+				got = append(got, OccurrenceKey(d))
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.rrule, tt.rule.RRule())
+		})
+	}
+}
+
+func TestRecurrenceRuleValidate(t *testing.T) {
+	until := time.Now()
+	invalid := []RecurrenceRule{
+		{Frequency: "daily", Count: 2},
+		{Frequency: RecurrenceWeekly},
+		{Frequency: RecurrenceWeekly, Count: 2, Until: &until},
+		{Frequency: RecurrenceWeekly, Count: MaxSeriesOccurrences + 1},
+	}
+	for _, rule := range invalid {
+		assert.Error(t, rule.Validate(), "%+v", rule)
+	}
+}
+
+func TestSeriesOccurrences(t *testing.T) {
+	loc := stockholm(t)
+	series := EventSeries{
+		ID:        3,
+		Title:     "Reading group",
+		Location:  "Library",
+		StartDate: time.Date(2025, 9, 2, 18, 0, 0, 0, loc),
+		EndDate:   time.Date(2025, 9, 2, 19, 30, 0, 0, loc),
+		Recurrence: RecurrenceRule{
+			Frequency: RecurrenceWeekly,
+			Count:     2,
+		},
+	}
+	events := series.Occurrences()
+	assert.Len(t, events, 2)
+	for _, e := range events {
+		assert.Equal(t, "Reading group", e.Title)
+		assert.Equal(t, uint(3), *e.SeriesID)
+		assert.Equal(t, 90*time.Minute, e.EndDate.Sub(e.StartDate))
+		assert.True(t, e.OccurrenceDate.Equal(e.StartDate))
+	}
+}
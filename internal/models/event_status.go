@@ -0,0 +1,55 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+)
+
+type EventStatus string
+
+const (
+	EventStatusDraft     EventStatus = "draft"     // only visible to its organizers
+	EventStatusPublished EventStatus = "published" // listed and open for registration
+	EventStatusCancelled EventStatus = "cancelled"
+	EventStatusCompleted EventStatus = "completed" // registrations are final, attendance can still be corrected
+)
+
+// eventTransitions lists the statuses each status can move to. Cancelled and
+// completed are final.
+var eventTransitions = map[EventStatus][]EventStatus{
+	EventStatusDraft:     {EventStatusPublished},
+	EventStatusPublished: {EventStatusCancelled, EventStatusCompleted},
+}
+
+// PublicEventStatuses are the statuses of events everyone can see
+var PublicEventStatuses = []EventStatus{
+	EventStatusPublished,
+	EventStatusCancelled,
+	EventStatusCompleted,
+}
+
+func (s EventStatus) Valid() bool {
+	return s == EventStatusDraft || slices.Contains(PublicEventStatuses, s)
+}
+
+// TransitionTo checks that an event can move from status s to next
+func (s EventStatus) TransitionTo(next EventStatus) error {
+	if !next.Valid() {
+		return fmt.Errorf("unknown status %q", next)
+	}
+	if !slices.Contains(eventTransitions[s], next) {
+		return fmt.Errorf("can't change status from %s to %s", s, next)
+	}
+	return nil
+}
+
+// IsPublic reports whether the event can be seen by everyone
+func (e *Event) IsPublic() bool {
+	return e.Status != EventStatusDraft
+}
+
+// RegistrationsLocked reports whether registrations to the event can no
+// longer be created or changed
+func (e *Event) RegistrationsLocked() bool {
+	return e.Status == EventStatusCancelled || e.Status == EventStatusCompleted
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from, to EventStatus
+		ok       bool
+	}{
+		{EventStatusDraft, EventStatusPublished, true},
+		{EventStatusPublished, EventStatusCancelled, true},
+		{EventStatusPublished, EventStatusCompleted, true},
+		{EventStatusDraft, EventStatusCancelled, false},
+		{EventStatusDraft, EventStatusCompleted, false},
+		{EventStatusPublished, EventStatusDraft, false},
+		{EventStatusPublished, EventStatusPublished, false},
+		{EventStatusCancelled, EventStatusPublished, false},
+		{EventStatusCompleted, EventStatusCancelled, false},
+		{EventStatusPublished, "archived", false},
+	}
+	for _, tt := range tests {
+		err := tt.from.TransitionTo(tt.to)
+		assert.Equal(t, tt.ok, err == nil, "%s -> %s: %v", tt.from, tt.to, err)
+	}
+}
+
+func TestRegistrationsLocked(t *testing.T) {
+	for status, locked := range map[EventStatus]bool{
+		EventStatusDraft:     false,
+		EventStatusPublished: false,
+		EventStatusCancelled: true,
+		EventStatusCompleted: true,
+	} {
+		event := Event{Status: status}
+		assert.Equal(t, locked, event.RegistrationsLocked(), status)
+	}
+}
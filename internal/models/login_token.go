@@ -0,0 +1,60 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LoginTokenTTL is how long a magic link can be used to log in
+const LoginTokenTTL = 15 * time.Minute
+
+// ErrLoginTokenInvalid is returned for magic links that are unknown, expired
+// or already used
+var ErrLoginTokenInvalid = errors.New("login token is invalid or expired")
+
+// LoginToken is a magic link emailed to log in without a password. Only the
+// hash of its token is stored and it can be used once.
+type LoginToken struct {
+	ID        uint       `gorm:"primarykey"`
+	Email     string     `gorm:"not null;index"`
+	TokenHash string     `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time // set when the link is used
+	CreatedAt time.Time
+}
+
+// CreateLoginToken returns the token of a new magic link for an email
+// address. Earlier links of the address stop working, only the latest one
+// can be used.
+func CreateLoginToken(db *gorm.DB, email string, now time.Time) (string, error) {
+	token, hash := newToken()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("email = ?", email).Delete(&LoginToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&LoginToken{Email: email, TokenHash: hash, ExpiresAt: now.Add(LoginTokenTTL)}).Error
+	})
+	return token, err
+}
+
+// ConsumeLoginToken uses up a magic link and returns the email address it was
+// sent to
+func ConsumeLoginToken(db *gorm.DB, token string, now time.Time) (string, error) {
+	hash := hashToken(token)
+	// a link clicked twice at once must still only log in once
+	result := db.Model(&LoginToken{}).Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hash, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", ErrLoginTokenInvalid
+	}
+	var login LoginToken
+	if err := db.Where("token_hash = ?", hash).First(&login).Error; err != nil {
+		return "", err
+	}
+	return login.Email, nil
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginTokens(t *testing.T) {
+	db := newWaitlistDB(t)
+	require.NoError(t, db.AutoMigrate(&LoginToken{}))
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	token, err := CreateLoginToken(db, "ada@kthais.com", now)
+	require.NoError(t, err)
+	email, err := ConsumeLoginToken(db, token, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "ada@kthais.com", email)
+
+	// links work only once
+	_, err = ConsumeLoginToken(db, token, now.Add(2*time.Minute))
+	assert.ErrorIs(t, err, ErrLoginTokenInvalid)
+
+	// and only until they expire
+	token, err = CreateLoginToken(db, "ada@kthais.com", now)
+	require.NoError(t, err)
+	_, err = ConsumeLoginToken(db, token, now.Add(LoginTokenTTL))
+	assert.ErrorIs(t, err, ErrLoginTokenInvalid)
+
+	// a new link replaces the old one
+	old, err := CreateLoginToken(db, "ada@kthais.com", now)
+	require.NoError(t, err)
+	latest, err := CreateLoginToken(db, "ada@kthais.com", now)
+	require.NoError(t, err)
+	_, err = ConsumeLoginToken(db, old, now)
+	assert.ErrorIs(t, err, ErrLoginTokenInvalid)
+	_, err = ConsumeLoginToken(db, latest, now)
+	assert.NoError(t, err)
+
+	_, err = ConsumeLoginToken(db, "made-up", now)
+	assert.ErrorIs(t, err, ErrLoginTokenInvalid)
+}
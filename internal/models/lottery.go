@@ -0,0 +1,200 @@
+package models
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SelectionMode string
+
+const (
+	SelectionFirstCome SelectionMode = "first_come" // spots go to the first registrants
+	SelectionLottery   SelectionMode = "lottery"    // registrants apply until the deadline, then spots are drawn
+)
+
+// LotteryRules configure how spots are drawn for an event in lottery mode.
+// Every registrant has a weight of 1, multiplied by the weights below that
+// apply to them.
+type LotteryRules struct {
+	FirstTimeWeight  float64                  `json:"first_time_weight,omitempty"` // for registrants who never attended an event
+	ProgrammeWeights map[StudyProgram]float64 `json:"programme_weights,omitempty"`
+	Quotas           []LotteryQuota           `json:"quotas,omitempty"`     // spots reserved for groups, drawn first
+	Unselected       RegistrationStatus       `json:"unselected,omitempty"` // rejected (default) or waitlisted
+	AutoDraw         bool                     `json:"auto_draw,omitempty"`  // draw as soon as applications close instead of waiting for an organizer
+}
+
+// LotteryQuota reserves spots for the registrants matching all of its
+// conditions. Spots a quota can't fill go to everyone.
+type LotteryQuota struct {
+	Programme StudyProgram `json:"programme,omitempty"`
+	FirstTime bool         `json:"first_time,omitempty"`
+	Spots     int          `json:"spots"`
+}
+
+// LotteryCandidate is a pending registration taking part in a draw
+type LotteryCandidate struct {
+	RegistrationID uint         `json:"registration_id"`
+	Programme      StudyProgram `json:"programme"`
+	FirstTime      bool         `json:"first_time"`
+	Deprioritized  bool         `json:"deprioritized,omitempty"` // by the no-show policy, only drawn once everyone else is
+}
+
+// maxLotterySeed bounds random seeds to the integers JavaScript numbers hold
+// exactly, so that a seed read back from the API still reproduces the draw
+const maxLotterySeed = 1 << 53
+
+// NewLotterySeed returns a random seed for a draw
+func NewLotterySeed() int64 {
+	return rand.Int64N(maxLotterySeed)
+}
+
+// LotteryDraw records a draw so that it can be audited and reproduced:
+// drawing the same candidates with the same rules, spots and seed gives the
+// same result.
+type LotteryDraw struct {
+	ID         uint               `gorm:"primarykey" json:"id"`
+	EventID    uint               `gorm:"not null;uniqueIndex" json:"event_id"`
+	Seed       int64              `gorm:"not null" json:"seed"`
+	Rules      LotteryRules       `gorm:"serializer:json" json:"rules"`
+	Spots      int                `json:"spots"`
+	Candidates []LotteryCandidate `gorm:"serializer:json" json:"candidates"`
+	Selected   []uint             `gorm:"serializer:json" json:"selected"` // registration IDs, in draw order
+	DrawnBy    *uint              `json:"drawn_by,omitempty"`              // nil if drawn by the scheduler
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+// Validate checks the rules of an event with room for capacity registrants,
+// 0 meaning unlimited
+func (r LotteryRules) Validate(capacity int) error {
+	if r.FirstTimeWeight < 0 {
+		return fmt.Errorf("first_time_weight can't be negative")
+	}
+	for programme, w := range r.ProgrammeWeights {
+		if w <= 0 {
+			return fmt.Errorf("weight of %s must be positive", programme)
+		}
+	}
+	reserved := 0
+	for i, q := range r.Quotas {
+		if q.Spots <= 0 {
+			return fmt.Errorf("quota %d must reserve at least one spot", i+1)
+		}
+		if q.Programme == "" && !q.FirstTime {
+			return fmt.Errorf("quota %d needs a programme or first_time", i+1)
+		}
+		reserved += q.Spots
+	}
+	if capacity > 0 && reserved > capacity {
+		return fmt.Errorf("quotas reserve %d spots but the event only has %d", reserved, capacity)
+	}
+	if r.Unselected != "" && r.Unselected != RegistrationStatusRejected && r.Unselected != RegistrationStatusWaitlisted {
+		return fmt.Errorf("unselected must be rejected or waitlisted")
+	}
+	return nil
+}
+
+// UnselectedStatus returns the status of registrants who weren't drawn
+func (r LotteryRules) UnselectedStatus() RegistrationStatus {
+	if r.Unselected == "" {
+		return RegistrationStatusRejected
+	}
+	return r.Unselected
+}
+
+func (r LotteryRules) weight(c LotteryCandidate) float64 {
+	w := 1.0
+	if c.FirstTime && r.FirstTimeWeight > 0 {
+		w *= r.FirstTimeWeight
+	}
+	if pw, ok := r.ProgrammeWeights[c.Programme]; ok {
+		w *= pw
+	}
+	return w
+}
+
+func (q LotteryQuota) matches(c LotteryCandidate) bool {
+	return (q.Programme == "" || q.Programme == c.Programme) && (!q.FirstTime || c.FirstTime)
+}
+
+// Draw picks up to spots candidates. Every candidate gets a random key
+// weighted by the rules (Efraimidis-Spirakis sampling), quotas are filled
+// first and the remaining spots go to the highest keys. Deprioritized
+// candidates come after all others. Returns the selected candidates and the
+// rest, both in draw order.
+func (r LotteryRules) Draw(candidates []LotteryCandidate, spots int, seed int64) (selected, rest []LotteryCandidate) {
+	// the keys are handed out in registration order, so the result only
+	// depends on the seed and not on the order the candidates were loaded in
+	order := slices.SortedFunc(slices.Values(candidates), func(a, b LotteryCandidate) int {
+		return cmp.Compare(a.RegistrationID, b.RegistrationID)
+	})
+	rng := rand.New(rand.NewPCG(uint64(seed), 0))
+	keys := make(map[uint]float64, len(order))
+	for _, c := range order {
+		u := 1 - rng.Float64() // (0, 1], log(0) is undefined
+		keys[c.RegistrationID] = math.Log(u) / r.weight(c)
+	}
+	slices.SortStableFunc(order, func(a, b LotteryCandidate) int {
+		if a.Deprioritized != b.Deprioritized {
+			if a.Deprioritized {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Compare(keys[b.RegistrationID], keys[a.RegistrationID])
+	})
+
+	taken := make(map[uint]bool, spots)
+	take := func(c LotteryCandidate) {
+		selected = append(selected, c)
+		taken[c.RegistrationID] = true
+	}
+	for _, q := range r.Quotas {
+		filled := 0
+		for _, c := range order {
+			if filled == q.Spots || len(selected) == spots {
+				break
+			}
+			if !taken[c.RegistrationID] && q.matches(c) {
+				take(c)
+				filled++
+			}
+		}
+	}
+	for _, c := range order {
+		if len(selected) == spots {
+			break
+		}
+		if !taken[c.RegistrationID] {
+			take(c)
+		}
+	}
+	for _, c := range order {
+		if !taken[c.RegistrationID] {
+			rest = append(rest, c)
+		}
+	}
+	return selected, rest
+}
+
+// LotteryCandidates loads the pending registrations of an event along with
+// what the lottery rules can weigh them by
+func LotteryCandidates(tx *gorm.DB, eventID uint) ([]LotteryCandidate, error) {
+	var candidates []LotteryCandidate
+	err := tx.Model(&Registration{}).
+		Select(`registrations.id AS registration_id, registrations.deprioritized, COALESCE(profiles.programme, '') AS programme,
+			NOT EXISTS (SELECT 1 FROM registrations attended WHERE attended.user_id = registrations.user_id
+				AND attended.attended AND attended.event_id <> registrations.event_id
+				AND attended.deleted_at IS NULL) AS first_time`).
+		Joins("JOIN users ON users.id = registrations.user_id").
+		Joins("LEFT JOIN profiles ON profiles.user_id = users.user_id AND profiles.deleted_at IS NULL").
+		Where("registrations.event_id = ? AND registrations.status = ?", eventID, RegistrationStatusPending).
+		Order("registrations.id").
+		Scan(&candidates).Error
+	return candidates, err
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lotteryCandidates(n int, f func(i int, c *LotteryCandidate)) []LotteryCandidate {
+	candidates := make([]LotteryCandidate, n)
+	for i := range candidates {
+		candidates[i].RegistrationID = uint(i + 1)
+		if f != nil {
+			f(i, &candidates[i])
+		}
+	}
+	return candidates
+}
+
+func ids(candidates []LotteryCandidate) []uint {
+	var ids []uint
+	for _, c := range candidates {
+		ids = append(ids, c.RegistrationID)
+	}
+	return ids
+}
+
+func TestDrawIsReproducible(t *testing.T) {
+	candidates := lotteryCandidates(50, nil)
+	selected, rest := LotteryRules{}.Draw(candidates, 10, 42)
+	require.Len(t, selected, 10)
+	require.Len(t, rest, 40)
+
+	// the order the candidates come in doesn't matter, only the seed does
+	reversed := make([]LotteryCandidate, len(candidates))
+	for i, c := range candidates {
+		reversed[len(candidates)-1-i] = c
+	}
+	again, againRest := LotteryRules{}.Draw(reversed, 10, 42)
+	assert.Equal(t, ids(selected), ids(again))
+	assert.Equal(t, ids(rest), ids(againRest))
+
+	other, _ := LotteryRules{}.Draw(candidates, 10, 43)
+	assert.NotEqual(t, ids(selected), ids(other))
+}
+
+func TestLotterySeedsSurviveJavaScript(t *testing.T) {
+	for range 1000 {
+		seed := NewLotterySeed()
+		assert.GreaterOrEqual(t, seed, int64(0))
+		// float64 is what JSON numbers become in browsers
+		assert.Equal(t, seed, int64(float64(seed)))
+	}
+}
+
+func TestDrawWithMoreSpotsThanCandidates(t *testing.T) {
+	selected, rest := LotteryRules{}.Draw(lotteryCandidates(5, nil), 10, 1)
+	assert.Len(t, selected, 5)
+	assert.Empty(t, rest)
+
+	selected, rest = LotteryRules{}.Draw(lotteryCandidates(5, nil), 0, 1)
+	assert.Empty(t, selected)
+	assert.Len(t, rest, 5)
+}
+
+func TestDrawWeights(t *testing.T) {
+	// half of the candidates are first-timers weighing 10 times as much
+	candidates := lotteryCandidates(200, func(i int, c *LotteryCandidate) { c.FirstTime = i%2 == 0 })
+	rules := LotteryRules{FirstTimeWeight: 10}
+
+	firstTimers := 0
+	for seed := range int64(20) {
+		selected, _ := rules.Draw(candidates, 20, seed)
+		for _, c := range selected {
+			if c.FirstTime {
+				firstTimers++
+			}
+		}
+	}
+	assert.Greater(t, firstTimers, 300, "first-timers should get most of the 400 spots")
+}
+
+func TestDrawQuotas(t *testing.T) {
+	candidates := lotteryCandidates(100, func(i int, c *LotteryCandidate) {
+		c.Programme = StudyProgramComputerScience
+		if i < 3 {
+			c.Programme = StudyProgramMathematics
+		}
+	})
+	rules := LotteryRules{Quotas: []LotteryQuota{{Programme: StudyProgramMathematics, Spots: 5}}}
+
+	for seed := range int64(10) {
+		selected, rest := rules.Draw(candidates, 10, seed)
+		require.Len(t, selected, 10)
+		require.Len(t, rest, 90)
+		// the quota can only be filled with the three mathematicians, the
+		// spots left over go to everyone
+		assert.ElementsMatch(t, []uint{1, 2, 3}, ids(selected[:3]))
+	}
+}
+
+func TestLotteryRulesValidate(t *testing.T) {
+	assert.NoError(t, LotteryRules{}.Validate(0))
+	assert.NoError(t, LotteryRules{
+		FirstTimeWeight:  2,
+		ProgrammeWeights: map[StudyProgram]float64{StudyProgramMathematics: 0.5},
+		Quotas:           []LotteryQuota{{FirstTime: true, Spots: 10}},
+		Unselected:       RegistrationStatusWaitlisted,
+	}.Validate(10))
+
+	for name, rules := range map[string]LotteryRules{
+		"negative weight":   {FirstTimeWeight: -1},
+		"zero programme":    {ProgrammeWeights: map[StudyProgram]float64{StudyProgramMathematics: 0}},
+		"empty quota":       {Quotas: []LotteryQuota{{Programme: StudyProgramMathematics}}},
+		"quota for anyone":  {Quotas: []LotteryQuota{{Spots: 1}}},
+		"quotas too big":    {Quotas: []LotteryQuota{{FirstTime: true, Spots: 11}}},
+		"unselected status": {Unselected: RegistrationStatusApproved},
+	} {
+		assert.Error(t, rules.Validate(10), name)
+	}
+}
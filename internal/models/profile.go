@@ -43,3 +43,36 @@ type Profile struct {
 	LinkedInLink   string       `json:"linkedin_link,omitempty"`
 	Registered     bool         `json:"registered"`
 }
+
+// Registrations refer to users by their numeric id while profiles use the
+// UUID, these look up profiles by the numeric id.
+
+// ProfileForUser looks up the profile of a user by the id used in registrations
+func ProfileForUser(db *gorm.DB, userID uint) (Profile, error) {
+	var profile Profile
+	err := db.Joins("JOIN users ON users.user_id = profiles.user_id").
+		Where("users.id = ?", userID).First(&profile).Error
+	return profile, err
+}
+
+// ProfilesForUsers looks up the profiles of many users at once. Users without
+// a profile are left out of the map.
+func ProfilesForUsers(db *gorm.DB, userIDs []uint) (map[uint]Profile, error) {
+	var rows []struct {
+		Profile
+		RegistrationUserID uint
+	}
+	err := db.Model(&Profile{}).
+		Select("profiles.*, users.id AS registration_user_id").
+		Joins("JOIN users ON users.user_id = profiles.user_id").
+		Where("users.id IN ?", userIDs).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	profiles := make(map[uint]Profile, len(rows))
+	for _, row := range rows {
+		profiles[row.RegistrationUserID] = row.Profile
+	}
+	return profiles, nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"slices"
 	"time"
 
 	"gorm.io/gorm"
@@ -9,21 +10,60 @@ import (
 type RegistrationStatus string
 
 const (
-	RegistrationStatusPending  RegistrationStatus = "pending"
-	RegistrationStatusApproved RegistrationStatus = "approved"
-	RegistrationStatusRejected RegistrationStatus = "rejected"
+	RegistrationStatusPending    RegistrationStatus = "pending"
+	RegistrationStatusApproved   RegistrationStatus = "approved"
+	RegistrationStatusRejected   RegistrationStatus = "rejected"
+	RegistrationStatusWaitlisted RegistrationStatus = "waitlisted" // over capacity, waiting for a spot to open up
+	RegistrationStatusOffered    RegistrationStatus = "offered"    // promoted from the waitlist, must confirm before OfferExpiresAt
 )
 
+// SpotHoldingStatuses are the statuses that count towards RegistrationMax
+var SpotHoldingStatuses = []RegistrationStatus{
+	RegistrationStatusPending,
+	RegistrationStatusApproved,
+	RegistrationStatusOffered,
+}
+
+func (s RegistrationStatus) HoldsSpot() bool {
+	return slices.Contains(SpotHoldingStatuses, s)
+}
+
+func (s RegistrationStatus) Valid() bool {
+	return s.HoldsSpot() || s == RegistrationStatusRejected || s == RegistrationStatusWaitlisted
+}
+
 type Registration struct {
 	ID                  uint               `gorm:"primarykey" json:"id"`
-	EventID             uint               `gorm:"not null" json:"event_id"`
+	EventID             uint               `gorm:"not null;uniqueIndex:idx_registration_event_user,where:deleted_at IS NULL" json:"event_id"`
 	Event               Event              `gorm:"foreignKey:EventID" json:"event"`
-	UserID              uint               `gorm:"not null" json:"user_id"`
+	UserID              uint               `gorm:"not null;uniqueIndex:idx_registration_event_user,where:deleted_at IS NULL" json:"user_id"`
 	User                User               `gorm:"foreignKey:UserID" json:"user"`
 	Status              RegistrationStatus `gorm:"not null" json:"status"`
 	Attended            bool               `gorm:"not null" json:"attended"`
+	CheckedInAt         *time.Time         `json:"checked_in_at,omitempty"`
+	TicketNonce         string             `json:"-"` // set when approved, part of the ticket token
 	DietaryRestrictions string             `json:"dietary_restrictions"`
+	Answers             FormAnswers        `gorm:"serializer:json" json:"answers,omitempty"` // answers to the registration form of the event
+	OfferExpiresAt      *time.Time         `json:"offer_expires_at,omitempty"`
+	LotteryRank         int                `json:"lottery_rank,omitempty"`  // place in the draw of registrants who didn't get a spot, orders the waitlist
+	Deprioritized       bool               `json:"deprioritized,omitempty"` // missed too many events, goes behind everyone else on the waitlist
+	TeamID              *uint              `gorm:"index" json:"team_id,omitempty"`
+	Team                *Team              `gorm:"foreignKey:TeamID" json:"team,omitempty"`
+	CancelledAt         *time.Time         `json:"cancelled_at,omitempty"`   // set when the registrant cancelled
+	CancelledLate       bool               `json:"cancelled_late,omitempty"` // cancelled a spot after the event's cancellation deadline
+	WaitlistPosition    int                `gorm:"-" json:"waitlist_position,omitempty"`
 	CreatedAt           time.Time          `json:"created_at"`
 	UpdatedAt           time.Time          `json:"updated_at"`
 	DeletedAt           gorm.DeletedAt     `gorm:"index" json:"-"`
 }
+
+// RemoveDuplicateRegistrations soft deletes all but the first registration of
+// each user to an event, so that the unique index can be created on databases
+// from before it existed
+func RemoveDuplicateRegistrations(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&Registration{}) {
+		return nil
+	}
+	return db.Exec(`UPDATE registrations SET deleted_at = ? WHERE deleted_at IS NULL AND id NOT IN (
+		SELECT MIN(id) FROM registrations WHERE deleted_at IS NULL GROUP BY event_id, user_id)`, time.Now()).Error
+}
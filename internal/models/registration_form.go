@@ -0,0 +1,241 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+type FormFieldType string
+
+const (
+	FormFieldText        FormFieldType = "text"
+	FormFieldSelect      FormFieldType = "select"
+	FormFieldMultiSelect FormFieldType = "multi_select"
+	FormFieldCheckbox    FormFieldType = "checkbox" // a required checkbox must be checked, e.g. for consent
+	FormFieldNumber      FormFieldType = "number"
+)
+
+// FormField is a question on a registration form. Which validation rules
+// apply depends on the type.
+type FormField struct {
+	Name        string        `json:"name"` // key of the answer
+	Label       string        `json:"label"`
+	Type        FormFieldType `json:"type"`
+	Required    bool          `json:"required,omitempty"`
+	Options     []string      `json:"options,omitempty"`      // select and multi_select
+	MaxSelected int           `json:"max_selected,omitempty"` // multi_select, 0 = no limit
+	MinLength   int           `json:"min_length,omitempty"`   // text
+	MaxLength   int           `json:"max_length,omitempty"`   // text, 0 = no limit
+	Pattern     string        `json:"pattern,omitempty"`      // text, regular expression the whole answer must match
+	Min         *float64      `json:"min,omitempty"`          // number
+	Max         *float64      `json:"max,omitempty"`          // number
+	Integer     bool          `json:"integer,omitempty"`      // number
+}
+
+// FormSchema is the custom registration form of an event
+type FormSchema struct {
+	Fields []FormField `json:"fields,omitempty"`
+}
+
+// FormAnswers maps field names to answers: strings for text and select,
+// string lists for multi_select, booleans for checkbox and numbers for number
+type FormAnswers map[string]any
+
+// FormErrors maps field names to what is wrong with their answers
+type FormErrors map[string]string
+
+func (e FormErrors) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = name + ": " + e[name]
+	}
+	return "invalid answers: " + strings.Join(msgs, "; ")
+}
+
+var fieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Validate checks that the schema itself is well-formed
+func (s FormSchema) Validate() error {
+	seen := map[string]bool{}
+	for i, f := range s.Fields {
+		if !fieldNamePattern.MatchString(f.Name) {
+			return fmt.Errorf("field %d: name must be lowercase letters, digits and underscores", i)
+		}
+		if seen[f.Name] {
+			return fmt.Errorf("field %s: duplicate name", f.Name)
+		}
+		seen[f.Name] = true
+
+		switch f.Type {
+		case FormFieldText:
+			if f.MinLength < 0 || f.MaxLength < 0 || (f.MaxLength > 0 && f.MinLength > f.MaxLength) {
+				return fmt.Errorf("field %s: invalid length limits", f.Name)
+			}
+			if f.Pattern != "" {
+				if _, err := f.pattern(); err != nil {
+					return fmt.Errorf("field %s: invalid pattern: %w", f.Name, err)
+				}
+			}
+		case FormFieldSelect, FormFieldMultiSelect:
+			if len(f.Options) == 0 {
+				return fmt.Errorf("field %s: options are required", f.Name)
+			}
+			if f.MaxSelected < 0 {
+				return fmt.Errorf("field %s: invalid max_selected", f.Name)
+			}
+		case FormFieldNumber:
+			if f.Min != nil && f.Max != nil && *f.Min > *f.Max {
+				return fmt.Errorf("field %s: min is larger than max", f.Name)
+			}
+		case FormFieldCheckbox:
+		default:
+			return fmt.Errorf("field %s: unknown type %q", f.Name, f.Type)
+		}
+	}
+	return nil
+}
+
+// ValidateAnswers checks answers against the schema and returns them cleaned
+// up: text is trimmed and unanswered optional fields are left out. The error
+// is a FormErrors if any answer is invalid.
+func (s FormSchema) ValidateAnswers(answers FormAnswers) (FormAnswers, error) {
+	clean := FormAnswers{}
+	errs := FormErrors{}
+	for name := range answers {
+		if !slices.ContainsFunc(s.Fields, func(f FormField) bool { return f.Name == name }) {
+			errs[name] = "unknown field"
+		}
+	}
+
+	for _, f := range s.Fields {
+		answer, ok := answers[f.Name]
+		if !ok || answer == nil {
+			if f.Required {
+				errs[f.Name] = "required"
+			}
+			continue
+		}
+		value, err := f.validate(answer)
+		if err != nil {
+			errs[f.Name] = err.Error()
+			continue
+		}
+		if value != nil {
+			clean[f.Name] = value
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return clean, nil
+}
+
+// pattern compiles the pattern of a text field, which has to match the whole
+// answer
+func (f FormField) pattern() (*regexp.Regexp, error) {
+	return regexp.Compile(`^(?:` + f.Pattern + `)$`)
+}
+
+// validate checks a single answer. A nil value without error means the
+// optional field was left empty.
+func (f FormField) validate(answer any) (any, error) {
+	switch f.Type {
+	case FormFieldText:
+		text, ok := answer.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be text")
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			if f.Required {
+				return nil, fmt.Errorf("required")
+			}
+			return nil, nil
+		}
+		length := utf8.RuneCountInString(text)
+		if length < f.MinLength {
+			return nil, fmt.Errorf("must be at least %d characters", f.MinLength)
+		}
+		if f.MaxLength > 0 && length > f.MaxLength {
+			return nil, fmt.Errorf("must be at most %d characters", f.MaxLength)
+		}
+		if f.Pattern != "" {
+			pattern, err := f.pattern()
+			if err != nil {
+				return nil, fmt.Errorf("can't be checked, the form has an invalid pattern")
+			}
+			if !pattern.MatchString(text) {
+				return nil, fmt.Errorf("has the wrong format")
+			}
+		}
+		return text, nil
+
+	case FormFieldSelect:
+		choice, ok := answer.(string)
+		if !ok || !slices.Contains(f.Options, choice) {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(f.Options, ", "))
+		}
+		return choice, nil
+
+	case FormFieldMultiSelect:
+		list, ok := answer.([]any)
+		if !ok {
+			return nil, fmt.Errorf("must be a list")
+		}
+		choices := []string{}
+		for _, item := range list {
+			choice, ok := item.(string)
+			if !ok || !slices.Contains(f.Options, choice) {
+				return nil, fmt.Errorf("must only contain %s", strings.Join(f.Options, ", "))
+			}
+			if !slices.Contains(choices, choice) {
+				choices = append(choices, choice)
+			}
+		}
+		if f.Required && len(choices) == 0 {
+			return nil, fmt.Errorf("required")
+		}
+		if f.MaxSelected > 0 && len(choices) > f.MaxSelected {
+			return nil, fmt.Errorf("can have at most %d choices", f.MaxSelected)
+		}
+		return choices, nil
+
+	case FormFieldCheckbox:
+		checked, ok := answer.(bool)
+		if !ok {
+			return nil, fmt.Errorf("must be true or false")
+		}
+		if f.Required && !checked {
+			return nil, fmt.Errorf("must be checked")
+		}
+		return checked, nil
+
+	case FormFieldNumber:
+		number, ok := answer.(float64)
+		if !ok {
+			return nil, fmt.Errorf("must be a number")
+		}
+		if f.Integer && number != math.Trunc(number) {
+			return nil, fmt.Errorf("must be a whole number")
+		}
+		if f.Min != nil && number < *f.Min {
+			return nil, fmt.Errorf("must be at least %v", *f.Min)
+		}
+		if f.Max != nil && number > *f.Max {
+			return nil, fmt.Errorf("must be at most %v", *f.Max)
+		}
+		return number, nil
+	}
+	return nil, fmt.Errorf("unknown field type %q", f.Type)
+}
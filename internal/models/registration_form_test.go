@@ -0,0 +1,106 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+var hackathonForm = FormSchema{Fields: []FormField{
+	{Name: "team_name", Label: "Team name", Type: FormFieldText, Required: true, MaxLength: 20},
+	{Name: "github", Label: "GitHub", Type: FormFieldText, Pattern: `https://github\.com/[A-Za-z0-9-]+`},
+	{Name: "tshirt", Label: "T-shirt size", Type: FormFieldSelect, Required: true, Options: []string{"S", "M", "L"}},
+	{Name: "interests", Label: "Interests", Type: FormFieldMultiSelect, Options: []string{"nlp", "cv", "rl"}, MaxSelected: 2},
+	{Name: "consent", Label: "I agree to be photographed", Type: FormFieldCheckbox, Required: true},
+	{Name: "years", Label: "Years of experience", Type: FormFieldNumber, Min: ptr(0.0), Max: ptr(50.0), Integer: true},
+}}
+
+// answers decodes JSON so the types match what handlers get from a request body
+func answers(t *testing.T, data string) FormAnswers {
+	t.Helper()
+	var a FormAnswers
+	require.NoError(t, json.Unmarshal([]byte(data), &a))
+	return a
+}
+
+func TestValidateAnswers(t *testing.T) {
+	valid := `"team_name": " Gradient Descenders ", "tshirt": "M", "consent": true`
+	tests := []struct {
+		name    string
+		answers string
+		errors  FormErrors
+	}{
+		{"minimal", `{` + valid + `}`, nil},
+		{"all fields", `{` + valid + `, "github": "https://github.com/kthais", "interests": ["nlp", "cv"], "years": 3}`, nil},
+		{"missing required", `{"tshirt": "M"}`, FormErrors{"team_name": "required", "consent": "required"}},
+		{"blank text", `{"team_name": "  ", "tshirt": "M", "consent": true}`, FormErrors{"team_name": "required"}},
+		{"too long", `{"team_name": "a team name that is far too long", "tshirt": "M", "consent": true}`, FormErrors{"team_name": "must be at most 20 characters"}},
+		{"pattern", `{` + valid + `, "github": "https://gitlab.com/kthais"}`, FormErrors{"github": "has the wrong format"}},
+		{"unknown option", `{"team_name": "x", "tshirt": "XXL", "consent": true}`, FormErrors{"tshirt": "must be one of S, M, L"}},
+		{"too many choices", `{` + valid + `, "interests": ["nlp", "cv", "rl"]}`, FormErrors{"interests": "can have at most 2 choices"}},
+		{"unchecked consent", `{"team_name": "x", "tshirt": "M", "consent": false}`, FormErrors{"consent": "must be checked"}},
+		{"wrong type", `{"team_name": 5, "tshirt": "M", "consent": "yes"}`, FormErrors{"team_name": "must be text", "consent": "must be true or false"}},
+		{"out of range", `{` + valid + `, "years": 51}`, FormErrors{"years": "must be at most 50"}},
+		{"not whole", `{` + valid + `, "years": 1.5}`, FormErrors{"years": "must be a whole number"}},
+		{"unknown field", `{` + valid + `, "shoe_size": 44}`, FormErrors{"shoe_size": "unknown field"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clean, err := hackathonForm.ValidateAnswers(answers(t, tt.answers))
+			if tt.errors == nil {
+				require.NoError(t, err)
+				assert.Equal(t, "Gradient Descenders", clean["team_name"])
+				return
+			}
+			assert.Equal(t, tt.errors, err)
+		})
+	}
+}
+
+func TestValidateAnswersCleansUp(t *testing.T) {
+	clean, err := hackathonForm.ValidateAnswers(answers(t,
+		`{"team_name": "x", "tshirt": "S", "consent": true, "github": "", "interests": ["cv", "cv"]}`))
+	require.NoError(t, err)
+	assert.NotContains(t, clean, "github")
+	assert.Equal(t, []string{"cv"}, clean["interests"])
+}
+
+func TestEmptyFormAcceptsNoAnswers(t *testing.T) {
+	clean, err := FormSchema{}.ValidateAnswers(nil)
+	require.NoError(t, err)
+	assert.Empty(t, clean)
+}
+
+func TestValidateSchema(t *testing.T) {
+	require.NoError(t, hackathonForm.Validate())
+
+	tests := map[string]FormField{
+		"bad name":        {Name: "Team Name", Type: FormFieldText},
+		"unknown type":    {Name: "x", Type: "date"},
+		"no options":      {Name: "x", Type: FormFieldSelect},
+		"bad pattern":     {Name: "x", Type: FormFieldText, Pattern: "("},
+		"unclosed quote":  {Name: "x", Type: FormFieldText, Pattern: `\Qabc`}, // fine alone, not once anchored
+		"bad length":      {Name: "x", Type: FormFieldText, MinLength: 5, MaxLength: 2},
+		"min above max":   {Name: "x", Type: FormFieldNumber, Min: ptr(2.0), Max: ptr(1.0)},
+		"negative choice": {Name: "x", Type: FormFieldMultiSelect, Options: []string{"a"}, MaxSelected: -1},
+	}
+	for name, field := range tests {
+		assert.Error(t, FormSchema{Fields: []FormField{field}}.Validate(), name)
+	}
+	duplicate := FormSchema{Fields: []FormField{{Name: "x", Type: FormFieldCheckbox}, {Name: "x", Type: FormFieldCheckbox}}}
+	assert.Error(t, duplicate.Validate())
+}
+
+func TestInvalidPatternDoesNotPanic(t *testing.T) {
+	form := FormSchema{Fields: []FormField{{Name: "code", Type: FormFieldText, Pattern: `\Qabc`}}}
+	_, err := form.ValidateAnswers(FormAnswers{"code": "abc"})
+	var formErrors FormErrors
+	require.ErrorAs(t, err, &formErrors)
+	assert.Contains(t, formErrors["code"], "invalid pattern")
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoveDuplicateRegistrations(t *testing.T) {
+	db := newWaitlistDB(t)
+	// databases from before the unique index can have duplicates
+	require.NoError(t, db.Migrator().DropIndex(&Registration{}, "idx_registration_event_user"))
+	first := Registration{EventID: 1, UserID: 1, Status: RegistrationStatusApproved}
+	for _, r := range []*Registration{
+		&first,
+		{EventID: 1, UserID: 1, Status: RegistrationStatusPending},
+		{EventID: 1, UserID: 2, Status: RegistrationStatusPending},
+		{EventID: 2, UserID: 1, Status: RegistrationStatusPending},
+	} {
+		require.NoError(t, db.Create(r).Error)
+	}
+
+	require.NoError(t, RemoveDuplicateRegistrations(db))
+	var left []Registration
+	require.NoError(t, db.Order("id").Find(&left).Error)
+	require.Len(t, left, 3)
+	assert.Equal(t, first.ID, left[0].ID)
+
+	require.NoError(t, db.AutoMigrate(&Registration{}))
+	err := db.Create(&Registration{EventID: 1, UserID: 1, Status: RegistrationStatusPending}).Error
+	assert.Error(t, err, "the unique index should be back")
+
+	// soft deleted registrations don't count, so users can register again
+	require.NoError(t, db.Delete(&left[0]).Error)
+	assert.NoError(t, db.Create(&Registration{EventID: 1, UserID: 1, Status: RegistrationStatusPending}).Error)
+}
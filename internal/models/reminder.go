@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SentReminder records that the reminder sent at an offset before the event
+// went out to a registrant. The unique index makes claiming a reminder atomic,
+// so several API replicas can run the reminder scheduler side by side.
+type SentReminder struct {
+	ID             uint      `gorm:"primarykey"`
+	RegistrationID uint      `gorm:"not null;uniqueIndex:idx_sent_reminder"`
+	OffsetMinutes  int       `gorm:"not null;uniqueIndex:idx_sent_reminder"`
+	SentAt         time.Time `gorm:"not null"`
+}
+
+// ClaimReminder records a reminder as sent. Returns false if it was already
+// claimed, in which case it must not be sent again.
+func ClaimReminder(db *gorm.DB, registrationID uint, offset time.Duration, now time.Time) (bool, error) {
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&SentReminder{
+		RegistrationID: registrationID,
+		OffsetMinutes:  int(offset / time.Minute),
+		SentAt:         now,
+	})
+	return result.RowsAffected == 1, result.Error
+}
+
+// ReleaseReminder removes a claim so the reminder is retried, for when
+// sending it failed
+func ReleaseReminder(db *gorm.DB, registrationID uint, offset time.Duration) error {
+	return db.Where("registration_id = ? AND offset_minutes = ?", registrationID, int(offset/time.Minute)).
+		Delete(&SentReminder{}).Error
+}
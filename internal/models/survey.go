@@ -0,0 +1,220 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxSurveyComments limits the length of free-text survey comments
+const MaxSurveyComments = 5000
+
+// ErrSurveySubmitted is returned when a survey link is used a second time
+var ErrSurveySubmitted = errors.New("survey already submitted")
+
+// SurveyResponse is the feedback of an attendee after an event. The row is
+// created when the survey link is sent and filled in when it is submitted,
+// which can only happen once. Only the hash of the link's token is stored.
+type SurveyResponse struct {
+	ID             uint        `gorm:"primarykey" json:"id"`
+	EventID        uint        `gorm:"not null;index" json:"event_id"`
+	RegistrationID uint        `gorm:"not null;uniqueIndex" json:"-"`
+	TokenHash      string      `gorm:"not null;uniqueIndex" json:"-"`
+	Rating         int         `json:"rating,omitempty"` // 1-5, 0 until submitted
+	Comments       string      `json:"comments,omitempty"`
+	Answers        FormAnswers `gorm:"serializer:json" json:"answers,omitempty"` // to the event's survey form
+	SentAt         time.Time   `json:"sent_at"`
+	SubmittedAt    *time.Time  `json:"submitted_at,omitempty"`
+}
+
+// ClaimSurvey records that a survey link is sent to a registrant and returns
+// the token of the link. Returns false if they already got one, in which case
+// it must not be sent again.
+func ClaimSurvey(db *gorm.DB, registration Registration, now time.Time) (string, bool, error) {
+	token, hash := newToken()
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&SurveyResponse{
+		EventID:        registration.EventID,
+		RegistrationID: registration.ID,
+		TokenHash:      hash,
+		SentAt:         now,
+	})
+	return token, result.RowsAffected == 1, result.Error
+}
+
+// ReleaseSurvey removes a claim so the survey is sent again, for when sending
+// it failed
+func ReleaseSurvey(db *gorm.DB, registrationID uint) error {
+	return db.Where("registration_id = ?", registrationID).Delete(&SurveyResponse{}).Error
+}
+
+// FindSurvey returns the survey response a link's token belongs to
+func FindSurvey(db *gorm.DB, token string) (SurveyResponse, error) {
+	var response SurveyResponse
+	err := db.Where("token_hash = ?", hashToken(token)).First(&response).Error
+	return response, err
+}
+
+// Submit validates and stores the feedback. The error is a FormErrors if any
+// answer is invalid and ErrSurveySubmitted if the survey was already filled in.
+func (r *SurveyResponse) Submit(db *gorm.DB, form FormSchema, rating int, comments string, answers FormAnswers, now time.Time) error {
+	errs := FormErrors{}
+	if rating < 1 || rating > 5 {
+		errs["rating"] = "must be between 1 and 5"
+	}
+	comments = strings.TrimSpace(comments)
+	if utf8.RuneCountInString(comments) > MaxSurveyComments {
+		errs["comments"] = fmt.Sprintf("must be at most %d characters", MaxSurveyComments)
+	}
+	clean, err := form.ValidateAnswers(answers)
+	var formErrors FormErrors
+	if errors.As(err, &formErrors) {
+		for name, msg := range formErrors {
+			errs[name] = msg
+		}
+	} else if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	// a link opened in two tabs must still only count once
+	result := db.Model(&SurveyResponse{}).Where("id = ? AND submitted_at IS NULL", r.ID).
+		Select("rating", "comments", "answers", "submitted_at").
+		Updates(&SurveyResponse{Rating: rating, Comments: comments, Answers: clean, SubmittedAt: &now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSurveySubmitted
+	}
+	r.Rating, r.Comments, r.Answers, r.SubmittedAt = rating, comments, clean, &now
+	return nil
+}
+
+// SurveySummary aggregates the survey responses of an event
+type SurveySummary struct {
+	Attendees     int               `json:"attendees"`
+	Invited       int               `json:"invited"` // attendees the survey was sent to
+	Responses     int               `json:"responses"`
+	ResponseRate  float64           `json:"response_rate"`  // responses per invited attendee
+	AverageRating float64           `json:"average_rating"` // 0 without responses
+	Ratings       map[int]int       `json:"ratings"`        // number of responses per rating
+	Comments      []string          `json:"comments"`
+	Questions     []QuestionSummary `json:"questions"`
+}
+
+// QuestionSummary aggregates the answers to one question of a survey form.
+// Which fields are set depends on the type of the question.
+type QuestionSummary struct {
+	Name      string         `json:"name"`
+	Label     string         `json:"label"`
+	Type      FormFieldType  `json:"type"`
+	Responses int            `json:"responses"`         // how many answered the question
+	Average   *float64       `json:"average,omitempty"` // number
+	Counts    map[string]int `json:"counts,omitempty"`  // select, multi_select and checkbox
+	Answers   []string       `json:"answers,omitempty"` // text
+}
+
+// SummarizeSurvey aggregates the survey responses of an event
+func SummarizeSurvey(db *gorm.DB, event Event) (SurveySummary, error) {
+	summary := SurveySummary{Ratings: map[int]int{}, Comments: []string{}, Questions: []QuestionSummary{}}
+	for rating := 1; rating <= 5; rating++ {
+		summary.Ratings[rating] = 0
+	}
+
+	var attendees, invited int64
+	if err := db.Model(&Registration{}).Where("event_id = ? AND attended", event.ID).Count(&attendees).Error; err != nil {
+		return summary, err
+	}
+	if err := db.Model(&SurveyResponse{}).Where("event_id = ?", event.ID).Count(&invited).Error; err != nil {
+		return summary, err
+	}
+	var responses []SurveyResponse
+	if err := db.Where("event_id = ? AND submitted_at IS NOT NULL", event.ID).Order("submitted_at, id").
+		Find(&responses).Error; err != nil {
+		return summary, err
+	}
+
+	summary.Attendees, summary.Invited, summary.Responses = int(attendees), int(invited), len(responses)
+	if invited > 0 {
+		summary.ResponseRate = float64(len(responses)) / float64(invited)
+	}
+	total := 0
+	for _, r := range responses {
+		total += r.Rating
+		summary.Ratings[r.Rating]++
+		if r.Comments != "" {
+			summary.Comments = append(summary.Comments, r.Comments)
+		}
+	}
+	if len(responses) > 0 {
+		summary.AverageRating = float64(total) / float64(len(responses))
+	}
+	for _, f := range event.SurveyForm.Fields {
+		summary.Questions = append(summary.Questions, summarizeQuestion(f, responses))
+	}
+	return summary, nil
+}
+
+func summarizeQuestion(f FormField, responses []SurveyResponse) QuestionSummary {
+	q := QuestionSummary{Name: f.Name, Label: f.Label, Type: f.Type}
+	// choices nobody picked are still listed
+	switch f.Type {
+	case FormFieldSelect, FormFieldMultiSelect:
+		q.Counts = map[string]int{}
+		for _, option := range f.Options {
+			q.Counts[option] = 0
+		}
+	case FormFieldCheckbox:
+		q.Counts = map[string]int{"true": 0, "false": 0}
+	}
+
+	// answers that don't fit the question, e.g. after its type was changed, are skipped
+	sum := 0.0
+	for _, r := range responses {
+		switch value := r.Answers[f.Name].(type) {
+		case string:
+			switch f.Type {
+			case FormFieldText:
+				q.Answers = append(q.Answers, value)
+			case FormFieldSelect:
+				q.Counts[value]++
+			default:
+				continue
+			}
+		case []any: // multi_select, as read back from JSON
+			if f.Type != FormFieldMultiSelect {
+				continue
+			}
+			for _, choice := range value {
+				if s, ok := choice.(string); ok {
+					q.Counts[s]++
+				}
+			}
+		case bool:
+			if f.Type != FormFieldCheckbox {
+				continue
+			}
+			q.Counts[fmt.Sprint(value)]++
+		case float64:
+			if f.Type != FormFieldNumber {
+				continue
+			}
+			sum += value
+		default:
+			continue
+		}
+		q.Responses++
+	}
+	if f.Type == FormFieldNumber && q.Responses > 0 {
+		average := sum / float64(q.Responses)
+		q.Average = &average
+	}
+	return q
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeQuestion(t *testing.T) {
+	responses := []SurveyResponse{
+		{Answers: FormAnswers{"hours": 2.0, "topics": []any{"llms", "vision"}, "wish": "More food"}},
+		{Answers: FormAnswers{"hours": 4.0, "topics": []any{"llms"}}},
+		{Answers: FormAnswers{}},
+	}
+
+	q := summarizeQuestion(FormField{Name: "hours", Type: FormFieldNumber}, responses)
+	assert.Equal(t, 2, q.Responses)
+	if assert.NotNil(t, q.Average) {
+		assert.InDelta(t, 3.0, *q.Average, 0.001)
+	}
+
+	q = summarizeQuestion(FormField{Name: "topics", Type: FormFieldMultiSelect, Options: []string{"llms", "vision", "robotics"}}, responses)
+	assert.Equal(t, map[string]int{"llms": 2, "vision": 1, "robotics": 0}, q.Counts)
+
+	q = summarizeQuestion(FormField{Name: "wish", Type: FormFieldText}, responses)
+	assert.Equal(t, []string{"More food"}, q.Answers)
+
+	// the question was a text question when it was answered
+	q = summarizeQuestion(FormField{Name: "wish", Type: FormFieldNumber}, responses)
+	assert.Zero(t, q.Responses)
+	assert.Nil(t, q.Average)
+}
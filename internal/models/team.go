@@ -0,0 +1,96 @@
+package models
+
+import (
+	"crypto/rand"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Team groups registrations to an event, e.g. for a hackathon. Members join
+// with the invite code of the team. Each member still has a registration of
+// their own, but events can count capacity in teams instead of people.
+type Team struct {
+	ID         uint           `gorm:"primarykey" json:"id"`
+	EventID    uint           `gorm:"not null;index" json:"event_id"`
+	Name       string         `gorm:"not null" json:"name"`
+	InviteCode string         `gorm:"not null;uniqueIndex" json:"invite_code,omitempty"`
+	CreatedBy  uint           `json:"created_by"`
+	Members    []TeamMate     `gorm:"-" json:"members,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TeamMate is a member of a team as shown to the other members
+type TeamMate struct {
+	RegistrationID uint               `json:"registration_id"`
+	UserID         uint               `json:"user_id"`
+	FirstName      string             `json:"first_name"`
+	LastName       string             `json:"last_name"`
+	Status         RegistrationStatus `json:"status"`
+}
+
+// no 0/O or 1/I, codes are read out loud and typed in by hand
+const inviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// NewInviteCode returns a random code to join a team with
+func NewInviteCode() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	for i := range b {
+		b[i] = inviteCodeAlphabet[int(b[i])%len(inviteCodeAlphabet)]
+	}
+	return string(b)
+}
+
+// TeamSize returns the number of registrations in a team. Cancelled ones
+// left it.
+func TeamSize(db *gorm.DB, teamID uint) (int, error) {
+	var count int64
+	err := db.Model(&Registration{}).Where("team_id = ? AND cancelled_at IS NULL", teamID).Count(&count).Error
+	return int(count), err
+}
+
+// DeleteTeamIfEmpty deletes a team once its last member left
+func DeleteTeamIfEmpty(db *gorm.DB, teamID uint) error {
+	size, err := TeamSize(db, teamID)
+	if err != nil || size > 0 {
+		return err
+	}
+	return db.Delete(&Team{}, teamID).Error
+}
+
+// LoadTeamMates fills in the members of teams, in the order they registered
+func LoadTeamMates(db *gorm.DB, teams ...*Team) error {
+	if len(teams) == 0 {
+		return nil
+	}
+	byID := make(map[uint]*Team, len(teams))
+	ids := make([]uint, 0, len(teams))
+	for _, t := range teams {
+		t.Members = nil
+		byID[t.ID] = t
+		ids = append(ids, t.ID)
+	}
+
+	var rows []struct {
+		TeamMate
+		TeamID uint
+	}
+	err := db.Model(&Registration{}).
+		Select(`registrations.team_id, registrations.id AS registration_id, registrations.user_id, registrations.status,
+			COALESCE(profiles.first_name, '') AS first_name, COALESCE(profiles.last_name, '') AS last_name`).
+		Joins("JOIN users ON users.id = registrations.user_id").
+		Joins("LEFT JOIN profiles ON profiles.user_id = users.user_id AND profiles.deleted_at IS NULL").
+		Where("registrations.team_id IN ?", ids).
+		Order("registrations.created_at ASC, registrations.id ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		byID[row.TeamID].Members = append(byID[row.TeamID].Members, row.TeamMate)
+	}
+	return nil
+}
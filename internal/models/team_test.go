@@ -0,0 +1,80 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTeams(t *testing.T) {
+	closes := time.Now()
+	assert.NoError(t, (&Event{}).ValidateRegistration())
+	assert.NoError(t, (&Event{TeamMinSize: 2, TeamMaxSize: 4, CapacityCountsTeams: true}).ValidateRegistration())
+
+	for name, event := range map[string]Event{
+		"negative size":        {TeamMaxSize: -1},
+		"min without max":      {TeamMinSize: 2},
+		"teams without max":    {CapacityCountsTeams: true},
+		"min larger than max":  {TeamMinSize: 5, TeamMaxSize: 4},
+		"lottery counts teams": {TeamMaxSize: 4, CapacityCountsTeams: true, SelectionMode: SelectionLottery, ApplicationsClose: &closes},
+	} {
+		assert.Error(t, event.ValidateRegistration(), name)
+	}
+}
+
+func TestInviteCode(t *testing.T) {
+	code := NewInviteCode()
+	assert.Len(t, code, 8)
+	for _, r := range code {
+		assert.Contains(t, inviteCodeAlphabet, string(r))
+	}
+	assert.NotEqual(t, code, NewInviteCode())
+}
+
+func TestTeamsShareSpots(t *testing.T) {
+	db := newWaitlistDB(t)
+	require.NoError(t, db.AutoMigrate(&Team{}))
+	event := Event{Title: "Hackathon", RegistrationMax: 2, TeamMaxSize: 3, CapacityCountsTeams: true}
+	require.NoError(t, db.Create(&event).Error)
+	team := Team{EventID: event.ID, Name: "Transformers", InviteCode: NewInviteCode()}
+	require.NoError(t, db.Create(&team).Error)
+
+	regs := seedRegistrations(t, db, event,
+		RegistrationStatusApproved, RegistrationStatusApproved, // a team
+		RegistrationStatusWaitlisted,                               // on their own
+		RegistrationStatusWaitlisted, RegistrationStatusWaitlisted, // another team
+		RegistrationStatusWaitlisted, // on their own
+	)
+	other := Team{EventID: event.ID, Name: "Diffusers", InviteCode: NewInviteCode()}
+	require.NoError(t, db.Create(&other).Error)
+	for i, teamID := range map[int]uint{0: team.ID, 1: team.ID, 3: other.ID, 4: other.ID} {
+		require.NoError(t, db.Model(&regs[i]).Update("team_id", teamID).Error)
+	}
+
+	held, err := CountHeldSpots(db, event)
+	require.NoError(t, err)
+	assert.Equal(t, 1, held)
+	size, err := TeamSize(db, team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, size)
+
+	// one spot is left, it goes to the first in line
+	promoted, err := PromoteFromWaitlist(db, event, time.Now())
+	require.NoError(t, err)
+	require.Len(t, promoted, 1)
+	assert.Equal(t, regs[2].ID, promoted[0].ID)
+
+	// freeing it promotes the other team as a whole
+	require.NoError(t, db.Model(&regs[2]).Update("status", RegistrationStatusRejected).Error)
+	promoted, err = PromoteFromWaitlist(db, event, time.Now())
+	require.NoError(t, err)
+	require.Len(t, promoted, 2)
+	assert.ElementsMatch(t, []uint{regs[3].ID, regs[4].ID}, []uint{promoted[0].ID, promoted[1].ID})
+	assert.Equal(t, RegistrationStatusWaitlisted, statusOf(t, db, regs[5].ID).Status)
+
+	held, err = CountHeldSpots(db, event)
+	require.NoError(t, err)
+	assert.Equal(t, 2, held)
+}
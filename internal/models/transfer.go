@@ -0,0 +1,123 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TransferStatus string
+
+const (
+	TransferPending   TransferStatus = "pending"
+	TransferAccepted  TransferStatus = "accepted"
+	TransferCancelled TransferStatus = "cancelled" // withdrawn by the holder or replaced by a newer transfer
+)
+
+var (
+	// ErrTransferClosed is returned when accepting a transfer that was
+	// cancelled, already accepted, or whose registration changed since
+	ErrTransferClosed = errors.New("transfer is no longer open")
+	// ErrRecipientRegistered is returned when the recipient already holds a
+	// spot at the event
+	ErrRecipientRegistered = errors.New("recipient is already registered")
+)
+
+// RegistrationTransfer hands an approved registration over to another member.
+// The recipient accepts with the token emailed to them, only its hash is
+// stored.
+type RegistrationTransfer struct {
+	ID             uint           `gorm:"primarykey" json:"id"`
+	RegistrationID uint           `gorm:"not null;index" json:"registration_id"`
+	EventID        uint           `gorm:"not null" json:"event_id"`
+	FromUserID     uint           `gorm:"not null" json:"from_user_id"`
+	ToUserID       uint           `gorm:"not null;index" json:"to_user_id"`
+	TokenHash      string         `gorm:"not null;uniqueIndex" json:"-"`
+	Status         TransferStatus `gorm:"not null;default:'pending'" json:"status"`
+	CreatedAt      time.Time      `json:"created_at"`
+	AcceptedAt     *time.Time     `json:"accepted_at,omitempty"`
+}
+
+// CreateTransfer offers a registration to another user and returns the token
+// they accept it with. Earlier open transfers of the registration are
+// cancelled, there is only ever one.
+func CreateTransfer(db *gorm.DB, registration Registration, toUserID uint) (RegistrationTransfer, string, error) {
+	token, hash := newToken()
+	transfer := RegistrationTransfer{
+		RegistrationID: registration.ID,
+		EventID:        registration.EventID,
+		FromUserID:     registration.UserID,
+		ToUserID:       toUserID,
+		TokenHash:      hash,
+		Status:         TransferPending,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := CancelTransfers(tx, registration.ID); err != nil {
+			return err
+		}
+		return tx.Create(&transfer).Error
+	})
+	return transfer, token, err
+}
+
+// CancelTransfers cancels the open transfers of a registration
+func CancelTransfers(db *gorm.DB, registrationID uint) error {
+	return db.Model(&RegistrationTransfer{}).
+		Where("registration_id = ? AND status = ?", registrationID, TransferPending).
+		Update("status", TransferCancelled).Error
+}
+
+// FindTransfer returns the transfer a token belongs to
+func FindTransfer(db *gorm.DB, token string) (RegistrationTransfer, error) {
+	var transfer RegistrationTransfer
+	err := db.Where("token_hash = ?", hashToken(token)).First(&transfer).Error
+	return transfer, err
+}
+
+// Accept moves the registration over to the recipient, with their own dietary
+// restrictions and answers and whether the no-show policy of the event
+// deprioritizes them. A registration of the recipient that doesn't hold
+// a spot, e.g. on the waitlist, is replaced. The ticket of the old holder is
+// revoked: the returned registration has none, the caller issues a new one
+// within the same transaction. Run it in a transaction, so the transfer and
+// the move happen together or not at all.
+func (t *RegistrationTransfer) Accept(tx *gorm.DB, dietaryRestrictions string, answers FormAnswers, deprioritized bool, now time.Time) (Registration, error) {
+	var registration Registration
+	result := tx.Model(&RegistrationTransfer{}).Where("id = ? AND status = ?", t.ID, TransferPending).
+		Updates(map[string]any{"status": TransferAccepted, "accepted_at": now})
+	if result.Error != nil {
+		return registration, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return registration, ErrTransferClosed
+	}
+
+	var existing Registration
+	err := tx.Where("event_id = ? AND user_id = ?", t.EventID, t.ToUserID).First(&existing).Error
+	switch {
+	case err == nil && existing.Status.HoldsSpot():
+		return registration, ErrRecipientRegistered
+	case err == nil:
+		if err := tx.Delete(&existing).Error; err != nil {
+			return registration, err
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return registration, err
+	}
+
+	// the registration must still be the approved one the transfer was made for
+	result = tx.Model(&Registration{}).
+		Where("id = ? AND user_id = ? AND status = ?", t.RegistrationID, t.FromUserID, RegistrationStatusApproved).
+		Select("UserID", "TicketNonce", "TeamID", "DietaryRestrictions", "Answers", "Attended", "CheckedInAt", "Deprioritized").
+		Updates(&Registration{UserID: t.ToUserID, DietaryRestrictions: dietaryRestrictions, Answers: answers, Deprioritized: deprioritized})
+	if result.Error != nil {
+		return registration, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return registration, ErrTransferClosed
+	}
+	t.Status, t.AcceptedAt = TransferAccepted, &now
+	err = tx.First(&registration, t.RegistrationID).Error
+	return registration, err
+}
@@ -7,6 +7,12 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	RoleUser      = "user"
+	RoleOrganizer = "organizer" // can create events and manage the ones they organize
+	RoleAdmin     = "admin"
+)
+
 type User struct {
 	gorm.Model
 	UserId    uuid.UUID `gorm:"uniqueIndex" json:"user_id"`
@@ -0,0 +1,160 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LockEvent loads an event and locks its row until the end of the
+// transaction, so that spots are handed out one transaction at a time
+func LockEvent(tx *gorm.DB, id uint) (Event, error) {
+	var event Event
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, id).Error
+	return event, err
+}
+
+// CountHeldSpots returns the number of spots taken at an event. If the event
+// counts capacity in teams, all members of a team share one spot.
+func CountHeldSpots(db *gorm.DB, event Event) (int, error) {
+	held := db.Model(&Registration{}).Where("event_id = ? AND status IN ?", event.ID, SpotHoldingStatuses)
+	var count int64
+	if !event.CapacityCountsTeams {
+		err := held.Count(&count).Error
+		return int(count), err
+	}
+
+	var teams int64
+	if err := held.Session(&gorm.Session{}).Where("team_id IS NULL").Count(&count).Error; err != nil {
+		return 0, err
+	}
+	err := held.Where("team_id IS NOT NULL").Distinct("team_id").Count(&teams).Error
+	return int(count + teams), err
+}
+
+// IsFull reports whether an event with limited capacity has no spots left
+func IsFull(db *gorm.DB, event Event) (bool, error) {
+	if event.RegistrationMax <= 0 {
+		return false, nil
+	}
+	held, err := CountHeldSpots(db, event)
+	return held >= event.RegistrationMax, err
+}
+
+// PromoteFromWaitlist fills the free spots of an event with the registrations
+// that have been on the waitlist the longest, or got the best places in the
+// lottery. Events with an offer window get offers that must be confirmed,
+// otherwise registrations go straight to pending. If the event counts
+// capacity in teams, a team is promoted as a whole once its first member is.
+// Returns the promoted registrations.
+func PromoteFromWaitlist(tx *gorm.DB, event Event, now time.Time) ([]Registration, error) {
+	if event.RegistrationMax <= 0 || event.RegistrationsLocked() {
+		return nil, nil
+	}
+	held, err := CountHeldSpots(tx, event)
+	if err != nil {
+		return nil, err
+	}
+	free := event.RegistrationMax - held
+	if free <= 0 {
+		return nil, nil
+	}
+
+	var waitlisted []Registration
+	query := tx.Where("event_id = ? AND status = ?", event.ID, RegistrationStatusWaitlisted).
+		Order("deprioritized ASC, lottery_rank ASC, created_at ASC, id ASC")
+	if !event.CapacityCountsTeams {
+		query = query.Limit(free)
+	}
+	if err := query.Find(&waitlisted).Error; err != nil {
+		return nil, err
+	}
+
+	// teams that already hold a spot take in their waitlisted members for free
+	seated := map[uint]bool{}
+	if event.CapacityCountsTeams {
+		var teamIDs []uint
+		if err := tx.Model(&Registration{}).
+			Where("event_id = ? AND status IN ? AND team_id IS NOT NULL", event.ID, SpotHoldingStatuses).
+			Distinct().Pluck("team_id", &teamIDs).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range teamIDs {
+			seated[id] = true
+		}
+	}
+
+	var promoted []Registration
+	for _, r := range waitlisted {
+		inTeam := event.CapacityCountsTeams && r.TeamID != nil
+		if !inTeam || !seated[*r.TeamID] {
+			if free == 0 {
+				continue
+			}
+			free--
+			if inTeam {
+				seated[*r.TeamID] = true
+			}
+		}
+
+		if event.OfferWindowHours > 0 {
+			expires := now.Add(time.Duration(event.OfferWindowHours) * time.Hour)
+			r.Status = RegistrationStatusOffered
+			r.OfferExpiresAt = &expires
+		} else {
+			r.Status = RegistrationStatusPending
+		}
+		if err := tx.Save(&r).Error; err != nil {
+			return nil, err
+		}
+		promoted = append(promoted, r)
+	}
+	return promoted, nil
+}
+
+// ExpireOffers rejects waitlist offers that were not confirmed in time and
+// passes the spots on. Returns the newly promoted registrations.
+func ExpireOffers(tx *gorm.DB, now time.Time) ([]Registration, error) {
+	var expired []Registration
+	if err := tx.Where("status = ? AND offer_expires_at < ?", RegistrationStatusOffered, now).
+		Find(&expired).Error; err != nil {
+		return nil, err
+	}
+
+	eventIDs := map[uint]bool{}
+	for _, r := range expired {
+		if err := tx.Model(&r).Updates(map[string]any{
+			"status":           RegistrationStatusRejected,
+			"offer_expires_at": nil,
+		}).Error; err != nil {
+			return nil, err
+		}
+		eventIDs[r.EventID] = true
+	}
+
+	var promoted []Registration
+	for eventID := range eventIDs {
+		event, err := LockEvent(tx, eventID)
+		if err != nil {
+			return nil, err
+		}
+		p, err := PromoteFromWaitlist(tx, event, now)
+		if err != nil {
+			return nil, err
+		}
+		promoted = append(promoted, p...)
+	}
+	return promoted, nil
+}
+
+// WaitlistPosition returns the 1-based position of a waitlisted registration
+func WaitlistPosition(db *gorm.DB, r Registration) (int, error) {
+	var ahead int64
+	err := db.Model(&Registration{}).
+		Where("event_id = ? AND status = ?", r.EventID, RegistrationStatusWaitlisted).
+		Where("deprioritized < ? OR (deprioritized = ? AND (lottery_rank < ? OR (lottery_rank = ? AND (created_at < ? OR (created_at = ? AND id < ?)))))",
+			r.Deprioritized, r.Deprioritized, r.LotteryRank, r.LotteryRank, r.CreatedAt, r.CreatedAt, r.ID).
+		Count(&ahead).Error
+	return int(ahead) + 1, err
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newWaitlistDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&User{}, &Event{}, &Registration{}))
+	return db
+}
+
+// seedRegistrations creates one registration per status, in order, one minute apart
+func seedRegistrations(t *testing.T, db *gorm.DB, event Event, statuses ...RegistrationStatus) []Registration {
+	t.Helper()
+	base := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	var regs []Registration
+	for i, status := range statuses {
+		r := Registration{
+			EventID:   event.ID,
+			UserID:    uint(i + 1),
+			Status:    status,
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		}
+		require.NoError(t, db.Create(&r).Error)
+		regs = append(regs, r)
+	}
+	return regs
+}
+
+func statusOf(t *testing.T, db *gorm.DB, id uint) Registration {
+	t.Helper()
+	var r Registration
+	require.NoError(t, db.First(&r, id).Error)
+	return r
+}
+
+func TestPromoteFromWaitlistIsFIFO(t *testing.T) {
+	db := newWaitlistDB(t)
+	event := Event{Title: "Workshop", RegistrationMax: 2}
+	require.NoError(t, db.Create(&event).Error)
+	regs := seedRegistrations(t, db, event,
+		RegistrationStatusApproved,
+		RegistrationStatusRejected, // freed spot
+		RegistrationStatusWaitlisted,
+		RegistrationStatusWaitlisted,
+	)
+
+	promoted, err := PromoteFromWaitlist(db, event, time.Now())
+	require.NoError(t, err)
+	require.Len(t, promoted, 1)
+	assert.Equal(t, regs[2].ID, promoted[0].ID)
+	assert.Equal(t, RegistrationStatusPending, statusOf(t, db, regs[2].ID).Status)
+	assert.Equal(t, RegistrationStatusWaitlisted, statusOf(t, db, regs[3].ID).Status)
+
+	// no free spots left
+	promoted, err = PromoteFromWaitlist(db, event, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, promoted)
+}
+
+func TestPromoteFromWaitlistWithOfferWindow(t *testing.T) {
+	db := newWaitlistDB(t)
+	event := Event{Title: "Workshop", RegistrationMax: 1, OfferWindowHours: 24}
+	require.NoError(t, db.Create(&event).Error)
+	regs := seedRegistrations(t, db, event, RegistrationStatusWaitlisted)
+
+	now := time.Date(2025, 10, 2, 12, 0, 0, 0, time.UTC)
+	_, err := PromoteFromWaitlist(db, event, now)
+	require.NoError(t, err)
+
+	r := statusOf(t, db, regs[0].ID)
+	assert.Equal(t, RegistrationStatusOffered, r.Status)
+	require.NotNil(t, r.OfferExpiresAt)
+	assert.True(t, r.OfferExpiresAt.Equal(now.Add(24*time.Hour)))
+}
+
+func TestExpireOffersMovesToNext(t *testing.T) {
+	db := newWaitlistDB(t)
+	event := Event{Title: "Workshop", RegistrationMax: 1, OfferWindowHours: 2}
+	require.NoError(t, db.Create(&event).Error)
+	regs := seedRegistrations(t, db, event, RegistrationStatusWaitlisted, RegistrationStatusWaitlisted)
+
+	now := time.Date(2025, 10, 2, 12, 0, 0, 0, time.UTC)
+	_, err := PromoteFromWaitlist(db, event, now)
+	require.NoError(t, err)
+
+	// still within the window
+	promoted, err := ExpireOffers(db, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, promoted)
+
+	promoted, err = ExpireOffers(db, now.Add(3*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, promoted, 1)
+	assert.Equal(t, regs[1].ID, promoted[0].ID)
+	assert.Equal(t, RegistrationStatusRejected, statusOf(t, db, regs[0].ID).Status)
+	assert.Equal(t, RegistrationStatusOffered, statusOf(t, db, regs[1].ID).Status)
+}
+
+func TestUnlimitedEventHasNoWaitlist(t *testing.T) {
+	db := newWaitlistDB(t)
+	event := Event{Title: "Lecture"}
+	require.NoError(t, db.Create(&event).Error)
+	seedRegistrations(t, db, event, RegistrationStatusApproved, RegistrationStatusApproved)
+
+	full, err := IsFull(db, event)
+	require.NoError(t, err)
+	assert.False(t, full)
+}
+
+func TestWaitlistPosition(t *testing.T) {
+	db := newWaitlistDB(t)
+	event := Event{Title: "Workshop", RegistrationMax: 1}
+	require.NoError(t, db.Create(&event).Error)
+	regs := seedRegistrations(t, db, event,
+		RegistrationStatusApproved,
+		RegistrationStatusWaitlisted,
+		RegistrationStatusRejected,
+		RegistrationStatusWaitlisted,
+	)
+
+	full, err := IsFull(db, event)
+	require.NoError(t, err)
+	assert.True(t, full)
+
+	for i, want := range map[int]int{1: 1, 3: 2} {
+		got, err := WaitlistPosition(db, regs[i])
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+}
+
+func TestNoPromotionForLockedEvents(t *testing.T) {
+	db := newWaitlistDB(t)
+	event := Event{Title: "Workshop", RegistrationMax: 1, Status: EventStatusCancelled}
+	require.NoError(t, db.Create(&event).Error)
+	regs := seedRegistrations(t, db, event, RegistrationStatusWaitlisted)
+
+	promoted, err := PromoteFromWaitlist(db, event, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, promoted)
+	assert.Equal(t, RegistrationStatusWaitlisted, statusOf(t, db, regs[0].ID).Status)
+}
@@ -0,0 +1,157 @@
+// Package refresh issues the opaque refresh tokens that keep users logged in
+// while their JWTs are short-lived. Tokens are rotated on every use and belong
+// to a family, one per login. Using a token that was already rotated means it
+// was stolen, so the whole family is revoked and the user has to log in again.
+package refresh
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TTL is how long a refresh token can be used. Every refresh extends the
+// login by as much.
+const TTL = 30 * 24 * time.Hour
+
+var (
+	// ErrInvalidToken is returned for tokens that are unknown, expired or
+	// whose family was revoked
+	ErrInvalidToken = errors.New("invalid refresh token")
+	// ErrTokenReused is returned for tokens that were already rotated, their
+	// family is revoked
+	ErrTokenReused = errors.New("refresh token reused")
+)
+
+// record is stored under the hash of every token
+type record struct {
+	Family string    `json:"family"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// Manager issues, rotates and revokes refresh tokens. Only hashes of the
+// tokens are stored: a token points to its family, the family to its latest
+// token.
+type Manager struct {
+	store Store
+	ttl   time.Duration
+}
+
+func NewManager(store Store, ttl time.Duration) *Manager {
+	return &Manager{store: store, ttl: ttl}
+}
+
+// Issue returns the token of a new family for a user who just logged in
+func (m *Manager) Issue(ctx context.Context, userID uuid.UUID) (string, error) {
+	family := uuid.New().String()
+	token, hash := newToken()
+	if err := m.save(ctx, hash, record{Family: family, UserID: userID}); err != nil {
+		return "", err
+	}
+	if err := m.store.Set(ctx, familyKey(family), hash, m.ttl); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Rotate exchanges a token for the next one of its family and returns it
+// along with the user it belongs to. The user is also returned with
+// ErrTokenReused, so the theft can be looked into.
+func (m *Manager) Rotate(ctx context.Context, token string) (string, uuid.UUID, error) {
+	hash := hashToken(token)
+	rec, err := m.load(ctx, hash)
+	if err != nil {
+		return "", uuid.Nil, err
+	}
+	latest, err := m.store.Get(ctx, familyKey(rec.Family))
+	if errors.Is(err, ErrNotFound) {
+		return "", uuid.Nil, ErrInvalidToken
+	}
+	if err != nil {
+		return "", uuid.Nil, err
+	}
+
+	next, nextHash := newToken()
+	if err := m.save(ctx, nextHash, rec); err != nil {
+		return "", uuid.Nil, err
+	}
+	// two refreshes with the same token can't both win
+	swapped := false
+	if latest == hash {
+		if swapped, err = m.store.CompareAndSwap(ctx, familyKey(rec.Family), hash, nextHash, m.ttl); err != nil {
+			return "", uuid.Nil, err
+		}
+	}
+	if !swapped {
+		// the old tokens are kept until they expire for exactly this
+		err := m.store.Delete(ctx, familyKey(rec.Family), tokenKey(nextHash))
+		return "", rec.UserID, errors.Join(ErrTokenReused, err)
+	}
+	return next, rec.UserID, nil
+}
+
+// Revoke ends the login a token belongs to, e.g. on logout. Unknown tokens
+// are ignored.
+func (m *Manager) Revoke(ctx context.Context, token string) error {
+	rec, err := m.load(ctx, hashToken(token))
+	if errors.Is(err, ErrInvalidToken) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return m.store.Delete(ctx, familyKey(rec.Family))
+}
+
+func (m *Manager) save(ctx context.Context, hash string, rec record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return m.store.Set(ctx, tokenKey(hash), string(data), m.ttl)
+}
+
+func (m *Manager) load(ctx context.Context, hash string) (record, error) {
+	var rec record
+	data, err := m.store.Get(ctx, tokenKey(hash))
+	if errors.Is(err, ErrNotFound) {
+		return rec, ErrInvalidToken
+	}
+	if err != nil {
+		return rec, err
+	}
+	if err := json.Unmarshal([]byte(data), &rec); err != nil {
+		return rec, fmt.Errorf("corrupt refresh token record: %w", err)
+	}
+	return rec, nil
+}
+
+func tokenKey(hash string) string {
+	return "refresh:token:" + hash
+}
+
+func familyKey(family string) string {
+	return "refresh:family:" + family
+}
+
+func newToken() (string, string) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to read random bytes: %v", err))
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,79 @@
+package refresh
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotate(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(NewMemoryStore(), TTL)
+	userID := uuid.New()
+
+	first, err := m.Issue(ctx, userID)
+	require.NoError(t, err)
+	second, got, err := m.Rotate(ctx, first)
+	require.NoError(t, err)
+	assert.Equal(t, userID, got)
+	assert.NotEqual(t, first, second)
+	third, _, err := m.Rotate(ctx, second)
+	require.NoError(t, err)
+
+	// an old token shows up again: the family is revoked, its latest token too
+	_, _, err = m.Rotate(ctx, first)
+	assert.ErrorIs(t, err, ErrTokenReused)
+	_, _, err = m.Rotate(ctx, third)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// other logins of the user are left alone
+	other, err := m.Issue(ctx, userID)
+	require.NoError(t, err)
+	_, _, err = m.Rotate(ctx, other)
+	assert.NoError(t, err)
+
+	_, _, err = m.Rotate(ctx, "made-up")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestRevoke(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(NewMemoryStore(), TTL)
+
+	token, err := m.Issue(ctx, uuid.New())
+	require.NoError(t, err)
+	next, _, err := m.Rotate(ctx, token)
+	require.NoError(t, err)
+	// revoking with any token of the family logs out
+	require.NoError(t, m.Revoke(ctx, token))
+	_, _, err = m.Rotate(ctx, next)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	assert.NoError(t, m.Revoke(ctx, "made-up"))
+}
+
+func TestExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	m := NewManager(store, time.Hour)
+
+	token, err := m.Issue(ctx, uuid.New())
+	require.NoError(t, err)
+	// every refresh extends the login
+	now = now.Add(50 * time.Minute)
+	token, _, err = m.Rotate(ctx, token)
+	require.NoError(t, err)
+	now = now.Add(50 * time.Minute)
+	token, _, err = m.Rotate(ctx, token)
+	require.NoError(t, err)
+
+	now = now.Add(time.Hour)
+	_, _, err = m.Rotate(ctx, token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
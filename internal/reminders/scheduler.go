@@ -0,0 +1,152 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/email"
+	"backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Scheduler emails approved registrants at configured offsets before their
+// events start. Sent reminders are recorded in the database, so restarts and
+// other replicas never send the same reminder twice.
+type Scheduler struct {
+	db          *gorm.DB
+	offsets     []time.Duration // sorted, shortest first
+	frontendURL string
+
+	// swapped out in tests
+	now  func() time.Time
+	send func(profile models.Profile, event models.Event, eventURL string) error
+}
+
+func NewScheduler(db *gorm.DB, cfg *config.Config) *Scheduler {
+	offsets := slices.Clone(cfg.ReminderOffsets)
+	slices.Sort(offsets)
+	return &Scheduler{
+		db:          db,
+		offsets:     slices.Compact(offsets),
+		frontendURL: cfg.FrontendURL,
+		now:         time.Now,
+		send:        email.SendEventReminderEmail,
+	}
+}
+
+// Start sends due reminders every interval until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context, interval time.Duration) {
+	if len(s.offsets) == 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.SendDue(); err != nil {
+					log.Printf("Failed to send event reminders: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// SendDue sends the reminders that are due and returns how many were sent,
+// along with the errors of the events whose reminders couldn't all be sent.
+// If several reminders of an event are due at once, e.g. after downtime, only
+// the one closest to the start is sent.
+func (s *Scheduler) SendDue() (int, error) {
+	if len(s.offsets) == 0 {
+		return 0, nil
+	}
+	now := s.now()
+
+	var events []models.Event
+	if err := s.db.Where("status = ? AND start_date > ? AND start_date <= ?",
+		models.EventStatusPublished, now, now.Add(s.offsets[len(s.offsets)-1])).
+		Find(&events).Error; err != nil {
+		return 0, err
+	}
+
+	// one failing event doesn't hold up the reminders of the others
+	sent := 0
+	var errs []error
+	for _, event := range events {
+		n, err := s.remind(event, s.dueOffset(event, now), now)
+		sent += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("event %d: %w", event.ID, err))
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// dueOffset returns the shortest offset whose reminder time has passed
+func (s *Scheduler) dueOffset(event models.Event, now time.Time) time.Duration {
+	for _, offset := range s.offsets {
+		if !now.Before(event.StartDate.Add(-offset)) {
+			return offset
+		}
+	}
+	return s.offsets[len(s.offsets)-1]
+}
+
+// remind sends the reminder at offset to the approved registrants of an
+// event that haven't got it yet
+func (s *Scheduler) remind(event models.Event, offset time.Duration, now time.Time) (int, error) {
+	// people who registered after the reminder was due just got their confirmation
+	var registrations []models.Registration
+	if err := s.db.Where("event_id = ? AND status = ? AND created_at <= ?",
+		event.ID, models.RegistrationStatusApproved, event.StartDate.Add(-offset)).
+		Find(&registrations).Error; err != nil {
+		return 0, err
+	}
+	if len(registrations) == 0 {
+		return 0, nil
+	}
+
+	userIDs := make([]uint, len(registrations))
+	for i, r := range registrations {
+		userIDs[i] = r.UserID
+	}
+	profiles, err := models.ProfilesForUsers(s.db, userIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	eventURL := fmt.Sprintf("%s/events/%d", s.frontendURL, event.ID)
+	sent := 0
+	for _, r := range registrations {
+		profile, ok := profiles[r.UserID]
+		if !ok {
+			continue
+		}
+		// claim before sending, if another replica got here first it's theirs
+		claimed, err := models.ClaimReminder(s.db, r.ID, offset, now)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+		if err := s.send(profile, event, eventURL); err != nil {
+			log.Printf("Failed to send reminder to %s: %v", profile.Email, err)
+			if err := models.ReleaseReminder(s.db, r.ID, offset); err != nil {
+				return sent, err
+			}
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
@@ -0,0 +1,205 @@
+package reminders
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var start = time.Date(2025, 11, 3, 17, 0, 0, 0, time.UTC)
+
+// fakeMailer records reminders instead of sending them
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []string
+	fail bool
+}
+
+func (m *fakeMailer) send(profile models.Profile, event models.Event, eventURL string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fail {
+		return errors.New("mail server is down")
+	}
+	m.sent = append(m.sent, profile.Email+" "+eventURL)
+	return nil
+}
+
+// take returns the recorded reminders and clears them
+func (m *fakeMailer) take() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sent := m.sent
+	m.sent = nil
+	return sent
+}
+
+type fixture struct {
+	db     *gorm.DB
+	now    time.Time
+	mailer *fakeMailer
+	event  models.Event
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Profile{}, &models.Event{},
+		&models.Registration{}, &models.SentReminder{}, &models.SurveyResponse{}))
+
+	f := &fixture{db: db, now: start.Add(-48 * time.Hour), mailer: &fakeMailer{}}
+	f.event = models.Event{Title: "Workshop", StartDate: start, EndDate: start.Add(2 * time.Hour)}
+	require.NoError(t, db.Create(&f.event).Error)
+	return f
+}
+
+func (f *fixture) scheduler() *Scheduler {
+	cfg := &config.Config{FrontendURL: "https://kthais.com", ReminderOffsets: []time.Duration{time.Hour, 24 * time.Hour}}
+	s := NewScheduler(f.db, cfg)
+	s.now = func() time.Time { return f.now }
+	s.send = f.mailer.send
+	return s
+}
+
+func (f *fixture) register(t *testing.T, name string, status models.RegistrationStatus, at time.Time) {
+	t.Helper()
+	user := models.User{UserId: uuid.New(), Email: name + "@kthais.com"}
+	require.NoError(t, f.db.Omit("Roles").Create(&user).Error)
+	require.NoError(t, f.db.Create(&models.Profile{UserID: user.UserId, Email: user.Email}).Error)
+	require.NoError(t, f.db.Create(&models.Registration{
+		EventID: f.event.ID, UserID: user.ID, Status: status, CreatedAt: at,
+	}).Error)
+}
+
+func (f *fixture) sendDue(t *testing.T, s *Scheduler, at time.Time) []string {
+	t.Helper()
+	f.now = at
+	_, err := s.SendDue()
+	require.NoError(t, err)
+	return f.mailer.take()
+}
+
+func TestRemindersAreSentAtOffsets(t *testing.T) {
+	f := newFixture(t)
+	f.register(t, "ada", models.RegistrationStatusApproved, f.now)
+	f.register(t, "pending", models.RegistrationStatusPending, f.now)
+	f.register(t, "rejected", models.RegistrationStatusRejected, f.now)
+	s := f.scheduler()
+	want := []string{"ada@kthais.com https://kthais.com/events/1"}
+
+	assert.Empty(t, f.sendDue(t, s, start.Add(-25*time.Hour)))
+	assert.Equal(t, want, f.sendDue(t, s, start.Add(-24*time.Hour)))
+	assert.Empty(t, f.sendDue(t, s, start.Add(-23*time.Hour)), "already sent")
+	assert.Equal(t, want, f.sendDue(t, s, start.Add(-59*time.Minute)))
+	assert.Empty(t, f.sendDue(t, s, start.Add(-30*time.Minute)), "already sent")
+	assert.Empty(t, f.sendDue(t, s, start.Add(time.Minute)), "event has started")
+}
+
+func TestRestartDoesNotResend(t *testing.T) {
+	f := newFixture(t)
+	f.register(t, "ada", models.RegistrationStatusApproved, f.now)
+
+	assert.Len(t, f.sendDue(t, f.scheduler(), start.Add(-20*time.Hour)), 1)
+	assert.Empty(t, f.sendDue(t, f.scheduler(), start.Add(-20*time.Hour)))
+}
+
+func TestOnlyClosestReminderAfterDowntime(t *testing.T) {
+	f := newFixture(t)
+	f.register(t, "ada", models.RegistrationStatusApproved, f.now)
+	s := f.scheduler()
+
+	assert.Len(t, f.sendDue(t, s, start.Add(-30*time.Minute)), 1)
+	var claims []models.SentReminder
+	require.NoError(t, f.db.Find(&claims).Error)
+	require.Len(t, claims, 1)
+	assert.Equal(t, 60, claims[0].OffsetMinutes)
+}
+
+func TestLateRegistrationsSkipPassedReminders(t *testing.T) {
+	f := newFixture(t)
+	f.register(t, "late", models.RegistrationStatusApproved, start.Add(-12*time.Hour))
+	s := f.scheduler()
+
+	assert.Empty(t, f.sendDue(t, s, start.Add(-11*time.Hour)))
+	assert.Len(t, f.sendDue(t, s, start.Add(-time.Hour)), 1)
+}
+
+func TestNoRemindersForUnpublishedEvents(t *testing.T) {
+	f := newFixture(t)
+	f.register(t, "ada", models.RegistrationStatusApproved, f.now)
+	require.NoError(t, f.db.Model(&f.event).Update("status", models.EventStatusCancelled).Error)
+
+	assert.Empty(t, f.sendDue(t, f.scheduler(), start.Add(-time.Hour)))
+}
+
+func TestFailedReminderIsRetried(t *testing.T) {
+	f := newFixture(t)
+	f.register(t, "ada", models.RegistrationStatusApproved, f.now)
+	s := f.scheduler()
+
+	f.mailer.fail = true
+	assert.Empty(t, f.sendDue(t, s, start.Add(-24*time.Hour)))
+	f.mailer.fail = false
+	assert.Len(t, f.sendDue(t, s, start.Add(-23*time.Hour)), 1)
+}
+
+func TestFailingEventDoesNotHoldUpReminders(t *testing.T) {
+	f := newFixture(t)
+	f.register(t, "ada", models.RegistrationStatusApproved, f.now)
+	later := models.Event{Title: "Lecture", StartDate: start.Add(time.Hour), EndDate: start.Add(2 * time.Hour)}
+	require.NoError(t, f.db.Create(&later).Error)
+	var ada models.User
+	require.NoError(t, f.db.Where("email = ?", "ada@kthais.com").First(&ada).Error)
+	require.NoError(t, f.db.Create(&models.Registration{EventID: later.ID, UserID: ada.ID,
+		Status: models.RegistrationStatusApproved, CreatedAt: f.now}).Error)
+
+	// claiming reminders of the first event fails
+	var failing models.Registration
+	require.NoError(t, f.db.Where("event_id = ?", f.event.ID).First(&failing).Error)
+	require.NoError(t, f.db.Callback().Create().Before("gorm:create").Register("fail_claims", func(tx *gorm.DB) {
+		if claim, ok := tx.Statement.Dest.(*models.SentReminder); ok && claim.RegistrationID == failing.ID {
+			tx.AddError(errors.New("disk full"))
+		}
+	}))
+
+	f.now = start.Add(-time.Hour)
+	sent, err := f.scheduler().SendDue()
+	assert.ErrorContains(t, err, "disk full")
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, []string{"ada@kthais.com https://kthais.com/events/2"}, f.mailer.take())
+}
+
+func TestReplicasDoNotDoubleSend(t *testing.T) {
+	f := newFixture(t)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		f.register(t, name, models.RegistrationStatusApproved, f.now)
+	}
+	f.now = start.Add(-time.Hour)
+
+	var wg sync.WaitGroup
+	for range 4 {
+		s := f.scheduler()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.SendDue()
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, f.mailer.take(), 5)
+}
@@ -0,0 +1,131 @@
+package reminders
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/email"
+	"backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// surveyWindow is how long after an event ends attendees still get the
+// survey, e.g. when attendance is marked late. Older events are left alone.
+const surveyWindow = 7 * 24 * time.Hour
+
+// SurveySender emails attendees a link to the feedback survey once their
+// event has ended. Like reminders, sent surveys are recorded in the database
+// so every attendee gets exactly one link.
+type SurveySender struct {
+	db          *gorm.DB
+	delay       time.Duration
+	frontendURL string
+
+	// swapped out in tests
+	now  func() time.Time
+	send func(profile models.Profile, event models.Event, surveyURL string) error
+}
+
+func NewSurveySender(db *gorm.DB, cfg *config.Config) *SurveySender {
+	return &SurveySender{
+		db:          db,
+		delay:       cfg.SurveyDelay,
+		frontendURL: cfg.FrontendURL,
+		now:         time.Now,
+		send:        email.SendEventSurveyEmail,
+	}
+}
+
+// Start sends due surveys every interval until ctx is cancelled
+func (s *SurveySender) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.SendDue(); err != nil {
+					log.Printf("Failed to send event surveys: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// SendDue sends the surveys of events that ended at least the delay ago to
+// attendees who haven't got one yet, and returns how many were sent. An event
+// that fails is logged and tried again next time, the others still get theirs.
+func (s *SurveySender) SendDue() (int, error) {
+	now := s.now()
+	until := now.Add(-s.delay)
+
+	var events []models.Event
+	if err := s.db.Where("status IN ? AND end_date <= ? AND end_date > ?",
+		[]models.EventStatus{models.EventStatusPublished, models.EventStatusCompleted}, until, until.Add(-surveyWindow)).
+		Find(&events).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, event := range events {
+		n, err := s.survey(event, now)
+		sent += n
+		if err != nil {
+			log.Printf("Failed to send surveys for event %d: %v", event.ID, err)
+		}
+	}
+	return sent, nil
+}
+
+// survey sends the survey to the attendees of an event that haven't got it yet
+func (s *SurveySender) survey(event models.Event, now time.Time) (int, error) {
+	var registrations []models.Registration
+	if err := s.db.Where("event_id = ? AND attended AND NOT EXISTS (?)", event.ID,
+		s.db.Model(&models.SurveyResponse{}).Select("1").Where("survey_responses.registration_id = registrations.id")).
+		Find(&registrations).Error; err != nil {
+		return 0, err
+	}
+	if len(registrations) == 0 {
+		return 0, nil
+	}
+
+	userIDs := make([]uint, len(registrations))
+	for i, r := range registrations {
+		userIDs[i] = r.UserID
+	}
+	profiles, err := models.ProfilesForUsers(s.db, userIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, r := range registrations {
+		profile, ok := profiles[r.UserID]
+		if !ok {
+			continue
+		}
+		// claim before sending, if another replica got here first it's theirs
+		token, claimed, err := models.ClaimSurvey(s.db, r, now)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+		if err := s.send(profile, event, fmt.Sprintf("%s/surveys/%s", s.frontendURL, token)); err != nil {
+			log.Printf("Failed to send survey to %s: %v", profile.Email, err)
+			if err := models.ReleaseSurvey(s.db, r.ID); err != nil {
+				return sent, err
+			}
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
@@ -0,0 +1,112 @@
+package reminders
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func (f *fixture) surveySender() *SurveySender {
+	cfg := &config.Config{FrontendURL: "https://kthais.com", SurveyDelay: time.Hour}
+	s := NewSurveySender(f.db, cfg)
+	s.now = func() time.Time { return f.now }
+	s.send = f.mailer.send
+	return s
+}
+
+func (f *fixture) attend(t *testing.T, name string) {
+	t.Helper()
+	var user models.User
+	require.NoError(t, f.db.Where("email = ?", name+"@kthais.com").First(&user).Error)
+	require.NoError(t, f.db.Model(&models.Registration{}).Where("user_id = ?", user.ID).Update("attended", true).Error)
+}
+
+func (f *fixture) sendSurveys(t *testing.T, s *SurveySender, at time.Time) []string {
+	t.Helper()
+	f.now = at
+	_, err := s.SendDue()
+	require.NoError(t, err)
+	return f.mailer.take()
+}
+
+func TestSurveysAreSentToAttendees(t *testing.T) {
+	f := newFixture(t)
+	f.register(t, "ada", models.RegistrationStatusApproved, f.now)
+	f.register(t, "noshow", models.RegistrationStatusApproved, f.now)
+	f.attend(t, "ada")
+	s := f.surveySender()
+	end := f.event.EndDate
+
+	assert.Empty(t, f.sendSurveys(t, s, end.Add(59*time.Minute)), "before the delay")
+	sent := f.sendSurveys(t, s, end.Add(time.Hour))
+	require.Len(t, sent, 1)
+	url, ok := strings.CutPrefix(sent[0], "ada@kthais.com https://kthais.com/surveys/")
+	require.True(t, ok, sent[0])
+	response, err := models.FindSurvey(f.db, url)
+	require.NoError(t, err)
+	assert.Equal(t, f.event.ID, response.EventID)
+	assert.Empty(t, f.sendSurveys(t, f.surveySender(), end.Add(2*time.Hour)), "already sent")
+
+	// attendance marked the day after still gets a survey, a month later doesn't
+	f.register(t, "late", models.RegistrationStatusApproved, f.now)
+	f.attend(t, "late")
+	assert.Len(t, f.sendSurveys(t, s, end.Add(24*time.Hour)), 1)
+	f.register(t, "later", models.RegistrationStatusApproved, f.now)
+	f.attend(t, "later")
+	assert.Empty(t, f.sendSurveys(t, s, end.Add(30*24*time.Hour)))
+}
+
+func TestFailedSurveyIsRetried(t *testing.T) {
+	f := newFixture(t)
+	f.register(t, "ada", models.RegistrationStatusApproved, f.now)
+	f.attend(t, "ada")
+	s := f.surveySender()
+
+	f.mailer.fail = true
+	assert.Empty(t, f.sendSurveys(t, s, f.event.EndDate.Add(time.Hour)))
+	f.mailer.fail = false
+	assert.Len(t, f.sendSurveys(t, s, f.event.EndDate.Add(2*time.Hour)), 1)
+}
+
+func TestNoSurveysForCancelledEvents(t *testing.T) {
+	f := newFixture(t)
+	f.register(t, "ada", models.RegistrationStatusApproved, f.now)
+	f.attend(t, "ada")
+	require.NoError(t, f.db.Model(&f.event).Update("status", models.EventStatusCancelled).Error)
+
+	assert.Empty(t, f.sendSurveys(t, f.surveySender(), f.event.EndDate.Add(time.Hour)))
+}
+
+func TestFailingEventDoesNotHoldUpOthers(t *testing.T) {
+	f := newFixture(t)
+	f.register(t, "ada", models.RegistrationStatusApproved, f.now)
+	f.attend(t, "ada")
+	later := models.Event{Title: "Lecture", StartDate: start.Add(time.Hour), EndDate: start.Add(2 * time.Hour)}
+	require.NoError(t, f.db.Create(&later).Error)
+	var ada models.User
+	require.NoError(t, f.db.Where("email = ?", "ada@kthais.com").First(&ada).Error)
+	require.NoError(t, f.db.Create(&models.Registration{EventID: later.ID, UserID: ada.ID,
+		Status: models.RegistrationStatusApproved, Attended: true}).Error)
+
+	// claiming surveys of the first event fails
+	require.NoError(t, f.db.Callback().Create().Before("gorm:create").Register("fail_claims", func(tx *gorm.DB) {
+		if response, ok := tx.Statement.Dest.(*models.SurveyResponse); ok && response.EventID == f.event.ID {
+			tx.AddError(errors.New("disk full"))
+		}
+	}))
+
+	sent := f.sendSurveys(t, f.surveySender(), f.event.EndDate.Add(time.Hour))
+	assert.Len(t, sent, 1)
+	var responses []models.SurveyResponse
+	require.NoError(t, f.db.Find(&responses).Error)
+	require.Len(t, responses, 1)
+	assert.Equal(t, later.ID, responses[0].EventID)
+}
@@ -0,0 +1,52 @@
+package spreadsheet
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// this package writes tables as CSV or XLSX one row at a time, so exports of
+// large events go straight to the response instead of being built in memory.
+
+// Writer writes the rows of a table. Close must be called after the last row
+// to finish the file.
+type Writer interface {
+	Write(row []string) error
+	Close() error
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+// NewCSV returns a Writer for CSV. The output starts with a byte order mark,
+// without it Excel reads UTF-8 as Latin-1 and mangles names like Åsa.
+func NewCSV(w io.Writer) (Writer, error) {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return nil, err
+	}
+	return &csvWriter{w: csv.NewWriter(w)}, nil
+}
+
+func (c *csvWriter) Write(row []string) error {
+	escaped := make([]string, len(row))
+	for i, cell := range row {
+		escaped[i] = escapeFormula(cell)
+	}
+	return c.w.Write(escaped)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// escapeFormula keeps spreadsheet programs from evaluating a cell as a
+// formula, registrants control most of what ends up in an export
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
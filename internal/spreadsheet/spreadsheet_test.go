@@ -0,0 +1,92 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var table = [][]string{
+	{"Name", "Email", "Notes"},
+	{"Åsa Öberg", "asa@kthais.com", "vegan, \"no nuts\"\nthanks"},
+	{"Mallory", "=HYPERLINK(\"http://evil\")", "<b>&</b>\x00"},
+}
+
+func writeTable(t *testing.T, w Writer) {
+	t.Helper()
+	for _, row := range table {
+		require.NoError(t, w.Write(row))
+	}
+	require.NoError(t, w.Close())
+}
+
+func TestCSV(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewCSV(&buf)
+	require.NoError(t, err)
+	writeTable(t, w)
+
+	require.True(t, strings.HasPrefix(buf.String(), "\ufeff"))
+	rows, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\ufeff"))).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, table[:2], rows[:2])
+	// formulas are escaped, nothing else
+	assert.Equal(t, "'=HYPERLINK(\"http://evil\")", rows[2][1])
+	assert.Equal(t, table[2][2], rows[2][2])
+}
+
+type sheetXML struct {
+	Rows []struct {
+		R     string `xml:"r,attr"`
+		Cells []struct {
+			R    string `xml:"r,attr"`
+			Text string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func TestXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewXLSX(&buf, "Hackathon: 2025/26")
+	require.NoError(t, err)
+	writeTable(t, w)
+
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	parts := map[string][]byte{}
+	for _, f := range z.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		parts[f.Name], err = io.ReadAll(r)
+		require.NoError(t, err)
+		r.Close()
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		require.Contains(t, parts, name)
+		var v any
+		require.NoError(t, xml.Unmarshal(parts[name], &v), name)
+	}
+	assert.Contains(t, string(parts["xl/workbook.xml"]), `name="Hackathon 202526"`)
+
+	var sheet sheetXML
+	require.NoError(t, xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet))
+	require.Len(t, sheet.Rows, 3)
+	assert.Equal(t, "2", sheet.Rows[1].R)
+	assert.Equal(t, "C2", sheet.Rows[1].Cells[2].R)
+	assert.Equal(t, table[1][2], sheet.Rows[1].Cells[2].Text)
+	assert.Equal(t, table[2][1], sheet.Rows[2].Cells[1].Text)
+	assert.Equal(t, "<b>&</b>\ufffd", sheet.Rows[2].Cells[2].Text)
+}
+
+func TestColumnName(t *testing.T) {
+	for i, name := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		assert.Equal(t, name, columnName(i), i)
+	}
+}
@@ -0,0 +1,140 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// An XLSX file is a zip of XML parts. The worksheet is the last part so its
+// rows can be written as they come, everything else is fixed and written up
+// front. Cells are inline strings, which saves the shared string table that
+// would otherwise have to be held in memory until the end.
+
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+const (
+	workbookXML = `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	sheetStart = xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	sheetEnd   = `</sheetData></worksheet>`
+
+	// Excel refuses sheet names longer than this
+	maxSheetName = 31
+)
+
+type xlsxWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+}
+
+// NewXLSX returns a Writer for a workbook with a single sheet called name
+func NewXLSX(w io.Writer, name string) (Writer, error) {
+	z := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		if err := writePart(z, part.name, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := writePart(z, "xl/workbook.xml", workbook(sheetName(name))); err != nil {
+		return nil, err
+	}
+
+	sheet, err := z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	x := &xlsxWriter{zip: z, sheet: bufio.NewWriter(sheet)}
+	if _, err := x.sheet.WriteString(sheetStart); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *xlsxWriter) Write(row []string) error {
+	x.rows++
+	line := strconv.Itoa(x.rows)
+	x.sheet.WriteString(`<row r="` + line + `">`)
+	for i, cell := range row {
+		x.sheet.WriteString(`<c r="` + columnName(i) + line + `" t="inlineStr"><is><t xml:space="preserve">`)
+		// invalid XML characters are replaced instead of breaking the file
+		if err := xml.EscapeText(x.sheet, []byte(cell)); err != nil {
+			return err
+		}
+		x.sheet.WriteString(`</t></is></c>`)
+	}
+	_, err := x.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := x.sheet.WriteString(sheetEnd); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
+
+func writePart(z *zip.Writer, name, content string) error {
+	w, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, content)
+	return err
+}
+
+func workbook(sheet string) string {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(sheet))
+	return xml.Header + fmt.Sprintf(workbookXML, escaped.String())
+}
+
+// sheetName truncates name to what Excel accepts and drops the characters it
+// doesn't allow in sheet names
+func sheetName(name string) string {
+	var clean []rune
+	for _, r := range name {
+		if !strings.ContainsRune(`\/?*[]:`, r) {
+			clean = append(clean, r)
+		}
+	}
+	if len(clean) > maxSheetName {
+		clean = clean[:maxSheetName]
+	}
+	if len(clean) == 0 {
+		return "Sheet1"
+	}
+	return string(clean)
+}
+
+// columnName returns the letters of the i:th column, counting from 0: A, B,
+// ..., Z, AA, AB, ...
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,49 @@
+package tickets
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndParse(t *testing.T) {
+	ticket := Ticket{RegistrationID: 42, EventID: 7, Nonce: NewNonce()}
+	token := Sign("secret", ticket)
+
+	parsed, err := Parse("secret", token)
+	require.NoError(t, err)
+	assert.Equal(t, ticket, parsed)
+}
+
+func TestParseRejectsTampering(t *testing.T) {
+	token := Sign("secret", Ticket{RegistrationID: 42, EventID: 7, Nonce: NewNonce()})
+	forged := Sign("other key", Ticket{RegistrationID: 43, EventID: 7, Nonce: "x"})
+	payload, _, _ := bytes.Cut([]byte(forged), []byte("."))
+	_, sig, _ := bytes.Cut([]byte(token), []byte("."))
+
+	for _, bad := range []string{
+		"",
+		"no-dot",
+		token + "x",
+		forged,
+		string(payload) + "." + string(sig), // other payload, our signature
+	} {
+		_, err := Parse("secret", bad)
+		assert.ErrorIs(t, err, ErrInvalidTicket, bad)
+	}
+}
+
+func TestNoncesAreUnique(t *testing.T) {
+	assert.NotEqual(t, NewNonce(), NewNonce())
+}
+
+func TestQRCode(t *testing.T) {
+	data, err := QRCode(Sign("secret", Ticket{RegistrationID: 1, EventID: 1, Nonce: "n"}), 256)
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 256, img.Bounds().Dx())
+}
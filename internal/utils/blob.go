@@ -15,6 +15,13 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
+// BlobStore is where the data of blobs is kept, R2Client in production
+type BlobStore interface {
+	GetObject(object_key string) ([]byte, error)
+	PutObject(key string, obj []byte) error
+	DeleteObject(key string) error
+}
+
 type R2Client struct {
 	R2_client  *s3.Client
 	BucketName string
@@ -82,3 +89,11 @@ func (r2 R2Client) PutObject(key string, obj []byte) error {
 	})
 	return err
 }
+
+func (r2 R2Client) DeleteObject(key string) error {
+	_, err := r2.R2_client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: aws.String(r2.BucketName),
+		Key:    aws.String(key),
+	})
+	return err
+}
@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GoogleJWKSURL is where Google publishes the keys it signs ID tokens with
+const GoogleJWKSURL = "https://www.googleapis.com/oauth2/v3/certs"
+
+const (
+	jwksDefaultMaxAge = time.Hour        // when the response doesn't say how long to cache it
+	jwksMinRefetch    = time.Minute      // unknown kids don't trigger fetches more often than this
+	jwksRetryInterval = 30 * time.Second // background refreshes retry this soon after failing
+)
+
+// ErrUnknownKey is returned for key IDs that aren't in the key set
+var ErrUnknownKey = errors.New("no matching key found")
+
+// KeySource looks up the public keys tokens are signed with by key ID
+type KeySource interface {
+	Key(ctx context.Context, kid string) (*rsa.PublicKey, error)
+}
+
+// StaticKeys is a fixed set of keys, e.g. for tests
+type StaticKeys map[string]*rsa.PublicKey
+
+func (k StaticKeys) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	if key, ok := k[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// JWKSCache fetches a JSON Web Key Set and keeps it for as long as the
+// Cache-Control max-age of the response allows. Start keeps it fresh in the
+// background, so logins don't wait for Google. Keys it doesn't know yet, as
+// after Google rotated its keys, make it fetch the set again.
+type JWKSCache struct {
+	url    string
+	client *http.Client
+	now    func() time.Time
+
+	fetch     sync.Mutex // one fetch at a time
+	mu        sync.RWMutex
+	keys      map[string]*rsa.PublicKey
+	expiresAt time.Time
+	fetchedAt time.Time
+}
+
+func NewJWKSCache(url string, client *http.Client) *JWKSCache {
+	return &JWKSCache{url: url, client: client, now: time.Now}
+}
+
+// Key returns the key with an ID, fetching the key set if needed. When the
+// set can't be fetched, keys of an expired one are still used.
+func (s *JWKSCache) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	s.mu.RLock()
+	key := s.keys[kid]
+	fresh := s.now().Before(s.expiresAt)
+	recent := s.now().Sub(s.fetchedAt) < jwksMinRefetch
+	s.mu.RUnlock()
+
+	switch {
+	case key != nil && fresh:
+		return key, nil
+	// made up kids must not make us hammer Google
+	case key == nil && recent:
+		return nil, ErrUnknownKey
+	}
+	if err := s.Refresh(ctx); err != nil {
+		if key != nil {
+			log.Printf("Failed to refresh JWKS, using expired keys: %v", err)
+			return key, nil
+		}
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if key := s.keys[kid]; key != nil {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// Refresh fetches the key set
+func (s *JWKSCache) Refresh(ctx context.Context) error {
+	s.fetch.Lock()
+	defer s.fetch.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch JWKS: %s", resp.Status)
+	}
+	var data KeyList
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range data.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		key, err := k.PublicKey()
+		if err != nil {
+			log.Printf("Skipping JWKS key %s: %v", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+	s.fetchedAt = now
+	s.expiresAt = now.Add(maxAge(resp.Header.Get("Cache-Control")))
+	return nil
+}
+
+// Start refreshes the key set shortly before it expires until ctx is cancelled
+func (s *JWKSCache) Start(ctx context.Context) {
+	go func() {
+		for {
+			wait := jwksRetryInterval
+			if err := s.Refresh(ctx); err != nil {
+				log.Printf("Failed to refresh JWKS: %v", err)
+			} else {
+				s.mu.RLock()
+				// refresh when 90% of the lifetime has passed
+				wait = max(s.expiresAt.Sub(s.now())*9/10, jwksMinRefetch)
+				s.mu.RUnlock()
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+}
+
+// maxAge returns how long a response may be cached according to its
+// Cache-Control header
+func maxAge(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if !strings.EqualFold(name, "max-age") {
+			continue
+		}
+		if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return jwksDefaultMaxAge
+}
+
+type JwksKey struct {
+	N   string `json:"n"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	E   string `json:"e"`
+}
+
+// PublicKey decodes the modulus and exponent of an RSA key
+func (k JwksKey) PublicKey() (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+	e := new(big.Int).SetBytes(eBytes)
+	if len(nBytes) == 0 || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("invalid key")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(nBytes), E: int(e.Int64())}, nil
+}
+
+type KeyList struct {
+	Keys []JwksKey `json:"keys"`
+}
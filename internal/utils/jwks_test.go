@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func jwk(kid string, key *rsa.PublicKey) JwksKey {
+	return JwksKey{
+		Kid: kid,
+		Kty: "RSA",
+		Alg: "RS256",
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// jwksServer serves the keys it is given, the number of fetches is counted
+type jwksServer struct {
+	*httptest.Server
+	keys    atomic.Value // KeyList
+	fetches atomic.Int32
+}
+
+func newJWKSServer(t *testing.T, cacheControl string, keys ...JwksKey) *jwksServer {
+	t.Helper()
+	s := &jwksServer{}
+	s.keys.Store(KeyList{Keys: keys})
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		w.Header().Set("Cache-Control", cacheControl)
+		json.NewEncoder(w).Encode(s.keys.Load())
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestJWKSCacheHonoursMaxAge(t *testing.T) {
+	key := newRSAKey(t)
+	server := newJWKSServer(t, "public, max-age=600, must-revalidate", jwk("one", &key.PublicKey))
+	cache := NewJWKSCache(server.URL, server.Client())
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	got, err := cache.Key(ctx, "one")
+	require.NoError(t, err)
+	assert.True(t, key.PublicKey.Equal(got))
+	now = now.Add(9 * time.Minute)
+	_, err = cache.Key(ctx, "one")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, server.fetches.Load())
+
+	now = now.Add(2 * time.Minute)
+	_, err = cache.Key(ctx, "one")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, server.fetches.Load())
+}
+
+func TestJWKSCacheRefetchesUnknownKeys(t *testing.T) {
+	oldKey, newKey := newRSAKey(t), newRSAKey(t)
+	server := newJWKSServer(t, "max-age=3600", jwk("old", &oldKey.PublicKey))
+	cache := NewJWKSCache(server.URL, server.Client())
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err := cache.Key(ctx, "old")
+	require.NoError(t, err)
+
+	// Google rotated its keys
+	server.keys.Store(KeyList{Keys: []JwksKey{jwk("old", &oldKey.PublicKey), jwk("new", &newKey.PublicKey)}})
+	_, err = cache.Key(ctx, "new")
+	assert.ErrorIs(t, err, ErrUnknownKey, "refetched too soon")
+	now = now.Add(jwksMinRefetch)
+	got, err := cache.Key(ctx, "new")
+	require.NoError(t, err)
+	assert.True(t, newKey.PublicKey.Equal(got))
+	assert.EqualValues(t, 2, server.fetches.Load())
+
+	// made up kids don't cause a fetch each
+	for i := 0; i < 5; i++ {
+		_, err = cache.Key(ctx, "made-up")
+		assert.ErrorIs(t, err, ErrUnknownKey)
+	}
+	assert.EqualValues(t, 2, server.fetches.Load())
+}
+
+func TestJWKSCacheKeepsKeysWhenFetchingFails(t *testing.T) {
+	key := newRSAKey(t)
+	server := newJWKSServer(t, "max-age=60", jwk("one", &key.PublicKey))
+	cache := NewJWKSCache(server.URL, server.Client())
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err := cache.Key(ctx, "one")
+	require.NoError(t, err)
+	server.Close()
+	now = now.Add(time.Hour)
+	got, err := cache.Key(ctx, "one")
+	require.NoError(t, err)
+	assert.True(t, key.PublicKey.Equal(got))
+
+	// without any keys there is nothing to fall back on
+	empty := NewJWKSCache(server.URL, server.Client())
+	_, err = empty.Key(ctx, "one")
+	assert.Error(t, err)
+}
+
+func TestMaxAge(t *testing.T) {
+	for header, want := range map[string]time.Duration{
+		"public, max-age=19845, must-revalidate, no-transform": 19845 * time.Second,
+		"MAX-AGE=60":     time.Minute,
+		"no-cache":       jwksDefaultMaxAge,
+		"max-age=oops":   jwksDefaultMaxAge,
+		"max-age=0":      jwksDefaultMaxAge,
+		"":               jwksDefaultMaxAge,
+		"s-maxage=10, x": jwksDefaultMaxAge,
+	} {
+		assert.Equal(t, want, maxAge(header), header)
+	}
+}
@@ -1,17 +1,14 @@
 package utils
 
 import (
-	"crypto/rsa"
-	"encoding/base64"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"math/big"
-	"net/http"
+	"slices"
 	"strings"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
@@ -27,47 +24,95 @@ func ParseJWT(jwtIn string) (*jwt.Token, error) {
 	return token, err
 }
 
-func getKeyById(kl *KeyList, id string) *JwksKey {
-	for _, key := range kl.Keys {
-		if key.Kid == id {
-			return &key
-		}
-	}
-	return nil
-}
+var (
+	// ErrGoogleTokenInvalid is returned for Google ID tokens that aren't
+	// meant for us or whose email isn't verified
+	ErrGoogleTokenInvalid = errors.New("invalid google id token")
+	// ErrDomainNotAllowed is returned for Google ID tokens of users outside
+	// the allowed domains
+	ErrDomainNotAllowed = errors.New("domain not allowed")
+)
 
-func keyfunc(token *jwt.Token) (any, error) {
-	kid, ok := token.Header["kid"].(string)
-	if !ok {
-		return nil, fmt.Errorf("token has no kid")
-	}
+// googleIssuers are the values Google puts in the iss claim of ID tokens
+var googleIssuers = []string{"accounts.google.com", "https://accounts.google.com"}
 
-	klist := GetGoogleJWKSKey()
+// GoogleTokenChecks are what an ID token from Google must satisfy besides
+// being signed by Google and not expired
+type GoogleTokenChecks struct {
+	ClientID       string   // the token must be issued to us
+	AllowedDomains []string // hosted or email domains, any if empty
+}
 
-	key := getKeyById(klist, kid)
-	if key == nil {
-		return nil, fmt.Errorf("no matching key found")
+// VerifyGoogleIDToken verifies an ID token issued by Google with the keys of
+// the source and returns its claims. Besides the signature and expiry it
+// checks the audience, the issuer, that the email is verified and that it is
+// in one of the allowed domains.
+func VerifyGoogleIDToken(ctx context.Context, keys KeySource, jwtIn string, checks GoogleTokenChecks) (jwt.MapClaims, error) {
+	jwtParser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithAudience(checks.ClientID),
+	)
+	kf := func(token *jwt.Token) (any, error) {
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, fmt.Errorf("token has no kid")
+		}
+		return keys.Key(ctx, kid)
+	}
+	if checks.ClientID == "" {
+		return nil, fmt.Errorf("%w: no client id to check the audience against", ErrGoogleTokenInvalid)
+	}
+	claims := jwt.MapClaims{}
+	if _, err := jwtParser.ParseWithClaims(jwtIn, claims, kf); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGoogleTokenInvalid, err)
 	}
 
-	// Decode n and e (base64url to big.Int) and build rsa.PublicKey
-	nBytes, _ := base64.RawURLEncoding.DecodeString(key.N)
-	eBytes, _ := base64.RawURLEncoding.DecodeString(key.E)
-
-	n := new(big.Int).SetBytes(nBytes)
-	// e is usually 65537
-	e := int(new(big.Int).SetBytes(eBytes).Int64())
+	issuer, _ := claims.GetIssuer()
+	if !slices.Contains(googleIssuers, issuer) {
+		return nil, fmt.Errorf("%w: issued by %q", ErrGoogleTokenInvalid, issuer)
+	}
+	email, _ := claims["email"].(string)
+	if email == "" {
+		return nil, fmt.Errorf("%w: no email", ErrGoogleTokenInvalid)
+	}
+	// older tokens have it as a string
+	if verified := claims["email_verified"]; verified != true && verified != "true" {
+		return nil, fmt.Errorf("%w: email %s is not verified", ErrGoogleTokenInvalid, email)
+	}
 
-	return &rsa.PublicKey{N: n, E: e}, nil
+	if len(checks.AllowedDomains) > 0 {
+		hostedDomain, _ := claims["hd"].(string)
+		if !domainAllowed(hostedDomain, checks.AllowedDomains) && !EmailDomainAllowed(email, checks.AllowedDomains) {
+			return nil, fmt.Errorf("%w: %s", ErrDomainNotAllowed, email)
+		}
+	}
+	return claims, nil
 }
 
-func ParseAndVerifyGoogle(jwtIn string) (bool, *jwt.Token) {
-	jwtParser := jwt.NewParser()
+// EmailDomainAllowed reports whether the domain of an email address is one of
+// the allowed ones or a subdomain of one. Any address is allowed if none are.
+func EmailDomainAllowed(email string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	_, domain, _ := strings.Cut(email, "@")
+	return domainAllowed(domain, allowed)
+}
 
-	token, err := jwtParser.Parse(jwtIn, keyfunc)
-	if err != nil {
-		log.Printf("Error parsing encrypted token")
+// domainAllowed reports whether a domain is one of the allowed ones or a
+// subdomain of one, e.g. ug.kth.se of kth.se
+func domainAllowed(domain string, allowed []string) bool {
+	domain = strings.ToLower(domain)
+	if domain == "" {
+		return false
 	}
-	return token.Valid, token
+	for _, a := range allowed {
+		if domain == a || strings.HasSuffix(domain, "."+a) {
+			return true
+		}
+	}
+	return false
 }
 
 func ParseAndVerify(jwtIn string, skey string) (bool, *jwt.Token) {
@@ -79,26 +124,11 @@ func ParseAndVerify(jwtIn string, skey string) (bool, *jwt.Token) {
 	if err != nil {
 		log.Printf("Error parsing encrypted token %v\n", err)
 	}
-	return token.Valid, token
-}
-
-func GetJWTString(c *gin.Context) string {
-	for _, cookie := range c.Request.Cookies() {
-		if cookie.Name == "jwt" {
-			return cookie.Value
-		}
-	}
-	return ""
-}
-
-func GetJWT(c *gin.Context) *jwt.Token {
-	for _, cookie := range c.Request.Cookies() {
-		if cookie.Name == "jwt" {
-			token, _ := ParseJWT(cookie.Value)
-			return token
-		}
+	// malformed tokens don't parse into a token at all
+	if token == nil {
+		return false, nil
 	}
-	return nil
+	return token.Valid, token
 }
 
 func GetClaims(token *jwt.Token) jwt.MapClaims {
@@ -106,34 +136,6 @@ func GetClaims(token *jwt.Token) jwt.MapClaims {
 	return claims
 }
 
-type JwksKey struct {
-	N   string `json:"n"`
-	Alg string `json:"alg"`
-	Kid string `json:"kid"`
-	Kty string `json:"kty"`
-	Use string `json:"use"`
-	E   string `json:"e"`
-}
-
-type KeyList struct {
-	Keys []JwksKey `json:"keys"`
-}
-
-func GetGoogleJWKSKey() *KeyList {
-	url := "https://www.googleapis.com/oauth2/v3/certs"
-	resp, err := http.Get(url)
-	if err != nil {
-		log.Println("Could not retrieve google credentials")
-		return nil
-	}
-	defer resp.Body.Close()
-	var data KeyList
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		log.Printf("Error decoding json%v\n", err)
-	}
-	return &data
-}
-
 type UserClaims struct {
 	Email  string    `json:"email"`
 	Roles  string    `json:"roles"`
@@ -149,7 +151,7 @@ func WriteJWT(email string, roles []string, Id uuid.UUID, key string, validMinut
 		Id,
 		jwt.RegisteredClaims{
 			// A usual scenario is to set the expiration time relative to the current time
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(validMinutes) * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(validMinutes) * time.Minute)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "KTHAIS",
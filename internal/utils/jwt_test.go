@@ -1,10 +1,16 @@
 package utils
 
 import (
+	"context"
+	"crypto/rsa"
 	"log"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseJWT(t *testing.T) {
@@ -15,33 +21,6 @@ func TestParseJWT(t *testing.T) {
 	}
 }
 
-func TestKeyFetch(t *testing.T) {
-	GetGoogleJWKSKey()
-}
-
-// this is only to confirm that we can verify a google token, but requires a fresh token
-// func TestParseAndVerifyGoogle(t *testing.T) {
-// 	filename := "google.jwt"
-// 	f, _ := os.Open(filename)
-// 	reader := bufio.NewReader(f)
-// 	test_jwt, isPrefix, _ := reader.ReadLine()
-// 	if isPrefix {
-// 		log.Println("Long JWT")
-// 	}
-// 	valid, token := ParseAndVerifyGoogle(string(test_jwt))
-// 	if !valid {
-// 		t.Errorf("Token not valid!!")
-// 	} else {
-// 		claims, _ := token.Claims.(jwt.MapClaims)
-// 		for keys, values := range claims {
-// 			log.Printf("Key: %v --- Value: %v\n", keys, values)
-// 		}
-// 	}
-// 	if false {
-// 		t.Errorf("No error\n")
-// 	}
-// }
-
 func TestParseAndVerify(t *testing.T) {
 	key := "testkey123456"
 	uuid, _ := uuid.Parse("50c06e4d-b594-4489-9d4b-a513f63c90bd")
@@ -58,3 +37,85 @@ func TestJWTCreate(t *testing.T) {
 	newJwt := WriteJWT("vivienne@kthais.com", []string{"user", "admin", "queen"}, uuid, key, 15)
 	log.Printf("JWT Generated: %v\n", newJwt)
 }
+
+func signGoogleToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestVerifyGoogleIDToken(t *testing.T) {
+	key, other := newRSAKey(t), newRSAKey(t)
+	keys := StaticKeys{"one": &key.PublicKey}
+	const clientID = "client.apps.googleusercontent.com"
+	valid := func(change func(jwt.MapClaims)) jwt.MapClaims {
+		claims := jwt.MapClaims{
+			"iss":            "https://accounts.google.com",
+			"aud":            clientID,
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"email":          "ada@kth.se",
+			"email_verified": true,
+			"hd":             "kth.se",
+		}
+		if change != nil {
+			change(claims)
+		}
+		return claims
+	}
+	checks := GoogleTokenChecks{ClientID: clientID}
+	kthOnly := GoogleTokenChecks{ClientID: clientID, AllowedDomains: []string{"kth.se", "kthais.com"}}
+
+	tests := []struct {
+		name   string
+		token  string
+		checks GoogleTokenChecks
+		err    error
+	}{
+		{"valid", signGoogleToken(t, key, "one", valid(nil)), checks, nil},
+		{"issuer without scheme", signGoogleToken(t, key, "one", valid(func(c jwt.MapClaims) { c["iss"] = "accounts.google.com" })), checks, nil},
+		{"verified as string", signGoogleToken(t, key, "one", valid(func(c jwt.MapClaims) { c["email_verified"] = "true" })), checks, nil},
+		{"not signed by google", signGoogleToken(t, other, "one", valid(nil)), checks, ErrGoogleTokenInvalid},
+		{"unknown key", signGoogleToken(t, key, "two", valid(nil)), checks, ErrGoogleTokenInvalid},
+		{"garbage", "not-a-token", checks, ErrGoogleTokenInvalid},
+		{"expired", signGoogleToken(t, key, "one", valid(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() })), checks, ErrGoogleTokenInvalid},
+		{"no expiry", signGoogleToken(t, key, "one", valid(func(c jwt.MapClaims) { delete(c, "exp") })), checks, ErrGoogleTokenInvalid},
+		{"other audience", signGoogleToken(t, key, "one", valid(func(c jwt.MapClaims) { c["aud"] = "someone-else" })), checks, ErrGoogleTokenInvalid},
+		{"no client id configured", signGoogleToken(t, key, "one", valid(nil)), GoogleTokenChecks{}, ErrGoogleTokenInvalid},
+		{"other issuer", signGoogleToken(t, key, "one", valid(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" })), checks, ErrGoogleTokenInvalid},
+		{"email not verified", signGoogleToken(t, key, "one", valid(func(c jwt.MapClaims) { c["email_verified"] = false })), checks, ErrGoogleTokenInvalid},
+		{"email verification missing", signGoogleToken(t, key, "one", valid(func(c jwt.MapClaims) { delete(c, "email_verified") })), checks, ErrGoogleTokenInvalid},
+		{"no email", signGoogleToken(t, key, "one", valid(func(c jwt.MapClaims) { delete(c, "email") })), checks, ErrGoogleTokenInvalid},
+		{"hosted domain allowed", signGoogleToken(t, key, "one", valid(nil)), kthOnly, nil},
+		{"email domain allowed", signGoogleToken(t, key, "one", valid(func(c jwt.MapClaims) {
+			c["email"] = "ada@kthais.com"
+			delete(c, "hd")
+		})), kthOnly, nil},
+		{"subdomain allowed", signGoogleToken(t, key, "one", valid(func(c jwt.MapClaims) {
+			c["email"] = "ada@ug.KTH.se"
+			delete(c, "hd")
+		})), kthOnly, nil},
+		{"domain not allowed", signGoogleToken(t, key, "one", valid(func(c jwt.MapClaims) {
+			c["email"] = "ada@gmail.com"
+			delete(c, "hd")
+		})), kthOnly, ErrDomainNotAllowed},
+		{"lookalike domain", signGoogleToken(t, key, "one", valid(func(c jwt.MapClaims) {
+			c["email"] = "ada@notkth.se"
+			c["hd"] = "notkth.se"
+		})), kthOnly, ErrDomainNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := VerifyGoogleIDToken(context.Background(), keys, tt.token, tt.checks)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				assert.Nil(t, claims)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, claims["email"])
+		})
+	}
+}